@@ -0,0 +1,403 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type apiThumb struct {
+	URI   string `json:"uri"`
+	Width int    `json:"width"`
+	WebP  string `json:"webp,omitempty"`
+}
+
+type apiMedia struct {
+	Type        string     `json:"type"`
+	URI         string     `json:"uri"`
+	Caption     string     `json:"caption,omitempty"`
+	Width       int        `json:"width,omitempty"`
+	Height      int        `json:"height,omitempty"`
+	Placeholder string     `json:"placeholder,omitempty"`
+	Color       string     `json:"color,omitempty"`
+	Poster      string     `json:"poster,omitempty"`
+	Duration    float64    `json:"duration,omitempty"`
+	CaptureTime string     `json:"captureTime,omitempty"`
+	Camera      string     `json:"camera,omitempty"`
+	WebP        string     `json:"webp,omitempty"`
+	Thumbs      []apiThumb `json:"thumbs,omitempty"`
+}
+
+type apiTag struct {
+	Title string `json:"title"`
+	URI   string `json:"uri"`
+	API   string `json:"api"`
+}
+
+type apiCollectionRef struct {
+	Collection string `json:"collection"`
+	Item       string `json:"item"`
+	URI        string `json:"uri"`
+	API        string `json:"api"`
+}
+
+type apiPost struct {
+	ID          string             `json:"id"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Date        string             `json:"date,omitempty"`
+	Time        string             `json:"time,omitempty"`
+	Published   string             `json:"published,omitempty"`
+	URI         string             `json:"uri"`
+	API         string             `json:"api"`
+	HTML        string             `json:"html"`
+	Tags        []apiTag           `json:"tags"`
+	Collections []apiCollectionRef `json:"collections"`
+	Media       []apiMedia         `json:"media"`
+}
+
+type apiPostSummary struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title,omitempty"`
+	Date    string   `json:"date,omitempty"`
+	Time    string   `json:"time,omitempty"`
+	URI     string   `json:"uri"`
+	API     string   `json:"api"`
+	Excerpt string   `json:"excerpt"`
+	Tags    []apiTag `json:"tags"`
+}
+
+type apiPostIndex struct {
+	Page       int              `json:"page"`
+	TotalPages int              `json:"totalPages"`
+	TotalCount int              `json:"totalCount"`
+	Prev       string           `json:"prev,omitempty"`
+	Next       string           `json:"next,omitempty"`
+	Posts      []apiPostSummary `json:"posts"`
+}
+
+type apiPage struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	URI         string     `json:"uri"`
+	API         string     `json:"api"`
+	HTML        string     `json:"html"`
+	Media       []apiMedia `json:"media"`
+}
+
+type apiPageSummary struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	URI   string `json:"uri"`
+	API   string `json:"api"`
+}
+
+type apiTagSummary struct {
+	apiTag
+	PostCount int `json:"postCount"`
+}
+
+type apiTagIndex struct {
+	Tags []apiTagSummary `json:"tags"`
+}
+
+type apiCollectionItem struct {
+	Title     string     `json:"title"`
+	URI       string     `json:"uri"`
+	API       string     `json:"api"`
+	PostCount int        `json:"postCount"`
+	Media     []apiMedia `json:"media"`
+}
+
+type apiCollection struct {
+	Title     string              `json:"title"`
+	URI       string              `json:"uri"`
+	API       string              `json:"api"`
+	PostCount int                 `json:"postCount"`
+	Items     []apiCollectionItem `json:"items"`
+}
+
+type apiCollectionIndex struct {
+	Collections []apiCollection `json:"collections"`
+}
+
+type apiSiteIndex struct {
+	SiteName        string           `json:"siteName,omitempty"`
+	SiteDescription string           `json:"siteDescription,omitempty"`
+	Posts           string           `json:"posts"`
+	Tags            string           `json:"tags"`
+	Collections     string           `json:"collections"`
+	Pages           []apiPageSummary `json:"pages"`
+}
+
+// isValidAPIDir checks whether the given (slash trimmed) API dir is a relative path
+// which doesn't clash with the dirs of the generated content
+func isValidAPIDir(apiDir string) bool {
+	if !apiDirRegexp.MatchString(apiDir) {
+		return false
+	}
+	topLevelDir, _, _ := strings.Cut(apiDir, "/")
+	return !slices.Contains(generatedContentDirNames, topLevelDir)
+}
+
+// siteURI returns the URI of the given site path in the language being processed
+func siteURI(config appConfig, path string) string {
+	return langURIPrefix(config.lang, config) + "/" + path
+}
+
+// apiURI returns the URI of the API document at the given path (relative to the API dir)
+func apiURI(config appConfig, path string) string {
+	return siteURI(config, config.apiDir+"/"+path)
+}
+
+func marshalAPIDocument(v any) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	// the documents carry rendered HTML, which is kept readable (not escaped)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(v)
+	check(err)
+	return buf.Bytes()
+}
+
+func buildAPIMedia(mediaList []media) []apiMedia {
+	apiMediaList := make([]apiMedia, 0, len(mediaList))
+	for _, m := range mediaList {
+		am := apiMedia{URI: m.Uri, Caption: m.Caption, Width: m.width, Height: m.height, Placeholder: m.placeholder, Color: m.color,
+			Poster: m.posterUri, Duration: m.duration, Camera: m.camera, WebP: m.webpUri}
+		if !m.captureTime.IsZero() {
+			am.CaptureTime = m.captureTime.String()
+		}
+		if m.Type.Video() {
+			am.Type = "video"
+		} else {
+			am.Type = "image"
+		}
+		for i, t := range m.thumbs {
+			at := apiThumb{URI: t.Uri, Width: t.Size}
+			if m.HasWebP() {
+				at.WebP = m.webpThumbs[i].Uri
+			}
+			am.Thumbs = append(am.Thumbs, at)
+		}
+		apiMediaList = append(apiMediaList, am)
+	}
+	return apiMediaList
+}
+
+// listAPIMedia lists all the media files of the given post/page (from its own media dir)
+func listAPIMedia(ceType contentEntityType, ceId string, config appConfig) []apiMedia {
+	return buildAPIMedia(parseMediaFileNames(listAllMedia(ceType, ceId, nil), ceType, ceId, config, false, nil, nil))
+}
+
+func buildAPITags(tags []string, config appConfig) []apiTag {
+	apiTags := make([]apiTag, 0, len(tags))
+	for _, tag := range tags {
+		tagUri := normalizeURIString(tag)
+		apiTags = append(apiTags, apiTag{
+			Title: tag,
+			URI:   siteURI(config, deployTagsDirName+"/"+tagUri+"/"),
+			API:   apiURI(config, deployTagsDirName+"/"+tagUri+"/"+apiIndexFileName),
+		})
+	}
+	return apiTags
+}
+
+func buildAPIPost(p post, config appConfig) apiPost {
+	ap := apiPost{
+		ID:          p.Id,
+		Title:       p.Title,
+		Description: p.Description,
+		Date:        p.FmtDate(),
+		Time:        p.FmtTime(),
+		URI:         siteURI(config, deployPostDirName+"/"+p.Id+contentFileExtension),
+		API:         apiURI(config, deployPostDirName+"/"+p.Id+apiFileExtension),
+		HTML:        p.Body,
+		Tags:        buildAPITags(p.Tags, config),
+		Collections: []apiCollectionRef{},
+		Media:       listAPIMedia(Post, p.Id, config),
+	}
+	if !p.Date.IsZero() {
+		ap.Published = p.publishTime().Format(time.RFC3339)
+	}
+	for _, ref := range p.Collections {
+		itemPath := deployCollectionsDirName + "/" + normalizeURIString(ref.Collection) + "/" + normalizeURIString(ref.Item) + "/"
+		ap.Collections = append(ap.Collections, apiCollectionRef{
+			Collection: ref.Collection,
+			Item:       ref.Item,
+			URI:        siteURI(config, itemPath),
+			API:        apiURI(config, itemPath+apiIndexFileName),
+		})
+	}
+	return ap
+}
+
+func buildAPIPostSummary(p post, config appConfig) apiPostSummary {
+	return apiPostSummary{
+		ID:      p.Id,
+		Title:   p.Title,
+		Date:    p.FmtDate(),
+		Time:    p.FmtTime(),
+		URI:     siteURI(config, deployPostDirName+"/"+p.Id+contentFileExtension),
+		API:     apiURI(config, deployPostDirName+"/"+p.Id+apiFileExtension),
+		Excerpt: toPlainText(buildExcerptMarkdown(p.FeedContent)),
+		Tags:    buildAPITags(p.Tags, config),
+	}
+}
+
+// buildAPIPostIndexes splits the given posts into the paginated index documents of the given dir
+// (relative to the API dir): `<dir>/index.json` for the first page, `<dir>/<num>.json` for the following ones
+// — mirroring the HTML pagination (path -> document)
+func buildAPIPostIndexes(posts []post, dir string, config appConfig) map[string]apiPostIndex {
+	pageSize := config.pageSize
+	totalPages := max(1, (len(posts)+pageSize-1)/pageSize)
+	pagePath := func(pageNum int) string {
+		if pageNum == 1 {
+			return dir + "/" + apiIndexFileName
+		}
+		return dir + "/" + strconv.Itoa(pageNum) + apiFileExtension
+	}
+	indexes := make(map[string]apiPostIndex, totalPages)
+	for pageNum := 1; pageNum <= totalPages; pageNum++ {
+		index := apiPostIndex{Page: pageNum, TotalPages: totalPages, TotalCount: len(posts), Posts: []apiPostSummary{}}
+		if pageNum > 1 {
+			index.Prev = apiURI(config, pagePath(pageNum-1))
+		}
+		if pageNum < totalPages {
+			index.Next = apiURI(config, pagePath(pageNum+1))
+		}
+		for _, p := range posts[(pageNum-1)*pageSize : min(pageNum*pageSize, len(posts))] {
+			index.Posts = append(index.Posts, buildAPIPostSummary(p, config))
+		}
+		indexes[pagePath(pageNum)] = index
+	}
+	return indexes
+}
+
+// buildAPIDocuments builds all the API documents (path relative to the API dir -> document data)
+// for the given pages, posts and (aggregated) collections
+func buildAPIDocuments(pages []page, posts []post, collections []collectionData, resLoader resourceLoader) map[string][]byte {
+	config := resLoader.config
+	docs := make(map[string][]byte)
+	addIndexes := func(indexes map[string]apiPostIndex) {
+		for path, index := range indexes {
+			docs[path] = marshalAPIDocument(index)
+		}
+	}
+
+	siteIndex := apiSiteIndex{
+		SiteName:        config.siteName,
+		SiteDescription: config.siteDescription,
+		Posts:           apiURI(config, deployPostsDirName+"/"+apiIndexFileName),
+		Tags:            apiURI(config, deployTagsDirName+"/"+apiIndexFileName),
+		Collections:     apiURI(config, deployCollectionsDirName+"/"+apiIndexFileName),
+		Pages:           []apiPageSummary{},
+	}
+	for _, p := range pages {
+		uri := siteURI(config, deployPageDirName+"/"+p.Id+contentFileExtension)
+		if p.Id == config.homePage {
+			uri = siteURI(config, "")
+		}
+		body := p.Body
+		if len(p.CollectionRefs) > 0 {
+			body = renderEmbeddedCollections(body, collections, resLoader)
+		}
+		ap := apiPage{
+			ID:          p.Id,
+			Title:       p.Title,
+			Description: p.Description,
+			URI:         uri,
+			API:         apiURI(config, deployPageDirName+"/"+p.Id+apiFileExtension),
+			HTML:        body,
+			Media:       listAPIMedia(Page, p.Id, config),
+		}
+		docs[deployPageDirName+"/"+p.Id+apiFileExtension] = marshalAPIDocument(ap)
+		siteIndex.Pages = append(siteIndex.Pages, apiPageSummary{ID: ap.ID, Title: ap.Title, URI: ap.URI, API: ap.API})
+	}
+	docs[apiIndexFileName] = marshalAPIDocument(siteIndex)
+
+	tagIndex := apiTagIndex{Tags: []apiTagSummary{}}
+	tagIdx := make(map[string]int)
+	var tagPosts [][]post
+	itemPosts := make(map[string][]post)
+	for _, p := range posts {
+		docs[deployPostDirName+"/"+p.Id+apiFileExtension] = marshalAPIDocument(buildAPIPost(p, config))
+		for _, tag := range buildAPITags(p.Tags, config) {
+			idx, ok := tagIdx[tag.URI]
+			if !ok {
+				// the tag title is taken from the newest post referencing it
+				idx = len(tagIndex.Tags)
+				tagIdx[tag.URI] = idx
+				tagIndex.Tags = append(tagIndex.Tags, apiTagSummary{apiTag: tag})
+				tagPosts = append(tagPosts, nil)
+			}
+			tagIndex.Tags[idx].PostCount++
+			tagPosts[idx] = append(tagPosts[idx], p)
+		}
+		for _, ref := range p.Collections {
+			key := normalizeURIString(ref.Collection) + "/" + normalizeURIString(ref.Item)
+			if !slices.ContainsFunc(itemPosts[key], func(ip post) bool { return ip.Id == p.Id }) {
+				itemPosts[key] = append(itemPosts[key], p)
+			}
+		}
+	}
+	addIndexes(buildAPIPostIndexes(posts, deployPostsDirName, config))
+
+	for idx, tag := range tagIndex.Tags {
+		addIndexes(buildAPIPostIndexes(tagPosts[idx], deployTagsDirName+"/"+normalizeURIString(tag.Title), config))
+	}
+	slices.SortStableFunc(tagIndex.Tags, func(a, b apiTagSummary) int {
+		return strings.Compare(a.URI, b.URI)
+	})
+	docs[deployTagsDirName+"/"+apiIndexFileName] = marshalAPIDocument(tagIndex)
+
+	collIndex := apiCollectionIndex{Collections: []apiCollection{}}
+	for _, coll := range collections {
+		collPath := deployCollectionsDirName + "/" + coll.URI
+		ac := apiCollection{
+			Title:     coll.Title,
+			URI:       siteURI(config, collPath+"/"),
+			API:       apiURI(config, collPath+"/"+apiIndexFileName),
+			PostCount: coll.PostCnt,
+			Items:     []apiCollectionItem{},
+		}
+		for _, item := range coll.Items {
+			itemPath := collPath + "/" + item.URI
+			ac.Items = append(ac.Items, apiCollectionItem{
+				Title:     item.Title,
+				URI:       siteURI(config, itemPath+"/"),
+				API:       apiURI(config, itemPath+"/"+apiIndexFileName),
+				PostCount: item.PostCnt,
+				Media:     buildAPIMedia(item.Media),
+			})
+			addIndexes(buildAPIPostIndexes(itemPosts[coll.URI+"/"+item.URI], itemPath, config))
+		}
+		docs[collPath+"/"+apiIndexFileName] = marshalAPIDocument(ac)
+		collIndex.Collections = append(collIndex.Collections, ac)
+	}
+	docs[deployCollectionsDirName+"/"+apiIndexFileName] = marshalAPIDocument(collIndex)
+
+	return docs
+}
+
+// generateAPI generates the static JSON API documents into the API dir (inside the deploy dir)
+func generateAPI(pages []page, posts []post, collections []collectionData, resLoader resourceLoader, handleOutput processorOutputHandler) {
+	if handleOutput == nil {
+		return
+	}
+	sprintln(" - generating API files ...")
+	docs := buildAPIDocuments(pages, posts, collections, resLoader)
+	paths := make([]string, 0, len(docs))
+	for path := range docs {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		handleOutput(filepath.Join(deployDirName, filepath.FromSlash(resLoader.config.apiDir), filepath.FromSlash(path)), docs[path])
+	}
+}
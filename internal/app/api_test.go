@@ -0,0 +1,99 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"cloud.google.com/go/civil"
+)
+
+func TestIsValidAPIDir(t *testing.T) {
+	for _, apiDir := range []string{"api", "api/v1", "json-api", "data_1"} {
+		if !isValidAPIDir(apiDir) {
+			t.Errorf("expected API dir to be valid: %s", apiDir)
+		}
+	}
+	for _, apiDir := range []string{"", "../api", "api//v1", "api dir", deployPostDirName, deployTagsDirName + "/api", mediaDirName, resourcesDirName} {
+		if isValidAPIDir(apiDir) {
+			t.Errorf("expected API dir to be invalid: %s", apiDir)
+		}
+	}
+}
+
+func TestBuildAPIDocuments(t *testing.T) {
+	resLoader := testResLoader()
+	resLoader.config.pageSize = 2
+
+	pages := []page{
+		{Id: "about", Title: "About", Body: "<p>About <b>me</b></p>"},
+	}
+	posts := []post{
+		{Id: "post-3", Title: "Third", Body: "<p>body 3</p>", FeedContent: "Body *three* here.", Tags: []string{"Go"}, Date: civil.Date{Year: 2024, Month: 3, Day: 1},
+			Collections: []postCollectionRef{{Collection: "Books", Item: "Go Book"}}},
+		{Id: "post-2", Body: "<p>body 2</p>", FeedContent: "Body two.", Tags: []string{"Misc"}},
+		{Id: "post-1", Title: "First", Body: "<p>body 1</p>", FeedContent: "Body one.", Tags: []string{"go"}},
+	}
+	collections := []collectionData{
+		{Title: "Books", URI: "books", PostCnt: 1, Items: []collectionItemData{{Title: "Go Book", URI: "go-book", PostCnt: 1}}},
+	}
+	docs := buildAPIDocuments(pages, posts, collections, resLoader)
+
+	var ap apiPost
+	check(json.Unmarshal(docs[deployPostDirName+"/post-3"+apiFileExtension], &ap))
+	verifyStringsEqual(ap.Title, "Third", t)
+	verifyStringsEqual(ap.HTML, "<p>body 3</p>", t)
+	verifyStringsEqual(ap.URI, "/post/post-3.html", t)
+	verifyStringsEqual(ap.API, "/api/post/post-3.json", t)
+	verifyStringsEqual(ap.Date, "2024-03-01", t)
+	if len(ap.Tags) != 1 || ap.Tags[0].API != "/api/tags/go/index.json" {
+		t.Errorf("unexpected post tags: %v", ap.Tags)
+	}
+	if len(ap.Collections) != 1 || ap.Collections[0].API != "/api/collections/books/go-book/index.json" {
+		t.Errorf("unexpected post collections: %v", ap.Collections)
+	}
+	verifyStringContains(string(docs[deployPostDirName+"/post-3"+apiFileExtension]), `"html":"<p>body 3</p>"`, t)
+
+	var ap2 apiPage
+	check(json.Unmarshal(docs[deployPageDirName+"/about"+apiFileExtension], &ap2))
+	verifyStringsEqual(ap2.HTML, "<p>About <b>me</b></p>", t)
+	verifyStringsEqual(ap2.URI, "/page/about.html", t)
+
+	var first, second apiPostIndex
+	check(json.Unmarshal(docs[deployPostsDirName+"/"+apiIndexFileName], &first))
+	check(json.Unmarshal(docs[deployPostsDirName+"/2"+apiFileExtension], &second))
+	if first.TotalPages != 2 || first.TotalCount != 3 || len(first.Posts) != 2 || len(second.Posts) != 1 {
+		t.Errorf("unexpected post index pagination: %+v / %+v", first, second)
+	}
+	verifyStringsEqual(first.Next, "/api/posts/2.json", t)
+	verifyStringsEqual(second.Prev, "/api/posts/index.json", t)
+	verifyStringsEqual(first.Posts[0].Excerpt, "Body three here.", t)
+	verifyStringsEqual(second.Posts[0].ID, "post-1", t)
+
+	var tagIndex apiTagIndex
+	check(json.Unmarshal(docs[deployTagsDirName+"/"+apiIndexFileName], &tagIndex))
+	if len(tagIndex.Tags) != 2 || tagIndex.Tags[0].Title != "Go" || tagIndex.Tags[0].PostCount != 2 {
+		t.Errorf("unexpected tag index: %+v", tagIndex)
+	}
+	var goTag apiPostIndex
+	check(json.Unmarshal(docs[deployTagsDirName+"/go/"+apiIndexFileName], &goTag))
+	if len(goTag.Posts) != 2 || goTag.Posts[1].ID != "post-1" {
+		t.Errorf("unexpected tag post index: %+v", goTag)
+	}
+
+	var coll apiCollection
+	check(json.Unmarshal(docs[deployCollectionsDirName+"/books/"+apiIndexFileName], &coll))
+	if len(coll.Items) != 1 || coll.Items[0].API != "/api/collections/books/go-book/index.json" {
+		t.Errorf("unexpected collection: %+v", coll)
+	}
+	var itemIndex apiPostIndex
+	check(json.Unmarshal(docs[deployCollectionsDirName+"/books/go-book/"+apiIndexFileName], &itemIndex))
+	if len(itemIndex.Posts) != 1 || itemIndex.Posts[0].ID != "post-3" {
+		t.Errorf("unexpected collection item post index: %+v", itemIndex)
+	}
+
+	var siteIndex apiSiteIndex
+	check(json.Unmarshal(docs[apiIndexFileName], &siteIndex))
+	if len(siteIndex.Pages) != 1 || siteIndex.Posts != "/api/posts/index.json" {
+		t.Errorf("unexpected site index: %+v", siteIndex)
+	}
+}
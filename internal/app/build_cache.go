@@ -0,0 +1,336 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"cloud.google.com/go/civil"
+)
+
+// buildManifest is the persistent build cache manifest (see the generate command),
+// recording the content hashes of the build inputs along with the results derived from them
+type buildManifest struct {
+	// the hash of the site-wide build inputs (app version, config, theme, includes and shared media listing):
+	// if it changes, nothing is reused
+	InputsHash string `json:"inputsHash"`
+	// the deploy environment the build was generated for (empty for the default one)
+	Environment *string `json:"environment,omitempty"`
+	// the parsed pages/posts (keyed by `<type>/<file name>`)
+	Entities map[string]buildCacheEntity `json:"entities"`
+	// the render keys (the content hashes of the render inputs) of the generated output files (keyed by file path)
+	Outputs map[string]string `json:"outputs"`
+	// the intrinsic info of the media files (keyed by file path, see getMediaInfo):
+	// unlike the rest of the manifest, it's reused regardless of the site-wide build inputs
+	Media map[string]mediaInfo `json:"media,omitempty"`
+}
+
+// buildCacheEntity is a parsed page/post along with the hash of its inputs (the source file and media listing)
+type buildCacheEntity struct {
+	Hash           string `json:"hash"`
+	Page           *page  `json:"page,omitempty"`
+	Post           *post  `json:"post,omitempty"`
+	ExcerptContent string `json:"excerptContent,omitempty"` // page.excerptContent (unexported)
+}
+
+// buildCache holds the manifest of the previous build (read-only)
+// along with the one of the current build (populated concurrently while parsing/rendering)
+type buildCache struct {
+	inputsHash  string
+	environment string
+	prev        buildManifest
+	entities    *syncCache[string, buildCacheEntity]
+	outputs     *syncCache[string, string]
+	prevMedia   map[string]mediaInfo
+	media       *syncCache[string, mediaInfo]
+}
+
+// activeBuildCache is the persistent build cache of the current generate run (nil if not in use)
+var activeBuildCache *buildCache
+
+// cachedMedia mirrors media for the build cache manifest, including the unexported fields
+type cachedMedia struct {
+	Type        mediaType
+	Uri         string
+	Caption     string
+	Thumbs      []thumb
+	WebPUri     string  `json:",omitempty"`
+	WebPThumbs  []thumb `json:",omitempty"`
+	Width       int     `json:",omitempty"`
+	Height      int     `json:",omitempty"`
+	Placeholder string  `json:",omitempty"`
+	Color       string  `json:",omitempty"`
+	PosterUri   string  `json:",omitempty"`
+	Duration    float64 `json:",omitempty"`
+	CaptureTime string  `json:",omitempty"`
+	Camera      string  `json:",omitempty"`
+	Focus       string  `json:",omitempty"` // `XxY`
+	CropThumbs  []thumb `json:",omitempty"`
+}
+
+func (m media) MarshalJSON() ([]byte, error) {
+	var captureTime string
+	if !m.captureTime.IsZero() {
+		captureTime = m.captureTime.String()
+	}
+	var focus string
+	if m.focus != nil {
+		focus = m.focus.String()
+	}
+	return json.Marshal(cachedMedia{Type: m.Type, Uri: m.Uri, Caption: m.Caption, Thumbs: m.thumbs, WebPUri: m.webpUri, WebPThumbs: m.webpThumbs,
+		Width: m.width, Height: m.height, Placeholder: m.placeholder, Color: m.color, PosterUri: m.posterUri, Duration: m.duration,
+		CaptureTime: captureTime, Camera: m.camera, Focus: focus, CropThumbs: m.cropThumbs})
+}
+
+func (m *media) UnmarshalJSON(data []byte) error {
+	var cm cachedMedia
+	if err := json.Unmarshal(data, &cm); err != nil {
+		return err
+	}
+	var captureTime civil.DateTime
+	if cm.CaptureTime != "" {
+		var err error
+		if captureTime, err = civil.ParseDateTime(cm.CaptureTime); err != nil {
+			return err
+		}
+	}
+	*m = media{Type: cm.Type, Uri: cm.Uri, Caption: cm.Caption, thumbs: cm.Thumbs, webpUri: cm.WebPUri, webpThumbs: cm.WebPThumbs,
+		width: cm.Width, height: cm.Height, placeholder: cm.Placeholder, color: cm.Color, posterUri: cm.PosterUri, duration: cm.Duration,
+		captureTime: captureTime, camera: cm.Camera, cropThumbs: cm.CropThumbs}
+	if cm.Focus != "" {
+		fp, ok := parseFocalPoint(cm.Focus)
+		if !ok {
+			return fmt.Errorf("invalid media focal point: %s", cm.Focus)
+		}
+		m.focus = &fp
+	}
+	return nil
+}
+
+// loadBuildCache activates the persistent build cache for the current generate run:
+// the previous build manifest is reused unless a full rebuild is requested,
+// or any of the site-wide build inputs has changed since
+func loadBuildCache(config appConfig, fullRebuild bool) {
+	inputsHash := buildInputsHash(config)
+	bc := &buildCache{
+		inputsHash:  inputsHash,
+		environment: config.environment,
+		entities:    newSyncCache[string, buildCacheEntity](),
+		outputs:     newSyncCache[string, string](),
+		media:       newSyncCache[string, mediaInfo](),
+	}
+	manifestFilePath := filepath.Join(buildCacheDirName, buildCacheManifestFileName)
+	if fullRebuild {
+		sprintln(" - full rebuild: ignoring the build cache ...")
+	} else if fileExists(manifestFilePath) {
+		var prev buildManifest
+		if err := json.Unmarshal(readDataFromFile(manifestFilePath), &prev); err != nil {
+			sprintln(" - ignoring the invalid build cache manifest: " + err.Error())
+		} else {
+			bc.prevMedia = prev.Media
+			if prev.InputsHash != inputsHash {
+				sprintln(" - site-wide build inputs changed: ignoring the build cache ...")
+			} else {
+				bc.prev = prev
+			}
+		}
+	}
+	activeBuildCache = bc
+}
+
+// saveBuildCache writes the manifest of the current build and deactivates the build cache
+func saveBuildCache() {
+	if activeBuildCache == nil {
+		return
+	}
+	// the media files of the reused pages/posts aren't looked up, so their info is carried over as long as they still exist
+	for mediaFilePath, info := range activeBuildCache.prevMedia {
+		if _, ok := activeBuildCache.media.m[mediaFilePath]; !ok && fileExists(mediaFilePath) {
+			activeBuildCache.media.m[mediaFilePath] = info
+		}
+	}
+	manifest := buildManifest{
+		InputsHash:  activeBuildCache.inputsHash,
+		Environment: &activeBuildCache.environment,
+		Entities:    activeBuildCache.entities.m,
+		Outputs:     activeBuildCache.outputs.m,
+		Media:       activeBuildCache.media.m,
+	}
+	data, err := json.Marshal(manifest)
+	check(err)
+	createDirIfNotExists(buildCacheDirName)
+	writeDataToFileIfChanged(filepath.Join(buildCacheDirName, buildCacheManifestFileName), data)
+	activeBuildCache = nil
+}
+
+// lastGenerateEnvironment returns the deploy environment the deploy dir was last generated for,
+// as recorded in the build cache manifest (not known if there's no manifest, or it predates the record)
+func lastGenerateEnvironment() (environment string, known bool) {
+	manifestFilePath := filepath.Join(buildCacheDirName, buildCacheManifestFileName)
+	if !fileExists(manifestFilePath) {
+		return "", false
+	}
+	var manifest struct {
+		Environment *string `json:"environment"`
+	}
+	if err := json.Unmarshal(readDataFromFile(manifestFilePath), &manifest); err != nil || manifest.Environment == nil {
+		return "", false
+	}
+	return *manifest.Environment, true
+}
+
+// readBuildManifestMedia returns the media info recorded in the build cache manifest (nil if there's none)
+func readBuildManifestMedia() map[string]mediaInfo {
+	manifestFilePath := filepath.Join(buildCacheDirName, buildCacheManifestFileName)
+	if !fileExists(manifestFilePath) {
+		return nil
+	}
+	var manifest struct {
+		Media map[string]mediaInfo `json:"media"`
+	}
+	if err := json.Unmarshal(readDataFromFile(manifestFilePath), &manifest); err != nil {
+		return nil
+	}
+	return manifest.Media
+}
+
+// hashStrings returns the (hex encoded) content hash of the given strings
+func hashStrings(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		// length prefixed, so that the part boundaries are a part of the hash
+		h.Write([]byte(strconv.Itoa(len(part)) + ":"))
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashJSON returns the content hash of the JSON representation of the given values
+func hashJSON(values ...any) string {
+	var parts []string
+	for _, v := range values {
+		data, err := json.Marshal(v)
+		check(err)
+		parts = append(parts, string(data))
+	}
+	return hashStrings(parts...)
+}
+
+// buildInputsHash hashes the site-wide build inputs: the app version, the config file (along with
+// the deploy environment it is applied for), the theme and include files, as well as the shared media listing
+func buildInputsHash(config appConfig) string {
+	parts := []string{appVersion, string(readDataFromFile(configFileName)), config.environment}
+	for _, dir := range []string{config.theme, includeDirName} {
+		if !dirExists(dir) {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			parts = append(parts, filepath.ToSlash(path), string(data))
+			return nil
+		})
+		check(err)
+	}
+	parts = append(parts, mediaDirListing(filepath.Join(deployDirName, mediaDirName, sharedMediaDirName)))
+	return hashStrings(parts...)
+}
+
+// mediaDirListing lists the (original) media files of the given dir along with their sizes and modification times,
+// leaving out the generated (crop) thumbnails and WebP variants
+func mediaDirListing(mediaDirPath string) string {
+	entries, err := os.ReadDir(mediaDirPath)
+	if err != nil {
+		return ""
+	}
+	var listing []string
+	for _, entry := range entries {
+		if entry.IsDir() || isImgThumbnailOrVariant(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		check(err)
+		listing = append(listing, entry.Name()+"|"+strconv.FormatInt(info.Size(), 10)+"|"+strconv.FormatInt(info.ModTime().UnixNano(), 10))
+	}
+	slices.Sort(listing)
+	return hashStrings(listing...)
+}
+
+// contentEntityBuildCacheHash hashes the inputs of a page/post: its source file content and media listing
+// (empty if the build cache is not in use)
+func contentEntityBuildCacheHash(content []byte, mediaDirPath string) string {
+	if activeBuildCache == nil {
+		return ""
+	}
+	return hashStrings(string(content), mediaDirListing(mediaDirPath))
+}
+
+// getContentEntityFromBuildCache returns the parsed page/post of the previous build
+// if its inputs haven't changed since, nil otherwise
+func getContentEntityFromBuildCache(ceType contentEntityType, fileName string, hash string) contentEntity {
+	if activeBuildCache == nil {
+		return nil
+	}
+	key := ceType.String() + "/" + fileName
+	entity, ok := activeBuildCache.prev.Entities[key]
+	if !ok || entity.Hash != hash {
+		return nil
+	}
+	switch {
+	case ceType == Page && entity.Page != nil:
+		activeBuildCache.entities.set(key, entity)
+		p := *entity.Page
+		p.excerptContent = entity.ExcerptContent
+		return p
+	case ceType == Post && entity.Post != nil:
+		activeBuildCache.entities.set(key, entity)
+		return *entity.Post
+	}
+	return nil
+}
+
+// addContentEntityToBuildCache records the given parsed page/post in the build cache
+func addContentEntityToBuildCache(fileName string, hash string, ce contentEntity) {
+	if activeBuildCache == nil {
+		return
+	}
+	entity := buildCacheEntity{Hash: hash}
+	switch ce.ContentEntityType() {
+	case Page:
+		p := ce.(page)
+		entity.Page = &p
+		entity.ExcerptContent = p.excerptContent
+	case Post:
+		p := ce.(post)
+		entity.Post = &p
+	}
+	activeBuildCache.entities.set(ce.ContentEntityType().String()+"/"+fileName, entity)
+}
+
+// renderOrReuseOutput returns the data of the given output file: the already generated one
+// if its render key (the content hash of its render inputs) is the same as in the previous build,
+// or the freshly rendered one otherwise
+func renderOrReuseOutput(outputFilePath string, renderKey func() string, config appConfig, render func() []byte) []byte {
+	if activeBuildCache == nil {
+		return render()
+	}
+	key := renderKey()
+	// the output file paths are localized by the output handler
+	outputFilePath = localizeOutputFilePath(outputFilePath, config)
+	activeBuildCache.outputs.set(outputFilePath, key)
+	if activeBuildCache.prev.Outputs[outputFilePath] == key && fileExists(outputFilePath) {
+		return readDataFromFile(outputFilePath)
+	}
+	return render()
+}
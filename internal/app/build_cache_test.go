@@ -0,0 +1,299 @@
+package app
+
+import (
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/civil"
+)
+
+func TestBuildCacheMediaRoundTrip(t *testing.T) {
+	m := media{Type: Image, Uri: "/media/post/p/a.jpg", Caption: "A", thumbs: []thumb{{Uri: "/media/post/p/a.jpg_480_thumb.jpg", Size: 480, Width: 480, Height: 320}},
+		webpUri: "/media/post/p/a.jpg.webp", webpThumbs: []thumb{{Uri: "/media/post/p/a.jpg_480_thumb.jpg.webp", Size: 480, Width: 480, Height: 320}},
+		width: 1200, height: 800, placeholder: "data:image/jpeg;base64,AA==", color: "#c86432", posterUri: "/media/post/p/a.mp4.poster.jpg", duration: 83.5,
+		captureTime: civil.DateTime{Date: civil.Date{Year: 2024, Month: 5, Day: 17}, Time: civil.Time{Hour: 14, Minute: 30}}, camera: "Apple iPhone 13",
+		focus: &focalPoint{x: 30, y: 40}, cropThumbs: []thumb{{Uri: "/media/post/p/a.jpg_480x480_f30x40_thumb.jpg", Size: 480, Width: 480, Height: 480}}}
+	data, err := json.Marshal(m)
+	check(err)
+	var restored media
+	check(json.Unmarshal(data, &restored))
+	if restored.Uri != m.Uri || restored.Caption != m.Caption || len(restored.thumbs) != 1 || restored.thumbs[0] != m.thumbs[0] {
+		t.Errorf("unexpected restored media: %+v", restored)
+	}
+	if restored.webpUri != m.webpUri || len(restored.webpThumbs) != 1 || restored.webpThumbs[0] != m.webpThumbs[0] {
+		t.Errorf("unexpected restored media WebP variants: %+v", restored)
+	}
+	if restored.width != m.width || restored.height != m.height || restored.placeholder != m.placeholder || restored.color != m.color ||
+		restored.posterUri != m.posterUri || restored.duration != m.duration || restored.captureTime != m.captureTime || restored.camera != m.camera {
+		t.Errorf("unexpected restored media image info: %+v", restored)
+	}
+	if restored.focus == nil || *restored.focus != *m.focus || len(restored.cropThumbs) != 1 || restored.cropThumbs[0] != m.cropThumbs[0] {
+		t.Errorf("unexpected restored media focal point / crop thumbnails: %+v", restored)
+	}
+}
+
+func TestBuildCache(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "mbgen-build-cache-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(origDir)
+
+	check(os.WriteFile(configFileName, []byte("theme: test\n"), 0o644))
+	config := defaultConfig()
+	outputFilePath := filepath.Join(deployDirName, indexPageFileName)
+	renderCnt := 0
+	render := func() []byte {
+		renderCnt++
+		return []byte("rendered")
+	}
+	key := func(k string) func() string {
+		return func() string { return k }
+	}
+	pageHash := hashStrings("page source")
+
+	// first build: nothing to reuse
+	loadBuildCache(config, false)
+	if getContentEntityFromBuildCache(Page, "about.md", pageHash) != nil {
+		t.Error("expected no cached page on the first build")
+	}
+	addContentEntityToBuildCache("about.md", pageHash, page{Id: "about", Title: "About", excerptContent: "excerpt"})
+	renderOrReuseOutput(outputFilePath, key("k1"), config, render)
+	createDirIfNotExists(deployDirName)
+	writeDataToFile(outputFilePath, []byte("generated"))
+	saveBuildCache()
+
+	// second build: the unchanged inputs are reused
+	loadBuildCache(config, false)
+	ce := getContentEntityFromBuildCache(Page, "about.md", pageHash)
+	if ce == nil || ce.(page).Title != "About" || ce.(page).excerptContent != "excerpt" {
+		t.Errorf("unexpected cached page: %+v", ce)
+	}
+	if getContentEntityFromBuildCache(Page, "about.md", hashStrings("changed source")) != nil {
+		t.Error("expected a changed page not to be reused")
+	}
+	verifyStringsEqual(string(renderOrReuseOutput(outputFilePath, key("k1"), config, render)), "generated", t)
+	verifyStringsEqual(string(renderOrReuseOutput(outputFilePath, key("k2"), config, render)), "rendered", t)
+	saveBuildCache()
+
+	// full rebuild: nothing is reused
+	loadBuildCache(config, true)
+	if getContentEntityFromBuildCache(Page, "about.md", pageHash) != nil {
+		t.Error("expected no cached page on a full rebuild")
+	}
+	renderOrReuseOutput(outputFilePath, key("k2"), config, render)
+	saveBuildCache()
+
+	// changed site-wide inputs: nothing is reused
+	check(os.WriteFile(configFileName, []byte("theme: test\npageSize: 5\n"), 0o644))
+	loadBuildCache(config, false)
+	renderOrReuseOutput(outputFilePath, key("k2"), config, render)
+	saveBuildCache()
+
+	if renderCnt != 4 {
+		t.Errorf("expected 4 renders, got: %d", renderCnt)
+	}
+	if activeBuildCache != nil {
+		t.Error("expected the build cache to be deactivated once saved")
+	}
+}
+
+func TestLastGenerateEnvironment(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(origDir)
+
+	check(os.WriteFile(configFileName, []byte("theme: test\n"), 0o644))
+	if _, known := lastGenerateEnvironment(); known {
+		t.Error("expected the environment not to be known without a build cache manifest")
+	}
+
+	config := defaultConfig()
+	config.environment = "staging"
+	loadBuildCache(config, false)
+	saveBuildCache()
+	if env, known := lastGenerateEnvironment(); !known || env != "staging" {
+		t.Errorf("unexpected last generate environment: %q (known: %v)", env, known)
+	}
+
+	// the default environment is recorded as well
+	loadBuildCache(defaultConfig(), false)
+	saveBuildCache()
+	if env, known := lastGenerateEnvironment(); !known || env != "" {
+		t.Errorf("unexpected last generate environment: %q (known: %v)", env, known)
+	}
+
+	// a manifest not recording it
+	writeDataToFile(filepath.Join(buildCacheDirName, buildCacheManifestFileName), []byte(`{"inputsHash":"x"}`))
+	if _, known := lastGenerateEnvironment(); known {
+		t.Error("expected the environment not to be known for a manifest not recording it")
+	}
+}
+
+// setUpBuildCacheSiteTest creates a site (in a temp dir) with a single post referencing an image,
+// returning the post media dir path along with a resource loader of the default theme templates
+func setUpBuildCacheSiteTest(t *testing.T, postContent string) (string, resourceLoader) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultThemeTemplatesDir := filepath.Join(origDir, "..", "..", "themes", defaultThemeName, "templates")
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	check(os.WriteFile(configFileName, []byte("theme: test\n"), 0o644))
+	check(os.MkdirAll(markdownPostsDirName, 0o755))
+	check(os.WriteFile(filepath.Join(markdownPostsDirName, "p"+markdownFileExtension), []byte(postContent), 0o644))
+	mediaDirPath := filepath.Join(deployDirName, mediaDirName, deployPostDirName, "p")
+	check(os.MkdirAll(mediaDirPath, 0o755))
+	f, err := os.Create(filepath.Join(mediaDirPath, "a.png"))
+	check(err)
+	check(png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 600, 400))))
+	check(f.Close())
+
+	resLoader := resourceLoader{
+		config: defaultConfig(),
+		loadTemplate: func(templateFileName string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(defaultThemeTemplatesDir, templateFileName))
+		},
+		loadInclude: func(includeFileName string, level templateIncludeLevel) ([]byte, error) {
+			return nil, nil
+		},
+	}
+	return mediaDirPath, resLoader
+}
+
+func TestBuildCacheRegeneratesThumbnails(t *testing.T) {
+	mediaDirPath, resLoader := setUpBuildCacheSiteTest(t, "---\ndate: 2026-04-18\n---\n\n{media:a.png}\n")
+	config := defaultConfig()
+	config.thumbSizes = []int{300}
+	config.cropThumbSizes = []cropThumbSize{{width: 100, height: 100}}
+	config.thumbThreshold = 0
+	thumbFilePath := filepath.Join(mediaDirPath, "a.png_300_thumb.png")
+	cropThumbFilePath := filepath.Join(mediaDirPath, cropThumbFileName("a.png", config.cropThumbSizes[0], focalPoint{x: 50, y: 50}))
+
+	parseBuild := func() post {
+		t.Helper()
+		loadBuildCache(config, false)
+		defer saveBuildCache()
+		posts := parseAllPosts(config, resLoader, processImgThumbnails, false)
+		if len(posts) != 1 {
+			t.Fatalf("expected a single post, got: %d", len(posts))
+		}
+		return posts[0]
+	}
+	parseBuild()
+	if !fileExists(thumbFilePath) || !fileExists(cropThumbFilePath) {
+		t.Fatal("expected the (crop) thumbnails to be generated")
+	}
+	entityHash := func() string {
+		t.Helper()
+		var manifest buildManifest
+		check(json.Unmarshal(readDataFromFile(filepath.Join(buildCacheDirName, buildCacheManifestFileName)), &manifest))
+		entity, ok := manifest.Entities[Post.String()+"/p"+markdownFileExtension]
+		if !ok {
+			t.Fatal("expected the post to be recorded in the build cache manifest")
+		}
+		return entity.Hash
+	}
+	hash := entityHash()
+
+	// the generated (crop) thumbnails don't change the build cache hash
+	parseBuild()
+	verifyStringsEqual(entityHash(), hash, t)
+
+	// the deleted thumbnails are regenerated for the cached post
+	deleteImgThumbnails(mediaDirPath, config)
+	p := parseBuild()
+	if !fileExists(thumbFilePath) || !fileExists(cropThumbFilePath) {
+		t.Error("expected the deleted (crop) thumbnails to be regenerated")
+	}
+	verifyStringsEqual(entityHash(), hash, t)
+	if !strings.Contains(p.Body, "/media/post/p/a.png_300_thumb.png") {
+		t.Errorf("expected the cached post to reference the thumbnail: %s", p.Body)
+	}
+}
+
+func TestBuildCacheManifestRoundTrip(t *testing.T) {
+	_, resLoader := setUpBuildCacheSiteTest(t, "---\ndate: 2026-04-18\n---\n\n{media:a.png}\n\nsee [the about page]({%page:about#team%})\n")
+	config := defaultConfig()
+
+	loadBuildCache(config, false)
+	parsed := parseAllPosts(config, resLoader, processImgThumbnails, false)
+	saveBuildCache()
+
+	// the manifest holding the parsed post (along with its content links) is loaded back
+	var manifest buildManifest
+	if err := json.Unmarshal(readDataFromFile(filepath.Join(buildCacheDirName, buildCacheManifestFileName)), &manifest); err != nil {
+		t.Fatalf("expected the build cache manifest to be loaded: %v", err)
+	}
+	loadBuildCache(config, false)
+	defer saveBuildCache()
+	hash := manifest.Entities[Post.String()+"/p"+markdownFileExtension].Hash
+	ce := getContentEntityFromBuildCache(Post, "p"+markdownFileExtension, hash)
+	if ce == nil {
+		t.Fatal("expected the post to be reused")
+	}
+	p := ce.(post)
+	if len(p.ContentLinks) != 1 || p.ContentLinks[0] != parsed[0].ContentLinks[0] || p.ContentLinks[0].Type != Page {
+		t.Errorf("unexpected cached post content links: %+v", p.ContentLinks)
+	}
+	verifyStringsEqual(p.Body, parsed[0].Body, t)
+}
+
+func TestBuildCacheReusesMediaInfo(t *testing.T) {
+	postContent := "---\ndate: 2026-04-18\n---\n\n{media:a.png}\n\nsee [the about page]({%page:about%})\n"
+	mediaDirPath, resLoader := setUpBuildCacheSiteTest(t, postContent)
+	config := defaultConfig()
+	config.imgPlaceholders = true
+	imgFilePath := filepath.Join(mediaDirPath, "a.png")
+
+	loadBuildCache(config, false)
+	parseAllPosts(config, resLoader, processImgThumbnails, false)
+	saveBuildCache()
+
+	// the recorded image info (along with the parsed post holding the content links) is loaded back
+	manifestFilePath := filepath.Join(buildCacheDirName, buildCacheManifestFileName)
+	var manifest buildManifest
+	check(json.Unmarshal(readDataFromFile(manifestFilePath), &manifest))
+	info, ok := manifest.Media[imgFilePath]
+	if !ok || info.Placeholder == "" {
+		t.Fatalf("expected the image info to be recorded: %+v", info)
+	}
+	info.Color = "#000000"
+	manifest.Media[imgFilePath] = info
+	data, err := json.Marshal(manifest)
+	check(err)
+	writeDataToFile(manifestFilePath, data)
+
+	// the changed post is parsed again, reusing the recorded image info instead of decoding the image
+	check(os.WriteFile(filepath.Join(markdownPostsDirName, "p"+markdownFileExtension), []byte(postContent+"\nchanged\n"), 0o644))
+	loadBuildCache(config, false)
+	parseAllPosts(config, resLoader, processImgThumbnails, false)
+	verifyStringsEqual(getMediaInfo(imgFilePath, true).Color, "#000000", t)
+	saveBuildCache()
+}
@@ -1,7 +1,9 @@
 package app
 
 import (
+	"bufio"
 	_ "embed"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +11,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -23,7 +27,7 @@ var (
 		description: "print out help/usage information",
 		usage: "mbgen help <command>\n\n" +
 			"where <command> is one of the following supported commands to print out help/usage information for:\n\n" +
-			"init, generate, serve, inspect, cleanup, theme, stats, version\n",
+			"init, generate, serve, inspect, check-links, cleanup, theme, stats, deploy, version\n",
 		reqConfig: false,
 		optArgCnt: 1,
 	}
@@ -42,16 +46,21 @@ var (
 		usage: "mbgen cleanup [<target>] [" + commandCleanupOptionDryRun + "]\n\n" +
 			" - <target> (optional) is one of the following:\n\n" +
 			"   - " + commandCleanupTargetContent + ": deletes all previously generated content (" + contentFileExtension + ") files\n" +
-			"     for which markdown (" + markdownFileExtension + ") content files no longer exist\n\n" +
-			"   - " + commandCleanupTargetThumbs + ": deletes all previously generated thumbnail files\n\n" +
-			"   - " + commandCleanupTargetTags + ": deletes all previously generated tag files\n" +
+			"     for which markdown (" + markdownFileExtension + ") content files no longer exist,\n" +
+			"     or which belong to posts pending publication (drafts and posts scheduled in the future)\n" +
+			"     or to another language (along with the deploy dirs of the languages no longer configured),\n" +
+			"     as well as their API (" + apiFileExtension + ") files and the album files of the posts no longer having any gallery\n\n" +
+			"   - " + commandCleanupTargetThumbs + ": deletes all previously generated thumbnail files (along with the WebP variants)\n\n" +
+			"   - " + commandCleanupTargetTags + ": deletes all previously generated tag (and series) files\n" +
 			"     that are no longer referenced by any markdown (" + markdownFileExtension + ") content files\n\n" +
 			"   - " + commandCleanupTargetTagIndex + ": deletes the previously generated tag index file\n\n" +
 			"   - " + commandCleanupTargetCollections + ": deletes all previously generated collection/item dirs\n" +
 			"     that are no longer referenced by any markdown (" + markdownFileExtension + ") content files\n\n" +
 			"   - " + commandCleanupTargetCollectionIndex + ": deletes the previously generated collection index file\n\n" +
 			"   - " + commandCleanupTargetArchive + ": deletes the previously generated archive files\n\n" +
+			"   - " + commandCleanupTargetAlbums + ": deletes the previously generated album files\n\n" +
 			"   - " + commandCleanupTargetSearch + ": deletes all previously generated search files\n\n" +
+			"   - " + commandCleanupTargetAPI + ": deletes the previously generated API dir (as per `apiDir` config option)\n\n" +
 			"   - " + commandCleanupTargetMedia + ": deletes all previously generated media directories\n" +
 			"     in deploy/media/page/ and deploy/media/post/ for which the corresponding\n" +
 			"     markdown (" + markdownFileExtension + ") content files no longer exist\n\n" +
@@ -63,7 +72,9 @@ var (
 			"   - " + commandCleanupTargetTagIndex + ": if `generateTagIndex` config option is disabled\n\n" +
 			"   - " + commandCleanupTargetCollectionIndex + ": if `generateCollectionIndex` config option is disabled\n\n" +
 			"   - " + commandCleanupTargetArchive + ": if `generateArchive` config option is disabled\n\n" +
+			"   - " + commandCleanupTargetAlbums + ": if `generateAlbums` config option is disabled\n\n" +
 			"   - " + commandCleanupTargetSearch + ": if `enableSearch` config option is disabled\n\n" +
+			"   - " + commandCleanupTargetAPI + ": if `generateAPI` config option is disabled\n\n" +
 			"   - " + commandCleanupTargetMedia + ": never (must be specified explicitly)\n\n" +
 			" - optional flags:\n\n" +
 			"   " + commandCleanupOptionDryRun + ": lists files that would be deleted without actually deleting them\n\n",
@@ -73,8 +84,13 @@ var (
 	commandGenerate = /* const */ appCommandDescriptor{
 		command:     "generate",
 		description: "parse content and generate site",
-		usage:       "mbgen generate\n\n",
-		reqConfig:   true,
+		usage: "mbgen generate [" + commandGenerateOptionFull + "]\n\n" +
+			" - the pages/posts and output files whose inputs haven't changed since the previous run are reused\n" +
+			"   from the build cache (kept in the " + buildCacheDirName + " dir)\n\n" +
+			" - optional flags:\n\n" +
+			"   " + commandGenerateOptionFull + ": ignores the build cache, forcing a clean rebuild\n\n",
+		reqConfig: true,
+		optArgCnt: 1,
 	}
 	commandInspect = /* const */ appCommandDescriptor{
 		command:     "inspect",
@@ -82,31 +98,38 @@ var (
 		usage: "mbgen inspect [" + commandInspectOptionFix + "]\n\n" +
 			"reports all detected issues; namely:\n" +
 			" - original images that exceed the `maxImgSize` config option value\n" +
+			" - original images with the metadata to be removed according to the `stripImgMetadata` config option value\n" +
 			" - tag URIs that appear with more than one distinct title across posts (report-only, no auto-fix)\n" +
 			" - collection/item URIs that appear with more than one distinct title across posts (report-only, no auto-fix)\n" +
 			" - meta collection definition errors (duplicate titles, collisions with collection URIs) that would fail the generate command\n" +
-			" - collection directive and meta collection reference issues (unknown collections, directive used in a post, etc.)\n\n" +
+			" - collection directive and meta collection reference issues (unknown collections, directive used in a post, etc.)\n" +
+			" - posts whose file name order disagrees with their date/time order (report-only, no auto-fix)\n\n" +
+			"also lists the posts pending publication (drafts and posts scheduled in the future)\n\n" +
 			"optional flags:\n" +
 			" " + commandInspectOptionFix + ": automatically fixes all auto-fixable issues; namely:\n" +
-			"   - resize and replace the original images that exceed the `maxImgSize` config option value\n\n",
+			"   - resize and replace the original images that exceed the `maxImgSize` config option value\n" +
+			"   - remove the metadata from the original images according to the `stripImgMetadata` config option value\n\n",
 		reqConfig: true,
 		optArgCnt: 1,
 	}
 	commandStats = /* consts */ appCommandDescriptor{
 		command:     "stats",
 		description: "parse content and print out stats",
-		usage:       "mbgen stats\n\n",
-		reqConfig:   true,
+		usage: "mbgen stats\n\n" +
+			"also lists the posts pending publication (drafts and posts scheduled in the future)\n\n",
+		reqConfig: true,
 	}
 	commandServe = /* const */ appCommandDescriptor{
 		command:     "serve",
 		description: "start a web server to serve the site",
-		usage: "mbgen serve [" + commandServeOptionWatchReload + "]\n\n" +
+		usage: "mbgen serve [" + commandServeOptionAdmin + " | " + commandServeOptionWatchReload + "] [" + commandServeOptionPreview + "]\n\n" +
 			"ONE of the following flags can be specified:\n" +
 			" " + commandServeOptionAdmin + " - to render content admin links\n" +
-			" " + commandServeOptionWatchReload + " - to automatically regenerate the site and see the changes being reflected in the browser in real-time when you change any of the markdown content (.md) files in the " + markdownPagesDirName + " or " + markdownPostsDirName + " dirs\n\n",
+			" " + commandServeOptionWatchReload + " - to automatically regenerate the site and see the changes being reflected in the browser in real-time when you change any of the markdown content (.md) files in the " + markdownPagesDirName + " or " + markdownPostsDirName + " dirs\n\n" +
+			"optionally combined with:\n" +
+			" " + commandServeOptionPreview + " - to also render the posts pending publication (drafts and posts scheduled in the future) at their future URIs\n\n",
 		reqConfig: true,
-		optArgCnt: 1,
+		optArgCnt: 2,
 	}
 	commandTheme = /* const */ appCommandDescriptor{
 		command:     "theme",
@@ -126,27 +149,55 @@ var (
 			"   - the default theme name is: \"" + defaultThemeName + "\", but you can also use the \"" + defaultThemeAlias + "\" alias instead\n\n",
 		reqConfig: true,
 	}
+	commandCheckLinks = /* const */ appCommandDescriptor{
+		command:     "check-links",
+		description: "check the generated site for broken internal links",
+		usage: "mbgen check-links\n\n" +
+			"resolves the href/src attributes of the generated (.html) files in the `" + deployDirName + "` dir against the file system\n" +
+			"(run after the generate command), reporting the broken links grouped by source markdown file\n" +
+			"(the links rendered by the templates are reported for the generated file instead)\n\n" +
+			"exits with a non-zero status if any broken links are found (e.g. to fail a CI build)\n\n",
+		reqConfig: true,
+	}
 	commandDeploy = /* const */ appCommandDescriptor{
 		command: "deploy",
 		description: "deploy generated site to a remote server\n\n" +
-			" - requires the `deployPath`, `deployHost`, and `deployUsername` config options to be set in the `config.yml`",
-		usage:     "mbgen deploy\n\n",
+			" - uses the `deployTarget` config option deploy target: " + strings.Join(deployTargetTypeStringValues(), ", ") + " (default: " + defaultDeployTarget.String() + ")\n" +
+			" - requires the `deployPath`, `deployHost`, and `deployUsername` config options to be set in the `config.yml` (rsync/sftp),\n" +
+			"   the `deployBucket` one (s3), or the `deployPath` one (local)\n" +
+			" - the `deploy*` config options can also be set via the `MBGEN_DEPLOY_*` environment variables (e.g. the credentials)\n" +
+			" - each deploy writes a log (the per-stage results and the exit status) into the " + deployLogDirName + " dir",
+		usage: "mbgen deploy [<environment>] [" + commandDeployOptionDryRun + "] [" + commandDeployOptionYes + "] [" + commandDeployOptionRollback + "]\n\n" +
+			" - <environment>: the name of a deploy environment listed under the `environments` config option:\n" +
+			"   its setting overrides (e.g. the deploy target and the `siteBaseURL`) are applied,\n" +
+			"   and the site is (re)generated with them before being deployed (unless on a dry run)\n" +
+			"   (without it, the site is regenerated only if it was last generated for another environment)\n\n" +
+			" - asks for a confirmation if more files than the `deployDeleteThreshold` config option value\n" +
+			"   (default: " + strconv.Itoa(defaultDeployDeleteThreshold) + ") are to be deleted from the destination\n" +
+			"   (failing instead if the standard input is not a terminal)\n\n" +
+			" - optional flags:\n\n" +
+			"   " + commandDeployOptionDryRun + ": lists files that would be added, updated and deleted (per stage) without deploying them\n" +
+			"   " + commandDeployOptionYes + ": skips the deletion confirmation (e.g. when deploying non-interactively)\n" +
+			"   " + commandDeployOptionRollback + ": switches back to the previous release, removing the current one\n" +
+			"   (requires the release-based deploys, see the `deployReleases` config option)\n\n",
 		reqConfig: true,
+		optArgCnt: 3,
 	}
 )
 
 func getSupportedCommands() map[string]tuple2[appCommand, appCommandDescriptor] {
 	return map[string]tuple2[appCommand, appCommandDescriptor]{
-		commandVersion.command:  {_version, commandVersion},
-		commandHelp.command:     {_help, commandHelp},
-		commandInit.command:     {_init, commandInit},
-		commandCleanup.command:  {_cleanup, commandCleanup},
-		commandGenerate.command: {_generate, commandGenerate},
-		commandInspect.command:  {_inspect, commandInspect},
-		commandStats.command:    {_stats, commandStats},
-		commandServe.command:    {_serve, commandServe},
-		commandTheme.command:    {_theme, commandTheme},
-		commandDeploy.command:   {_deploy, commandDeploy},
+		commandVersion.command:    {_version, commandVersion},
+		commandHelp.command:       {_help, commandHelp},
+		commandInit.command:       {_init, commandInit},
+		commandCleanup.command:    {_cleanup, commandCleanup},
+		commandGenerate.command:   {_generate, commandGenerate},
+		commandInspect.command:    {_inspect, commandInspect},
+		commandStats.command:      {_stats, commandStats},
+		commandServe.command:      {_serve, commandServe},
+		commandTheme.command:      {_theme, commandTheme},
+		commandDeploy.command:     {_deploy, commandDeploy},
+		commandCheckLinks.command: {_checkLinks, commandCheckLinks},
 	}
 }
 
@@ -231,8 +282,8 @@ func _cleanup(config appConfig, commandArgs ...string) {
 		case commandCleanupTargetContent, commandCleanupTargetThumbs,
 			commandCleanupTargetTags, commandCleanupTargetTagIndex,
 			commandCleanupTargetCollections, commandCleanupTargetCollectionIndex,
-			commandCleanupTargetArchive, commandCleanupTargetSearch,
-			commandCleanupTargetMedia:
+			commandCleanupTargetArchive, commandCleanupTargetAlbums, commandCleanupTargetSearch,
+			commandCleanupTargetAPI, commandCleanupTargetMedia:
 			target = arg
 		default:
 			sprintln("error: invalid cleanup command argument: " + arg)
@@ -242,7 +293,7 @@ func _cleanup(config appConfig, commandArgs ...string) {
 
 	cleanupContent, cleanupThumbs, cleanupTags := false, false, false
 	cleanupTagIndex, cleanupArchive, cleanupSearch, cleanupMedia := false, false, false, false
-	cleanupCollections, cleanupCollectionIndex := false, false
+	cleanupCollections, cleanupCollectionIndex, cleanupAPI, cleanupAlbums := false, false, false, false
 
 	if target == "" {
 		cleanupContent = true
@@ -250,9 +301,11 @@ func _cleanup(config appConfig, commandArgs ...string) {
 		cleanupCollections = true
 		cleanupThumbs = !config.useThumbs
 		cleanupArchive = !config.generateArchive
+		cleanupAlbums = !config.generateAlbums
 		cleanupTagIndex = !config.generateTagIndex
 		cleanupCollectionIndex = !config.generateCollectionIndex
 		cleanupSearch = !config.enableSearch
+		cleanupAPI = !config.generateAPI
 		// cleanupMedia intentionally stays false
 	} else {
 		switch target {
@@ -270,8 +323,12 @@ func _cleanup(config appConfig, commandArgs ...string) {
 			cleanupCollectionIndex = true
 		case commandCleanupTargetArchive:
 			cleanupArchive = true
+		case commandCleanupTargetAlbums:
+			cleanupAlbums = true
 		case commandCleanupTargetSearch:
 			cleanupSearch = true
+		case commandCleanupTargetAPI:
+			cleanupAPI = true
 		case commandCleanupTargetMedia:
 			cleanupMedia = true
 		}
@@ -285,74 +342,106 @@ func _cleanup(config appConfig, commandArgs ...string) {
 		commandCleanupTargetCollections:     0,
 		commandCleanupTargetCollectionIndex: 0,
 		commandCleanupTargetArchive:         0,
+		commandCleanupTargetAlbums:          0,
 		commandCleanupTargetSearch:          0,
+		commandCleanupTargetAPI:             0,
 		commandCleanupTargetMedia:           0,
 	}
 
 	if cleanupContent {
-		deployPageDirPath := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, deployPageDirName)
-		deployPageDirEntries, err := os.ReadDir(deployPageDirPath)
-		check(err)
-		if len(deployPageDirEntries) > 0 {
-			for _, deployPageEntry := range deployPageDirEntries {
-				deployPageEntryInfo, err := deployPageEntry.Info()
-				check(err)
-				if !deployPageEntryInfo.IsDir() {
-					deployPageEntryFileName := deployPageEntryInfo.Name()
-					pageId := deployPageEntryFileName[:len(deployPageEntryFileName)-len(filepath.Ext(deployPageEntryFileName))]
-					markdownPageFilePath := fmt.Sprintf("%s%c%s", markdownPagesDirName, os.PathSeparator, pageId+markdownFileExtension)
-					if !fileExists(markdownPageFilePath) {
-						deployPageFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployPageDirName, os.PathSeparator, deployPageEntryFileName)
-						if dryRun {
-							sprintln(" - [dry-run] delete page content file: " + deployPageFilePath)
-						} else {
-							sprintln(" - page markdown file no longer exists: " + markdownPageFilePath)
-							deleteFile(deployPageFilePath)
-							sprintln(" - deleted page content file: " + deployPageFilePath)
-						}
-						targetCnt[commandCleanupTargetContent]++
-					}
-				}
+		resLoader := getResourceLoader(config)
+		pageLangs := map[string]string{}
+		for _, p := range parsePages(config, resLoader, nil, false) {
+			pageLangs[p.Id] = contentLanguage(p.Lang, config)
+		}
+		// content files of the posts that got (back) to pending publication are removed as well
+		postLangs := map[string]string{}
+		pendingPostIds := map[string]struct{}{}
+		galleryPostIds := map[string]struct{}{}
+		posts, pendingPosts := splitPendingPosts(parseAllPosts(config, resLoader, nil, false), time.Now())
+		for _, p := range posts {
+			postLangs[p.Id] = contentLanguage(p.Lang, config)
+			if len(p.GalleryMedia) > 0 {
+				galleryPostIds[p.Id] = struct{}{}
 			}
 		}
-		deployPostDirPath := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, deployPostDirName)
-		deployPostDirEntries, err := os.ReadDir(deployPostDirPath)
-		check(err)
-		if len(deployPostDirEntries) > 0 {
-			for _, deployPostEntry := range deployPostDirEntries {
-				deployPostEntryInfo, err := deployPostEntry.Info()
-				check(err)
-				if !deployPostEntryInfo.IsDir() {
-					deployPostEntryFileName := deployPostEntryInfo.Name()
-					postId := deployPostEntryFileName[:len(deployPostEntryFileName)-len(filepath.Ext(deployPostEntryFileName))]
-					markdownPostFilePath := fmt.Sprintf("%s%c%s", markdownPostsDirName, os.PathSeparator, postId+markdownFileExtension)
-					if !fileExists(markdownPostFilePath) {
-						deployPostFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployPostDirName, os.PathSeparator, deployPostEntryFileName)
-						if dryRun {
-							sprintln(" - [dry-run] delete post content file: " + deployPostFilePath)
-						} else {
-							sprintln(" - post markdown file no longer exists: " + markdownPostFilePath)
-							deleteFile(deployPostFilePath)
-							sprintln(" - deleted post content file: " + deployPostFilePath)
-						}
-						targetCnt[commandCleanupTargetContent]++
-					}
+		for _, p := range pendingPosts {
+			pendingPostIds[p.Id] = struct{}{}
+		}
+		// the content files of each language are generated into the deploy dir of the language
+		for _, lang := range siteLanguages(config) {
+			lang = contentLanguage(lang, config)
+			deployLangDirPath := filepath.Join(deployDirName, langURIPrefix(lang, config))
+			deployPageDirPath := filepath.Join(deployLangDirPath, deployPageDirName)
+			stalePageReason := func(pageId string) string {
+				markdownPageFilePath := fmt.Sprintf("%s%c%s", markdownPagesDirName, os.PathSeparator, pageId+markdownFileExtension)
+				if !fileExists(markdownPageFilePath) {
+					return "page markdown file no longer exists: " + markdownPageFilePath
+				}
+				if pageLang, ok := pageLangs[pageId]; ok && pageLang != lang {
+					return "page belongs to another language: " + markdownPageFilePath
 				}
+				return ""
 			}
+			targetCnt[commandCleanupTargetContent] += cleanupContentFiles(deployPageDirPath, "page", stalePageReason, dryRun)
+			stalePostReason := func(postId string) string {
+				markdownPostFilePath := fmt.Sprintf("%s%c%s", markdownPostsDirName, os.PathSeparator, postId+markdownFileExtension)
+				if !fileExists(markdownPostFilePath) {
+					return "post markdown file no longer exists: " + markdownPostFilePath
+				}
+				if _, pending := pendingPostIds[postId]; pending {
+					return "post is pending publication: " + markdownPostFilePath
+				}
+				if postLang, ok := postLangs[postId]; ok && postLang != lang {
+					return "post belongs to another language: " + markdownPostFilePath
+				}
+				return ""
+			}
+			deployPostDirPath := filepath.Join(deployLangDirPath, deployPostDirName)
+			targetCnt[commandCleanupTargetContent] += cleanupContentFiles(deployPostDirPath, "post", stalePostReason, dryRun)
+			// the API documents of the pages/posts are removed along with their content files
+			deployAPIDirPath := filepath.Join(deployLangDirPath, filepath.FromSlash(config.apiDir))
+			targetCnt[commandCleanupTargetContent] += cleanupContentFiles(filepath.Join(deployAPIDirPath, deployPageDirName), "page API", stalePageReason, dryRun)
+			targetCnt[commandCleanupTargetContent] += cleanupContentFiles(filepath.Join(deployAPIDirPath, deployPostDirName), "post API", stalePostReason, dryRun)
+			// the album pages of the posts no longer having any gallery are removed as well
+			deployAlbumsDirPath := filepath.Join(deployLangDirPath, deployAlbumsDirName)
+			targetCnt[commandCleanupTargetContent] += cleanupContentFiles(deployAlbumsDirPath, "album", func(postId string) string {
+				if reason := stalePostReason(postId); reason != "" {
+					return reason
+				}
+				if _, ok := galleryPostIds[postId]; !ok {
+					return "post no longer has any gallery: " + postId
+				}
+				return ""
+			}, dryRun)
+		}
+		// the whole deploy dirs of the languages no longer configured are removed
+		for _, deployLangDirPath := range staleLanguageDirs(config) {
+			if dryRun {
+				sprintln(" - [dry-run] delete language dir: " + deployLangDirPath)
+			} else {
+				sprintln(" - language no longer configured: " + filepath.Base(deployLangDirPath))
+				deleteIfExists(deployLangDirPath)
+				sprintln(" - deleted language dir: " + deployLangDirPath)
+			}
+			targetCnt[commandCleanupTargetContent]++
 		}
 	}
 	if cleanupThumbs {
 		resLoader := getResourceLoader(config)
 		if dryRun {
+			var targetCntMutex sync.Mutex
 			thumbHandler := func(imgDirPath string, cfg appConfig) {
 				if dirExists(imgDirPath) {
-					imgFiles, err := listFilesByExt(imgDirPath, thumbImageFileExtensions...)
+					imgFiles, err := listFilesByExt(imgDirPath, slices.Concat(thumbImageFileExtensions, []string{webpFileExtension})...)
 					check(err)
 					for _, imgFile := range imgFiles {
-						if thumbImgFileNameRegexp.FindStringSubmatch(imgFile) != nil {
+						if isImgThumbnailOrVariant(imgFile) {
 							thumbFilePath := fmt.Sprintf("%s%c%s", imgDirPath, os.PathSeparator, imgFile)
 							sprintln(" - [dry-run] delete thumbnail: " + thumbFilePath)
+							targetCntMutex.Lock()
 							targetCnt[commandCleanupTargetThumbs]++
+							targetCntMutex.Unlock()
 						}
 					}
 				}
@@ -371,13 +460,21 @@ func _cleanup(config appConfig, commandArgs ...string) {
 		}
 	}
 	if cleanupTags {
-		deployTagsDirPath := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, deployTagsDirName)
-		deployTagsDirEntries, err := os.ReadDir(deployTagsDirPath)
-		check(err)
-		if len(deployTagsDirEntries) > 0 {
-			posts := parsePosts(config, getResourceLoader(config), nil, false)
+		posts := parsePosts(config, getResourceLoader(config), nil, false)
+		// the tag dirs of each language are generated into the deploy dir of the language
+		for _, lang := range siteLanguages(config) {
+			lang = contentLanguage(lang, config)
+			deployTagsDirPath := filepath.Join(deployDirName, langURIPrefix(lang, config), deployTagsDirName)
+			if !dirExists(deployTagsDirPath) {
+				continue
+			}
+			deployTagsDirEntries, err := os.ReadDir(deployTagsDirPath)
+			check(err)
 			var tags []string
 			for _, post := range posts {
+				if contentLanguage(post.Lang, config) != lang {
+					continue
+				}
 				for _, tag := range post.Tags {
 					t := normalizeURIString(tag)
 					if !slices.Contains(tags, t) {
@@ -391,7 +488,7 @@ func _cleanup(config appConfig, commandArgs ...string) {
 				if deployTagDirEntryInfo.IsDir() {
 					deployTagDirName := deployTagDirEntryInfo.Name()
 					if !slices.Contains(tags, deployTagDirName) {
-						deployTagDirPath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployTagsDirName, os.PathSeparator, deployTagDirName)
+						deployTagDirPath := fmt.Sprintf("%s%c%s", deployTagsDirPath, os.PathSeparator, deployTagDirName)
 						if dryRun {
 							sprintln(" - [dry-run] delete tag dir: " + deployTagDirPath)
 						} else {
@@ -404,6 +501,43 @@ func _cleanup(config appConfig, commandArgs ...string) {
 				}
 			}
 		}
+		// the series dirs (generated into the deploy dir of each language as well) are handled along with the tag ones
+		for _, lang := range siteLanguages(config) {
+			lang = contentLanguage(lang, config)
+			deploySeriesDirPath := filepath.Join(deployDirName, langURIPrefix(lang, config), deploySeriesDirName)
+			if !dirExists(deploySeriesDirPath) {
+				continue
+			}
+			deploySeriesDirEntries, err := os.ReadDir(deploySeriesDirPath)
+			check(err)
+			var series []string
+			for _, post := range posts {
+				if contentLanguage(post.Lang, config) != lang {
+					continue
+				}
+				if s := normalizeURIString(post.Series); s != "" && !slices.Contains(series, s) {
+					series = append(series, s)
+				}
+			}
+			for _, deploySeriesDirEntry := range deploySeriesDirEntries {
+				deploySeriesDirEntryInfo, err := deploySeriesDirEntry.Info()
+				check(err)
+				if deploySeriesDirEntryInfo.IsDir() {
+					seriesDirName := deploySeriesDirEntryInfo.Name()
+					if !slices.Contains(series, seriesDirName) {
+						seriesDirPath := fmt.Sprintf("%s%c%s", deploySeriesDirPath, os.PathSeparator, seriesDirName)
+						if dryRun {
+							sprintln(" - [dry-run] delete series dir: " + seriesDirPath)
+						} else {
+							sprintln(" - series no longer referenced: " + seriesDirName)
+							deleteIfExists(seriesDirPath)
+							sprintln(" - deleted series dir: " + seriesDirPath)
+						}
+						targetCnt[commandCleanupTargetTags]++
+					}
+				}
+			}
+		}
 	}
 	if cleanupTagIndex {
 		deployTagIndexPath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployTagsDirName, os.PathSeparator, indexPageFileName)
@@ -512,6 +646,22 @@ func _cleanup(config appConfig, commandArgs ...string) {
 			}
 		}
 	}
+	if cleanupAlbums {
+		for _, lang := range siteLanguages(config) {
+			deployAlbumsPath := filepath.Join(deployDirName, langURIPrefix(contentLanguage(lang, config), config), deployAlbumsDirName)
+			if dryRun {
+				if dirExists(deployAlbumsPath) {
+					sprintln(" - [dry-run] delete albums dir: " + deployAlbumsPath)
+					targetCnt[commandCleanupTargetAlbums]++
+				}
+			} else {
+				if deleteIfExists(deployAlbumsPath) {
+					sprintln(" - deleted albums dir: " + deployAlbumsPath)
+					targetCnt[commandCleanupTargetAlbums]++
+				}
+			}
+		}
+	}
 	if cleanupSearch {
 		deploySearchIndexPath := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, searchIndexFileName)
 		deploySearchPath := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, searchPageFileName)
@@ -545,6 +695,20 @@ func _cleanup(config appConfig, commandArgs ...string) {
 			}
 		}
 	}
+	if cleanupAPI {
+		deployAPIDirPath := filepath.Join(deployDirName, filepath.FromSlash(config.apiDir))
+		if dryRun {
+			if dirExists(deployAPIDirPath) {
+				sprintln(" - [dry-run] delete API dir: " + deployAPIDirPath)
+				targetCnt[commandCleanupTargetAPI]++
+			}
+		} else {
+			if deleteIfExists(deployAPIDirPath) {
+				sprintln(" - deleted API dir: " + deployAPIDirPath)
+				targetCnt[commandCleanupTargetAPI]++
+			}
+		}
+	}
 	if cleanupMedia {
 		for _, ceType := range []struct{ mediaSubDir, markdownDir string }{
 			{deployPageDirName, markdownPagesDirName},
@@ -585,7 +749,9 @@ func _cleanup(config appConfig, commandArgs ...string) {
 		commandCleanupTargetTags,
 		commandCleanupTargetTagIndex,
 		commandCleanupTargetArchive,
+		commandCleanupTargetAlbums,
 		commandCleanupTargetSearch,
+		commandCleanupTargetAPI,
 	}
 	lines := []interface{}{"[------ cleanup -------]\n"}
 	for _, t := range orderedTargets {
@@ -594,7 +760,51 @@ func _cleanup(config appConfig, commandArgs ...string) {
 	sprintln(lines...)
 }
 
+// cleanupContentFiles deletes the content files of the given deploy dir (e.g. `deploy/post` or `deploy/de/post`)
+// for which staleReason returns a (non-empty) reason, returning the number of the deleted files
+// (or the ones that would be deleted in the dry-run mode)
+func cleanupContentFiles(deployContentDirPath string, ceTypeName string, staleReason func(ceId string) string, dryRun bool) int {
+	if !dirExists(deployContentDirPath) {
+		return 0
+	}
+	deployContentDirEntries, err := os.ReadDir(deployContentDirPath)
+	check(err)
+	cnt := 0
+	for _, deployContentEntry := range deployContentDirEntries {
+		if deployContentEntry.IsDir() {
+			continue
+		}
+		deployContentEntryFileName := deployContentEntry.Name()
+		ceId := deployContentEntryFileName[:len(deployContentEntryFileName)-len(filepath.Ext(deployContentEntryFileName))]
+		reason := staleReason(ceId)
+		if reason == "" {
+			continue
+		}
+		deployContentFilePath := fmt.Sprintf("%s%c%s", deployContentDirPath, os.PathSeparator, deployContentEntryFileName)
+		if dryRun {
+			sprintln(" - [dry-run] delete " + ceTypeName + " content file: " + deployContentFilePath)
+		} else {
+			sprintln(" - " + reason)
+			deleteFile(deployContentFilePath)
+			sprintln(" - deleted " + ceTypeName + " content file: " + deployContentFilePath)
+		}
+		cnt++
+	}
+	return cnt
+}
+
 func _generate(config appConfig, commandArgs ...string) {
+	fullRebuild := false
+	for _, arg := range commandArgs {
+		if arg == commandGenerateOptionFull {
+			fullRebuild = true
+		} else {
+			sprintln("error: invalid generate command argument: " + arg)
+			usageHelp := "usage:\n\n" + commandGenerate.usage
+			usage(usageHelp, 1)
+		}
+	}
+
 	createDirIfNotExists(deployDirName)
 
 	resLoader := getResourceLoader(config)
@@ -612,7 +822,11 @@ func _generate(config appConfig, commandArgs ...string) {
 		writeDataToFileIfChanged(searchJSFilePath, []byte(searchJS))
 	}
 
+	writeCodeHighlightCSS(config)
+
+	loadBuildCache(config, fullRebuild)
 	processAndHandleStats(config, resLoader, false)
+	saveBuildCache()
 }
 
 func _inspect(config appConfig, commandArgs ...string) {
@@ -636,7 +850,7 @@ func _inspect(config appConfig, commandArgs ...string) {
 		collectionIssues := reportCollectionTitleDuplicates(config)
 		resLoader := getResourceLoader(config)
 		pages := parsePages(config, resLoader, nil, false)
-		posts := parsePosts(config, resLoader, nil, false)
+		posts, pendingPosts := splitPendingPosts(parseAllPosts(config, resLoader, nil, false), time.Now())
 		// appends non-fatal usage warnings to pages/posts (surfaced via reportContentWarnings below)
 		collectionUsageErrs := validateCollectionUsage(pages, posts, aggregateCollections(posts))
 		if len(collectionUsageErrs) > 0 {
@@ -645,12 +859,19 @@ func _inspect(config appConfig, commandArgs ...string) {
 				sprintln("   - " + e)
 			}
 		}
-		directiveIssues := reportContentWarnings(pages, posts)
+		// appends content link warnings to pages/posts (surfaced via reportContentWarnings below)
+		validateContentLinks(pages, posts, pendingPosts)
+		allPosts := slices.Concat(posts, pendingPosts)
+		directiveIssues := reportContentWarnings(pages, allPosts)
+		postOrderIssues := reportPostOrderMismatches(allPosts, config)
+		seriesIssues := reportSeriesPartIssues(allPosts)
+		// informational only: pending posts are not an issue
+		reportPendingPosts(pendingPosts)
 		if mediaIssues {
 			sprintln(" - run the following command to fix the media issues found:\n\n" +
 				"   mbgen inspect " + commandInspectOptionFix)
 		}
-		if !mediaIssues && !tagIssues && !collectionIssues && len(collectionUsageErrs) == 0 && !directiveIssues {
+		if !mediaIssues && !tagIssues && !collectionIssues && len(collectionUsageErrs) == 0 && !directiveIssues && !postOrderIssues && !seriesIssues {
 			sprintln(" - no issues found")
 		}
 	}
@@ -717,60 +938,134 @@ func reportCollectionTitleDuplicates(config appConfig) bool {
 	return true
 }
 
+// reportPostOrderMismatches reports the adjacent posts for which the file name order
+// and the date/time order disagree. Report-only (no auto-fix). Returns true when mismatches were found.
+func reportPostOrderMismatches(posts []post, config appConfig) bool {
+	mismatches := inspectPostOrderMismatches(posts)
+	if len(mismatches) == 0 {
+		return false
+	}
+	if config.postOrder == FileNamePostOrder {
+		sprintln(" - post file name order disagrees with the date/time order (posts are listed by file name; rename the files or set the `postOrder` config option to `" + DateTimePostOrder.String() + "`):")
+	} else {
+		sprintln(" - post file name order disagrees with the date/time order (posts are listed by date/time as per the `postOrder` config option):")
+	}
+	for _, m := range mismatches {
+		sprintln("   - " + m)
+	}
+	return true
+}
+
+// reportSeriesPartIssues reports the post series with missing or duplicate part numbers.
+// Report-only (no auto-fix). Returns true when issues were found.
+func reportSeriesPartIssues(posts []post) bool {
+	issues := inspectSeriesParts(posts)
+	if len(issues) == 0 {
+		return false
+	}
+	sprintln(" - post series part number issues (fix manually in post frontmatter):")
+	for _, issue := range issues {
+		sprintln("   - " + issue)
+	}
+	return true
+}
+
+// reportPendingPosts lists the posts pending publication (drafts and posts scheduled in the future),
+// if any, along with their publication status
+func reportPendingPosts(pendingPosts []post) {
+	if len(pendingPosts) == 0 {
+		return
+	}
+	sprintln(" - posts pending publication:")
+	for _, p := range pendingPosts {
+		status := "draft"
+		if !p.Draft {
+			status = "scheduled for " + p.publishTime().Format("2006-01-02 15:04")
+		}
+		sprintln("   - " + markdownPostsDirName + "/" + p.Id + markdownFileExtension + ": " + status)
+	}
+}
+
 func _stats(config appConfig, commandArgs ...string) {
 	resLoader := getResourceLoader(config)
-	handleStats(process(parsePages(config, resLoader, nil, false),
-		parsePosts(config, resLoader, nil, false),
-		resLoader, nil))
+	posts, pendingPosts := splitPendingPosts(parseAllPosts(config, resLoader, nil, false), time.Now())
+	pStats := process(parsePages(config, resLoader, nil, false), posts, resLoader, nil)
+	pStats.pendingCnt = len(pendingPosts)
+	handleStats(pStats)
+	reportPendingPosts(pendingPosts)
+}
+
+func _checkLinks(config appConfig, commandArgs ...string) {
+	if !dirExists(deployDirName) {
+		exitWithError("deploy dir not found: run the generate command first")
+	}
+	homePages := resolveHomePages(parsePages(config, getResourceLoader(config), nil, false), config)
+	if cnt := reportBrokenLinks(findBrokenLinks(config, homePages)); cnt > 0 {
+		exitWithError(fmt.Sprintf("%d broken link(s) found", cnt))
+	}
+	sprintln(" - no broken links found")
 }
 
 func _serve(config appConfig, commandArgs ...string) {
 	resLoader := getResourceLoader(config)
 	var wChan chan watchReloadData
-	var admin bool
-	if commandArgs != nil && len(commandArgs) > 0 {
-		if len(commandArgs) == 1 {
-			arg := commandArgs[0]
-			if arg == commandServeOptionAdmin {
-				admin = true
-			} else if arg == commandServeOptionWatchReload {
-				wChan = make(chan watchReloadData)
-				mdFileExt := []string{markdownFileExtension}
-				go watchDirForChanges(markdownPagesDirName, mdFileExt, false, func(dwEvent dirWatchEvent) {
-					filePath := strings.Split(dwEvent.filePath, string(os.PathSeparator))
-					fileName := filePath[len(filePath)-1]
-					pageId := fileName[:len(fileName)-len(filepath.Ext(fileName))]
-					handleMdContentDirWatchEvent(dwEvent, Page, pageId, config, resLoader, wChan)
-				})
-				go watchDirForChanges(markdownPostsDirName, mdFileExt, false, func(dwEvent dirWatchEvent) {
-					filePath := strings.Split(dwEvent.filePath, string(os.PathSeparator))
-					fileName := filePath[len(filePath)-1]
-					postId := fileName[:len(fileName)-len(filepath.Ext(fileName))]
-					handleMdContentDirWatchEvent(dwEvent, Post, postId, config, resLoader, wChan)
-				})
-				mediaDir := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, mediaDirName)
-				go watchDirForChanges(mediaDir, thumbImageFileExtensions, true, func(dwEvent dirWatchEvent) {
-					filePath := strings.Split(dwEvent.filePath, string(os.PathSeparator))
-					if filePath[len(filePath)-2] == sharedMediaDirName {
-						handleSharedMediaDirWatchEvent(dwEvent, config, resLoader, wChan)
-					} else {
-						ceType := contentEntityTypeFromString(filePath[len(filePath)-3])
-						ceId := filePath[len(filePath)-2]
-						handleContentEntityMediaDirWatchEvent(dwEvent, ceType, ceId, config, resLoader, wChan)
-					}
-				})
-			} else {
-				sprintln("error: invalid serve command argument: " + commandArgs[0])
-				usageHelp := "usage:\n\n" + commandServe.usage
-				usage(usageHelp, 1)
-			}
-		} else {
-			sprintln("error: invalid number of serve command arguments (max allowed: " + strconv.Itoa(commandServe.optArgCnt) + ")")
+	var admin, watchReload, preview bool
+	for _, arg := range commandArgs {
+		switch arg {
+		case commandServeOptionAdmin:
+			admin = true
+		case commandServeOptionWatchReload:
+			watchReload = true
+		case commandServeOptionPreview:
+			preview = true
+		default:
+			sprintln("error: invalid serve command argument: " + arg)
 			usageHelp := "usage:\n\n" + commandServe.usage
 			usage(usageHelp, 1)
 		}
 	}
-	listenAndServe(fmt.Sprintf("%s:%d", config.serveHost, config.servePort), admin, wChan, config, resLoader)
+	if admin && watchReload {
+		sprintln("error: the " + commandServeOptionAdmin + " and " + commandServeOptionWatchReload + " flags can't be used together")
+		usageHelp := "usage:\n\n" + commandServe.usage
+		usage(usageHelp, 1)
+	}
+	if watchReload {
+		wChan = make(chan watchReloadData)
+		mdFileExt := []string{markdownFileExtension}
+		go watchDirForChanges(markdownPagesDirName, mdFileExt, false, func(dwEvent dirWatchEvent) {
+			filePath := strings.Split(dwEvent.filePath, string(os.PathSeparator))
+			fileName := filePath[len(filePath)-1]
+			pageId := fileName[:len(fileName)-len(filepath.Ext(fileName))]
+			handleMdContentDirWatchEvent(dwEvent, Page, pageId, config, resLoader, wChan)
+		})
+		go watchDirForChanges(markdownPostsDirName, mdFileExt, false, func(dwEvent dirWatchEvent) {
+			filePath := strings.Split(dwEvent.filePath, string(os.PathSeparator))
+			fileName := filePath[len(filePath)-1]
+			postId := fileName[:len(fileName)-len(filepath.Ext(fileName))]
+			handleMdContentDirWatchEvent(dwEvent, Post, postId, config, resLoader, wChan)
+		})
+		mediaDir := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, mediaDirName)
+		go watchDirForChanges(mediaDir, thumbImageFileExtensions, true, func(dwEvent dirWatchEvent) {
+			filePath := strings.Split(dwEvent.filePath, string(os.PathSeparator))
+			if filePath[len(filePath)-2] == sharedMediaDirName {
+				handleSharedMediaDirWatchEvent(dwEvent, config, resLoader, wChan)
+			} else {
+				ceType := contentEntityTypeFromString(filePath[len(filePath)-3])
+				ceId := filePath[len(filePath)-2]
+				handleContentEntityMediaDirWatchEvent(dwEvent, ceType, ceId, config, resLoader, wChan)
+			}
+		})
+	}
+	if preview {
+		_, pendingPosts := splitPendingPosts(parseAllPosts(config, resLoader, nil, false), time.Now())
+		if len(pendingPosts) > 0 {
+			sprintln(" - previewing posts pending publication:")
+			for _, p := range pendingPosts {
+				sprintln(fmt.Sprintf("   - %s%s:%d/%s/%s%s", httpProtocol, config.serveHost, config.servePort, deployPostDirName, p.Id, contentFileExtension))
+			}
+		}
+	}
+	listenAndServe(fmt.Sprintf("%s:%d", config.serveHost, config.servePort), admin, preview, wChan, config, resLoader)
 }
 
 func handleMdContentDirWatchEvent(dwEvent dirWatchEvent, contentEntityType contentEntityType, contentEntityId string, config appConfig, resLoader resourceLoader, wChan chan watchReloadData) {
@@ -810,6 +1105,13 @@ func handleMdContentDirWatchEvent(dwEvent dirWatchEvent, contentEntityType conte
 		deleteIfExists(contentFilePath)
 		sprintln(" - deleted content file: " + contentFilePath)
 		// ==================================================
+		// delete the corresponding API document
+		// ==================================================
+		apiFilePath := filepath.Join(deployDirName, filepath.FromSlash(config.apiDir), ceType, contentEntityId+apiFileExtension)
+		if deleteIfExists(apiFilePath) {
+			sprintln(" - deleted API file: " + apiFilePath)
+		}
+		// ==================================================
 		// delete the corresponding media directory
 		// ==================================================
 		mediaDirPath := fmt.Sprintf("%s%c%s%c%s%c%s", deployDirName, os.PathSeparator, mediaDirName, os.PathSeparator, ceType, os.PathSeparator, contentEntityId)
@@ -945,8 +1247,11 @@ func _theme(config appConfig, commandArgs ...string) {
 		if !themeInstalled {
 			sprintln("theme is not installed: " + theme)
 		} else {
-			config.theme = themeDir
-			writeConfig(config)
+			// the config is written back as read from the config file,
+			// leaving out the deploy options set via environment variables
+			persistedConfig := readPersistedConfig()
+			persistedConfig.theme = themeDir
+			writeConfig(persistedConfig)
 			sprintln(" - " + configFileName + " updated to activate new theme: " + theme)
 		}
 	case commandThemeActionInstall:
@@ -1035,18 +1340,94 @@ func copyThemeIncludes(theme string) {
 }
 
 func _deploy(config appConfig, commandArgs ...string) {
-	if config.deployPath == "" {
-		sprintln("error: no deploy path specified in the config file")
-	} else {
-		deployDestination := config.deployPath
-		if config.deployHost != "" {
-			deployDestination = config.deployHost + ":" + deployDestination
-			if config.deployUsername != "" {
-				deployDestination = config.deployUsername + "@" + deployDestination
+	run := &deployRun{
+		confirm:        confirmFromStdin,
+		nonInteractive: !isStdinTerminal(),
+		started:        time.Now(),
+	}
+	environment := ""
+	rollback := false
+	for _, arg := range commandArgs {
+		switch arg {
+		case commandDeployOptionDryRun:
+			run.dryRun = true
+		case commandDeployOptionYes:
+			run.confirm = nil
+		case commandDeployOptionRollback:
+			rollback = true
+		default:
+			if environment != "" || strings.HasPrefix(arg, "-") {
+				sprintln("error: invalid deploy command argument: " + arg)
+				usage("usage:\n\n"+commandDeploy.usage, 1)
+			}
+			environment = arg
+		}
+	}
+	if rollback && run.dryRun {
+		sprintln("error: the " + commandDeployOptionRollback + " and " + commandDeployOptionDryRun + " deploy command options are mutually exclusive")
+		usage("usage:\n\n"+commandDeploy.usage, 1)
+	}
+	if environment != "" {
+		config = readEnvironmentConfig(environment)
+		printConfig(config)
+	}
+	if !rollback {
+		// the deploy dir is shared by all the environments, so it's regenerated
+		// unless it's known to have been last generated for the (default) one being deployed
+		lastEnvironment, known := lastGenerateEnvironment()
+		regenerateReason := ""
+		switch {
+		case environment != "":
+			regenerateReason = "deploying the " + environment + " environment"
+		case !known:
+			regenerateReason = "the environment the deploy dir was generated for is not known"
+		case lastEnvironment != config.environment:
+			regenerateReason = "the deploy dir was last generated for another environment (" + lastEnvironment + ")"
+		}
+		if regenerateReason != "" {
+			if run.dryRun {
+				// a dry run doesn't change anything (the deploy dir included), so the plan is based on the deploy dir as is
+				sprintln(" - [dry-run] " + regenerateReason + ": the site would be regenerated before being deployed")
+			} else {
+				sprintln(" - " + regenerateReason + ": regenerating ...")
+				_generate(config)
 			}
 		}
-		rsyncDeploy(deployDestination)
 	}
+	run.deleteThreshold = config.deployDeleteThreshold
+	target, err := newDeployTarget(config)
+	if err != nil {
+		exitWithError("error: " + err.Error())
+	}
+	if environment != "" {
+		run.logf("environment: %s", environment)
+	}
+	if rollback {
+		err = rollbackRelease(target, run)
+	} else {
+		err = deploy(target, run)
+	}
+	err = errors.Join(err, target.close())
+	logFilePath := writeDeployLog(run, config.deployTarget, err)
+	sprintln(" - deploy log: " + logFilePath)
+	if err != nil {
+		exitWithError(err.Error())
+	}
+}
+
+// confirmFromStdin prints the given prompt and reads the answer from the standard input
+// (anything other than `y`/`yes`, including no input at all, is treated as a refusal)
+func confirmFromStdin(prompt string) bool {
+	fmt.Print(prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// isStdinTerminal checks whether the standard input is a terminal (so that the confirmations can be asked for)
+func isStdinTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 func getResourceLoader(config appConfig) resourceLoader {
@@ -1073,6 +1454,7 @@ func handleStats(stats stats) {
 		"[------- stats --------]\n",
 		fmt.Sprintf(" - pages: %d", stats.pageCnt),
 		fmt.Sprintf(" - posts: %d", stats.postCnt),
+		fmt.Sprintf(" - posts pending publication: %d", stats.pendingCnt),
 		fmt.Sprintf(" - tags: %d", stats.tagCnt),
 		fmt.Sprintf(" - collections: %d", stats.collCnt),
 		fmt.Sprintf(" - collection items: %d", stats.collItemCnt),
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"image"
+	"image/png"
 	"os"
 	"path/filepath"
 	"testing"
@@ -55,6 +57,55 @@ func TestCleanupTagsPreservesReferencedMultiWordTag(t *testing.T) {
 	}
 }
 
+func TestCleanupSeries(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(origDir)
+
+	if err := os.MkdirAll(markdownPostsDirName, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	postContent := "---\n" +
+		"date: 2026-04-18\n" +
+		"series:\n" +
+		"  title: Building a Blog\n" +
+		"  part: 1\n" +
+		"---\n\n" +
+		"Body.\n"
+	postPath := filepath.Join(markdownPostsDirName, "sample-post"+markdownFileExtension)
+	if err := os.WriteFile(postPath, []byte(postContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	referencedSeriesDir := filepath.Join(deployDirName, deploySeriesDirName, "building-a-blog")
+	renamedSeriesDir := filepath.Join(deployDirName, deploySeriesDirName, "blog-building")
+	for _, dir := range []string{referencedSeriesDir, renamedSeriesDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_cleanup(defaultConfig(), commandCleanupTargetTags, commandCleanupOptionDryRun)
+	if !dirExists(renamedSeriesDir) {
+		t.Errorf("series dir should have been kept on a dry run: %s", renamedSeriesDir)
+	}
+
+	_cleanup(defaultConfig(), commandCleanupTargetTags)
+
+	if !dirExists(referencedSeriesDir) {
+		t.Errorf("referenced series dir was incorrectly deleted: %s", referencedSeriesDir)
+	}
+	if dirExists(renamedSeriesDir) {
+		t.Errorf("unreferenced series dir should have been deleted but still exists: %s", renamedSeriesDir)
+	}
+}
+
 func TestCleanupCollectionsPreservesReferenced(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "mbgen-cleanup-collections-*")
 	if err != nil {
@@ -138,3 +189,91 @@ func TestCleanupCollectionIndex(t *testing.T) {
 		t.Errorf("collection index file should have been deleted but still exists: %s", collIndexPath)
 	}
 }
+
+func TestCleanupContentLanguages(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(origDir)
+
+	if err := os.MkdirAll(markdownPostsDirName, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for postId, postContent := range map[string]string{
+		"hallo":   "---\ndate: 2026-04-18\nlang: de\ntags:\n  - Reise\n---\n\nBody.\n",
+		"hello":   "---\ndate: 2026-04-18\n---\n\nBody.\n",
+		"entwurf": "---\ndate: 2026-04-18\nlang: de\ndraft: true\n---\n\nBody.\n",
+		"album":   "---\ndate: 2026-04-18\n---\n\n{gallery}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(markdownPostsDirName, postId+markdownFileExtension), []byte(postContent), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deployFiles := map[string]bool{
+		// the post language changed from the default one
+		filepath.Join(deployDirName, deployPostDirName, "hallo.html"):       false,
+		filepath.Join(deployDirName, deployPostDirName, "hello.html"):       true,
+		filepath.Join(deployDirName, "de", deployPostDirName, "hallo.html"): true,
+		// the post language changed to the default one
+		filepath.Join(deployDirName, "de", deployPostDirName, "hello.html"): false,
+		// the post got back to draft
+		filepath.Join(deployDirName, "de", deployPostDirName, "entwurf.html"): false,
+		// the post markdown file no longer exists
+		filepath.Join(deployDirName, "de", deployPostDirName, "deleted.html"):             false,
+		filepath.Join(deployDirName, "de", deployTagsDirName, "reise", indexPageFileName): true,
+		filepath.Join(deployDirName, "de", deployTagsDirName, "stale", indexPageFileName): false,
+		// the language is no longer configured
+		filepath.Join(deployDirName, "fr", deployPostDirName, "bonjour.html"): false,
+		// custom (non generated) deploy dirs are preserved
+		filepath.Join(deployDirName, "res", "custom.css"): true,
+		// the album pages of the posts no longer having any gallery
+		filepath.Join(deployDirName, deployAlbumsDirName, "album.html"):         true,
+		filepath.Join(deployDirName, deployAlbumsDirName, "hello.html"):         false,
+		filepath.Join(deployDirName, "de", deployAlbumsDirName, "entwurf.html"): false,
+		// the API documents of the posts no longer published (or belonging to another language)
+		filepath.Join(deployDirName, defaultAPIDir, deployPostDirName, "hello.json"):         true,
+		filepath.Join(deployDirName, defaultAPIDir, deployPostDirName, "hallo.json"):         false,
+		filepath.Join(deployDirName, "de", defaultAPIDir, deployPostDirName, "hallo.json"):   true,
+		filepath.Join(deployDirName, "de", defaultAPIDir, deployPostDirName, "entwurf.json"): false,
+		filepath.Join(deployDirName, defaultAPIDir, deployPageDirName, "deleted.json"):       false,
+		filepath.Join(deployDirName, defaultAPIDir, apiIndexFileName):                        true,
+	}
+	for deployFile := range deployFiles {
+		if err := os.MkdirAll(filepath.Dir(deployFile), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(deployFile, []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	galleryMediaDirPath := filepath.Join(deployDirName, mediaDirName, deployPostDirName, "album")
+	if err := os.MkdirAll(galleryMediaDirPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(galleryMediaDirPath, "a.png"))
+	check(err)
+	check(png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 40, 30))))
+	check(f.Close())
+
+	config := defaultConfig()
+	config.languages = []string{"en", "de"}
+	// the gallery directive is rendered with the default theme template
+	config.theme = filepath.Join(origDir, "..", "..", "themes", defaultThemeName)
+	_cleanup(config, commandCleanupTargetContent)
+	_cleanup(config, commandCleanupTargetTags)
+
+	for deployFile, exists := range deployFiles {
+		if fileExists(deployFile) != exists {
+			t.Errorf("expected the file to exist (%t): %s", exists, deployFile)
+		}
+	}
+	if dirExists(filepath.Join(deployDirName, "fr")) {
+		t.Error("the deploy dir of the language no longer configured should have been deleted")
+	}
+}
@@ -2,11 +2,13 @@ package app
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"gopkg.in/yaml.v3"
 )
@@ -18,8 +20,19 @@ func defaultConfig() appConfig {
 		generateArchive:               defaultGenerateArchive,
 		generateTagIndex:              defaultGenerateTagIndex,
 		generateCollectionIndex:       defaultGenerateCollectionIndex,
+		generateAlbums:                defaultGenerateAlbums,
 		enableSearch:                  defaultEnableSearch,
+		generateSitemap:               defaultGenerateSitemap,
+		generateRobotsTxt:             defaultGenerateRobotsTxt,
+		generateAPI:                   defaultGenerateAPI,
+		apiDir:                        defaultAPIDir,
 		pageSize:                      defaultPageSize,
+		relatedPostCnt:                defaultRelatedPostCnt,
+		workerCnt:                     defaultWorkerCnt,
+		postOrder:                     defaultPostOrder,
+		undatedPosts:                  defaultUndatedPosts,
+		codeHighlighting:              defaultCodeHighlighting,
+		codeHighlightStyle:            defaultCodeHighlightStyle,
 		resizeOrigImages:              defaultResizeOrigImages,
 		maxImgSize:                    defaultMaxImgSize,
 		useThumbs:                     defaultUseThumbs,
@@ -27,12 +40,37 @@ func defaultConfig() appConfig {
 		thumbThreshold:                defaultThumbThreshold,
 		jpegQuality:                   defaultJPEGQuality,
 		pngCompressionLevel:           DefaultCompression,
+		webpVariants:                  defaultWebPVariants,
+		imgPlaceholders:               defaultImgPlaceholders,
+		stripImgMetadata:              defaultStripImgMetadata,
 		serveHost:                     defaultServeHost,
 		servePort:                     defaultServePort,
+		deployTarget:                  defaultDeployTarget,
+		deployPort:                    defaultDeployPort,
+		deployRegion:                  defaultDeployRegion,
+		deployDeleteThreshold:         defaultDeployDeleteThreshold,
+		deployReleases:                defaultDeployReleases,
 	}
 }
 
 func readConfig() appConfig {
+	return readEnvironmentConfig("")
+}
+
+// readEnvironmentConfig reads the config file, applying the setting overrides
+// of the given (named) deploy environment, if any (see the `environments` config option),
+// as well as the deploy options set via environment variables
+func readEnvironmentConfig(environment string) appConfig {
+	return readConfigFile(environment, true)
+}
+
+// readPersistedConfig reads the config file as is, without the deploy options set via environment variables
+// (which must never be written back to it, see writeConfig)
+func readPersistedConfig() appConfig {
+	return readConfigFile("", false)
+}
+
+func readConfigFile(environment string, envVarOverrides bool) appConfig {
 	if !fileExists(configFileName) {
 		exitWithError(configFileName + " not found")
 	}
@@ -40,9 +78,34 @@ func readConfig() appConfig {
 	config := defaultConfig()
 	configFile, err := os.ReadFile(configFileName)
 	check(err)
-	cm := make(map[string]string)
-	err = yaml.Unmarshal(configFile, &cm)
+	var cn map[string]yaml.Node
+	err = yaml.Unmarshal(configFile, &cn)
 	check(err)
+	cm := make(map[string]string)
+	for key, node := range cn {
+		if key == "environments" {
+			err = node.Decode(&config.environments)
+		} else {
+			var value string
+			err = node.Decode(&value)
+			cm[key] = value
+		}
+		if err != nil {
+			exitWithError(fmt.Sprintf("invalid config `%s` property value: %s", key, err))
+		}
+	}
+
+	var envOverrides map[string]string
+	if environment != "" {
+		var ok bool
+		envOverrides, ok = config.environments[environment]
+		if !ok {
+			exitWithError(fmt.Sprintf("unknown deploy environment: '%s' (expected one of: %s)",
+				environment, strings.Join(slices.Sorted(maps.Keys(config.environments)), ", ")))
+		}
+		maps.Copy(cm, envOverrides)
+		config.environment = environment
+	}
 
 	config.siteBaseURL = cm["siteBaseURL"]
 
@@ -57,6 +120,19 @@ func readConfig() appConfig {
 
 	config.homePage = cm["homePage"]
 
+	languages := cm["languages"]
+	if languages != "" {
+		for _, lang := range strings.Split(strings.ToLower(languages), ",") {
+			lang = strings.TrimSpace(lang)
+			if !isValidLanguage(lang) {
+				exitWithError(fmt.Sprintf("invalid language: '%s' (expected a language code, e.g. en or pt-br, not clashing with the generated content dirs)", lang))
+			}
+			if !slices.Contains(config.languages, lang) {
+				config.languages = append(config.languages, lang)
+			}
+		}
+	}
+
 	generateFeedFormats := cm["generateFeeds"]
 	if generateFeedFormats != "" {
 		v := strings.ToLower(generateFeedFormats)
@@ -77,15 +153,45 @@ func readConfig() appConfig {
 		}
 	}
 
-	if len(config.generateFeeds) > 0 {
+	generateSitemap := cm["generateSitemap"]
+	if generateSitemap != "" {
+		v := strings.ToLower(generateSitemap)
+		config.generateSitemap = v != "no" && v != "false"
+	}
+
+	generateRobotsTxt := cm["generateRobotsTxt"]
+	if generateRobotsTxt != "" {
+		v := strings.ToLower(generateRobotsTxt)
+		config.generateRobotsTxt = v != "no" && v != "false"
+	}
+
+	generateAPI := cm["generateAPI"]
+	if generateAPI != "" {
+		v := strings.ToLower(generateAPI)
+		config.generateAPI = v != "no" && v != "false"
+	}
+
+	apiDir := strings.Trim(cm["apiDir"], "/")
+	if apiDir != "" {
+		if !isValidAPIDir(apiDir) {
+			println(
+				" - invalid config API dir value: "+cm["apiDir"]+" (expected a relative dir path inside the deploy dir, not clashing with the generated content dirs)",
+				" - will use the default value instead",
+			)
+		} else {
+			config.apiDir = apiDir
+		}
+	}
+
+	if len(config.generateFeeds) > 0 || config.generateSitemap {
 		if config.siteBaseURL == "" {
-			exitWithError("error: config `siteBaseURL` is required when `generateFeeds` is enabled")
+			exitWithError("error: config `siteBaseURL` is required when `generateFeeds` or `generateSitemap` is enabled")
 		}
 		if !strings.HasPrefix(config.siteBaseURL, httpProtocol) && !strings.HasPrefix(config.siteBaseURL, httpsProtocol) {
 			exitWithError("error: config `siteBaseURL` must start with `http://` or `https://`")
 		}
-		config.siteBaseURL = strings.TrimSuffix(config.siteBaseURL, "/")
 	}
+	config.siteBaseURL = strings.TrimSuffix(config.siteBaseURL, "/")
 
 	feedPostCnt := cm["feedPostCnt"]
 	if feedPostCnt != "" {
@@ -122,6 +228,12 @@ func readConfig() appConfig {
 		config.generateCollectionIndex = v != "no" && v != "false"
 	}
 
+	generateAlbums := cm["generateAlbums"]
+	if generateAlbums != "" {
+		v := strings.ToLower(generateAlbums)
+		config.generateAlbums = v != "no" && v != "false"
+	}
+
 	enableSearch := cm["enableSearch"]
 	if enableSearch != "" {
 		v := strings.ToLower(enableSearch)
@@ -141,6 +253,83 @@ func readConfig() appConfig {
 		}
 	}
 
+	relatedPostCnt := cm["relatedPostCnt"]
+	if relatedPostCnt != "" {
+		rpc, err := strconv.Atoi(relatedPostCnt)
+		if err != nil || rpc < 0 {
+			println(
+				" - invalid config related post count value: "+relatedPostCnt,
+				" - will use the default value instead",
+			)
+		} else {
+			config.relatedPostCnt = rpc
+		}
+	}
+
+	workerCnt := cm["workerCnt"]
+	if workerCnt != "" {
+		wc, err := strconv.Atoi(workerCnt)
+		if err != nil || wc < 0 {
+			println(
+				" - invalid config worker count value: "+workerCnt,
+				" - will use the default value instead",
+			)
+		} else {
+			config.workerCnt = wc
+		}
+	}
+
+	postOrder := cm["postOrder"]
+	if postOrder != "" {
+		po := postOrderFromString(postOrder)
+		if po == "" {
+			println(
+				" - invalid config post order value: "+postOrder+" (allowed values: "+strings.Join(postOrderStringValues(), ", ")+")",
+				" - will use the default value instead",
+			)
+		} else {
+			config.postOrder = po
+		}
+	}
+
+	undatedPosts := cm["undatedPosts"]
+	if undatedPosts != "" {
+		up := undatedPostPolicyFromString(undatedPosts)
+		if up == "" {
+			println(
+				" - invalid config undated posts value: "+undatedPosts+" (allowed values: "+strings.Join(undatedPostPolicyStringValues(), ", ")+")",
+				" - will use the default value instead",
+			)
+		} else {
+			config.undatedPosts = up
+		}
+	}
+
+	codeHighlighting := cm["codeHighlighting"]
+	if codeHighlighting != "" {
+		ch := codeHighlightingFromString(codeHighlighting)
+		if ch == "" {
+			println(
+				" - invalid config code highlighting value: "+codeHighlighting+" (allowed values: "+strings.Join(codeHighlightingStringValues(), ", ")+")",
+				" - will use the default value instead",
+			)
+		} else {
+			config.codeHighlighting = ch
+		}
+	}
+
+	codeHighlightStyle := cm["codeHighlightStyle"]
+	if codeHighlightStyle != "" {
+		if !isCodeHighlightStyle(codeHighlightStyle) {
+			println(
+				" - invalid config code highlight style value: "+codeHighlightStyle+" (see https://xyproto.github.io/splash/docs/ for the available styles)",
+				" - will use the default value instead",
+			)
+		} else {
+			config.codeHighlightStyle = codeHighlightStyle
+		}
+	}
+
 	resizeOrigImages := cm["resizeOrigImages"]
 	if resizeOrigImages != "" {
 		v := strings.ToLower(resizeOrigImages)
@@ -175,8 +364,24 @@ func readConfig() appConfig {
 	thumbSizes := cm["thumbSizes"]
 	if thumbSizes != "" {
 		var sizes []int
+		var cropSizes []cropThumbSize
 		tSizes := strings.Split(thumbSizes, ",")
 		for _, ts := range tSizes {
+			// `WxH` is a fixed-aspect (crop) thumbnail size
+			if cm := cropThumbSizeRegexp.FindStringSubmatch(strings.TrimSpace(ts)); cm != nil {
+				w, _ := strconv.Atoi(cm[1])
+				h, _ := strconv.Atoi(cm[2])
+				if w < minAllowedCropThumbSize || h < minAllowedCropThumbSize {
+					println(
+						" - invalid config crop thumb size value: "+strings.TrimSpace(ts)+
+							fmt.Sprintf(" (min allowed width/height value: %d)", minAllowedCropThumbSize),
+						" - will ignore the crop thumb size",
+					)
+				} else if cs := (cropThumbSize{width: w, height: h}); !slices.Contains(cropSizes, cs) {
+					cropSizes = append(cropSizes, cs)
+				}
+				continue
+			}
 			s, cErr := strconv.Atoi(strings.TrimSpace(ts))
 			if cErr != nil || s < minAllowedThumbWidth {
 				var errMsg string
@@ -198,6 +403,7 @@ func readConfig() appConfig {
 		if sizes != nil {
 			config.thumbSizes = sizes
 		}
+		config.cropThumbSizes = cropSizes
 	}
 
 	thumbThreshold := cm["thumbThreshold"]
@@ -251,6 +457,44 @@ func readConfig() appConfig {
 		}
 	}
 
+	webpVariants := cm["webpVariants"]
+	if webpVariants != "" {
+		wv := webpVariantsPolicyFromString(webpVariants)
+		if wv == "" {
+			println(
+				" - invalid config webp variants value: "+webpVariants+" (allowed values: "+strings.Join(webpVariantsPolicyStringValues(), ", ")+")",
+				" - will use the default value instead",
+			)
+		} else {
+			if strings.ToLower(webpVariants) == webpVariantsAllValue {
+				println(
+					" - config webp variants value: "+webpVariants+" (the lossless WebP variants of the JPEG images aren't smaller than the images)",
+					" - will generate the WebP variants of the PNG images only",
+				)
+			}
+			config.webpVariants = wv
+		}
+	}
+
+	imgPlaceholders := cm["imgPlaceholders"]
+	if imgPlaceholders != "" {
+		v := strings.ToLower(imgPlaceholders)
+		config.imgPlaceholders = v != "no" && v != "false"
+	}
+
+	stripImgMetadata := cm["stripImgMetadata"]
+	if stripImgMetadata != "" {
+		sp := imgMetadataStripPolicyFromString(stripImgMetadata)
+		if sp == "" {
+			println(
+				" - invalid config strip image metadata value: "+stripImgMetadata+" (allowed values: "+strings.Join(imgMetadataStripPolicyStringValues(), ", ")+")",
+				" - will use the default value instead",
+			)
+		} else {
+			config.stripImgMetadata = sp
+		}
+	}
+
 	if serveHost, ok := cm["serveHost"]; ok && serveHost != "" {
 		config.serveHost = serveHost
 	}
@@ -265,6 +509,33 @@ func readConfig() appConfig {
 		}
 	}
 
+	// the deploy options (credentials in particular) can be set via environment variables as well,
+	// taking precedence over the config file ones, e.g. `MBGEN_DEPLOY_PASSWORD` for `deployPassword`,
+	// except for the ones explicitly overridden by the deploy environment
+	if envVarOverrides {
+		for _, key := range deployConfigKeys {
+			if _, ok := envOverrides[key]; ok {
+				continue
+			}
+			if value, ok := os.LookupEnv(configEnvVarName(key)); ok && value != "" {
+				cm[key] = value
+			}
+		}
+	}
+
+	deployTarget := cm["deployTarget"]
+	if deployTarget != "" {
+		dt := deployTargetTypeFromString(deployTarget)
+		if dt == "" {
+			println(
+				" - invalid config deploy target value: "+deployTarget+" (allowed values: "+strings.Join(deployTargetTypeStringValues(), ", ")+")",
+				" - will use the default value instead",
+			)
+		} else {
+			config.deployTarget = dt
+		}
+	}
+
 	if deployPath, ok := cm["deployPath"]; ok && deployPath != "" {
 		config.deployPath = deployPath
 	}
@@ -273,13 +544,84 @@ func readConfig() appConfig {
 		config.deployHost = deployHost
 	}
 
+	if deployPort, ok := cm["deployPort"]; ok && deployPort != "" {
+		dp, err := strconv.Atoi(deployPort)
+		if err != nil || dp <= 0 {
+			println(
+				" - invalid config deploy port value: "+deployPort,
+				" - will use the default value instead",
+			)
+		} else {
+			config.deployPort = dp
+		}
+	}
+
 	if deployUsername, ok := cm["deployUsername"]; ok && deployUsername != "" {
 		config.deployUsername = deployUsername
 	}
 
+	config.deployPassword = cm["deployPassword"]
+	config.deployKeyFile = cm["deployKeyFile"]
+	config.deployKnownHostsFile = cm["deployKnownHostsFile"]
+	config.deployBucket = cm["deployBucket"]
+	config.deployEndpoint = cm["deployEndpoint"]
+
+	if deployRegion, ok := cm["deployRegion"]; ok && deployRegion != "" {
+		config.deployRegion = deployRegion
+	}
+
+	config.deployAccessKey = cm["deployAccessKey"]
+	config.deploySecretKey = cm["deploySecretKey"]
+
+	if deployDeleteThreshold, ok := cm["deployDeleteThreshold"]; ok && deployDeleteThreshold != "" {
+		ddt, err := strconv.Atoi(deployDeleteThreshold)
+		if err != nil || ddt < 0 {
+			println(
+				" - invalid config deploy delete threshold value: "+deployDeleteThreshold,
+				" - will use the default value instead",
+			)
+		} else {
+			config.deployDeleteThreshold = ddt
+		}
+	}
+
+	if deployReleases, ok := cm["deployReleases"]; ok && deployReleases != "" {
+		dr, err := strconv.Atoi(deployReleases)
+		// the previous release is kept along with the current one, so that it can be rolled back to
+		if err != nil || dr < 0 || dr == 1 {
+			println(
+				" - invalid config deploy releases value: "+deployReleases+" (expected 0, or at least 2)",
+				" - will use the default value instead",
+			)
+		} else {
+			config.deployReleases = dr
+		}
+	}
+
 	return config
 }
 
+// deployConfigKeys lists the deploy config options that can be set via environment variables as well
+var deployConfigKeys = []string{
+	"deployTarget", "deployPath", "deployHost", "deployPort", "deployUsername", "deployPassword",
+	"deployKeyFile", "deployKnownHostsFile", "deployBucket", "deployEndpoint", "deployRegion",
+	"deployAccessKey", "deploySecretKey", "deployDeleteThreshold", "deployReleases",
+}
+
+// configEnvVarName returns the name of the environment variable of the given config option,
+// e.g. `MBGEN_DEPLOY_ACCESS_KEY` for `deployAccessKey`
+func configEnvVarName(key string) string {
+	var sb strings.Builder
+	sb.WriteString(configEnvVarPrefix)
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 {
+			sb.WriteRune('_')
+		}
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
+
 func writeConfig(config appConfig) {
 	yml := ""
 
@@ -321,6 +663,13 @@ func writeConfig(config appConfig) {
 		yml += "homePage: " + config.homePage
 	}
 
+	yml += "\n"
+	if len(config.languages) > 0 {
+		yml += "languages: " + strings.Join(config.languages, ", ")
+	} else {
+		yml += "#languages: en, de"
+	}
+
 	yml += "\n"
 	var generateArchive bool
 	if defaultGenerateArchive == config.generateArchive {
@@ -366,6 +715,21 @@ func writeConfig(config appConfig) {
 		yml += "no"
 	}
 
+	yml += "\n"
+	var generateAlbums bool
+	if defaultGenerateAlbums == config.generateAlbums {
+		generateAlbums = defaultGenerateAlbums
+		yml += "#generateAlbums: "
+	} else {
+		generateAlbums = config.generateAlbums
+		yml += "generateAlbums: "
+	}
+	if generateAlbums {
+		yml += "yes"
+	} else {
+		yml += "no"
+	}
+
 	yml += "\n"
 	if len(config.generateFeeds) > 0 {
 		yml += "generateFeeds: " + strings.Join(config.generateFeeds, ", ")
@@ -402,6 +766,58 @@ func writeConfig(config appConfig) {
 		yml += "no"
 	}
 
+	yml += "\n"
+	var generateSitemap bool
+	if defaultGenerateSitemap == config.generateSitemap {
+		generateSitemap = defaultGenerateSitemap
+		yml += "#generateSitemap: "
+	} else {
+		generateSitemap = config.generateSitemap
+		yml += "generateSitemap: "
+	}
+	if generateSitemap {
+		yml += "yes"
+	} else {
+		yml += "no"
+	}
+
+	yml += "\n"
+	var generateRobotsTxt bool
+	if defaultGenerateRobotsTxt == config.generateRobotsTxt {
+		generateRobotsTxt = defaultGenerateRobotsTxt
+		yml += "#generateRobotsTxt: "
+	} else {
+		generateRobotsTxt = config.generateRobotsTxt
+		yml += "generateRobotsTxt: "
+	}
+	if generateRobotsTxt {
+		yml += "yes"
+	} else {
+		yml += "no"
+	}
+
+	yml += "\n"
+	var generateAPI bool
+	if defaultGenerateAPI == config.generateAPI {
+		generateAPI = defaultGenerateAPI
+		yml += "#generateAPI: "
+	} else {
+		generateAPI = config.generateAPI
+		yml += "generateAPI: "
+	}
+	if generateAPI {
+		yml += "yes"
+	} else {
+		yml += "no"
+	}
+
+	yml += "\n"
+	if defaultAPIDir == config.apiDir {
+		yml += "#apiDir: " + defaultAPIDir
+	} else {
+		yml += "apiDir: " + config.apiDir
+	}
+
 	yml += "\n"
 	if defaultPageSize == config.pageSize {
 		yml += "#pageSize: " + strconv.Itoa(defaultPageSize)
@@ -409,6 +825,48 @@ func writeConfig(config appConfig) {
 		yml += "pageSize: " + strconv.Itoa(config.pageSize)
 	}
 
+	yml += "\n"
+	if defaultRelatedPostCnt == config.relatedPostCnt {
+		yml += "#relatedPostCnt: " + strconv.Itoa(defaultRelatedPostCnt)
+	} else {
+		yml += "relatedPostCnt: " + strconv.Itoa(config.relatedPostCnt)
+	}
+
+	yml += "\n"
+	if defaultWorkerCnt == config.workerCnt {
+		yml += "#workerCnt: " + strconv.Itoa(defaultWorkerCnt)
+	} else {
+		yml += "workerCnt: " + strconv.Itoa(config.workerCnt)
+	}
+
+	yml += "\n"
+	if defaultPostOrder == config.postOrder {
+		yml += "#postOrder: " + defaultPostOrder.String()
+	} else {
+		yml += "postOrder: " + config.postOrder.String()
+	}
+
+	yml += "\n"
+	if defaultUndatedPosts == config.undatedPosts {
+		yml += "#undatedPosts: " + defaultUndatedPosts.String()
+	} else {
+		yml += "undatedPosts: " + config.undatedPosts.String()
+	}
+
+	yml += "\n"
+	if defaultCodeHighlighting == config.codeHighlighting {
+		yml += "#codeHighlighting: " + defaultCodeHighlighting.String()
+	} else {
+		yml += "codeHighlighting: " + config.codeHighlighting.String()
+	}
+
+	yml += "\n"
+	if defaultCodeHighlightStyle == config.codeHighlightStyle {
+		yml += "#codeHighlightStyle: " + defaultCodeHighlightStyle
+	} else {
+		yml += "codeHighlightStyle: " + config.codeHighlightStyle
+	}
+
 	yml += "\n"
 	var resizeOrigImages bool
 	if defaultResizeOrigImages == config.resizeOrigImages {
@@ -450,10 +908,10 @@ func writeConfig(config appConfig) {
 	sort.Slice(config.thumbSizes, func(i, j int) bool {
 		return i < j
 	})
-	if slices.Equal(defaultThumbSizes, config.thumbSizes) {
+	if slices.Equal(defaultThumbSizes, config.thumbSizes) && len(config.cropThumbSizes) == 0 {
 		yml += "#thumbSizes: " + strings.Trim(strings.Join(strings.Fields(fmt.Sprint(defaultThumbSizes)), ", "), "[]")
 	} else {
-		yml += "thumbSizes: " + strings.Trim(strings.Join(strings.Fields(fmt.Sprint(config.thumbSizes)), ", "), "[]")
+		yml += "thumbSizes: " + fmtThumbSizes(config)
 	}
 
 	yml += "\n"
@@ -477,6 +935,35 @@ func writeConfig(config appConfig) {
 		yml += "pngCompressionLevel: " + config.pngCompressionLevel.String()
 	}
 
+	yml += "\n"
+	if defaultWebPVariants == config.webpVariants {
+		yml += "#webpVariants: " + defaultWebPVariants.String()
+	} else {
+		yml += "webpVariants: " + config.webpVariants.String()
+	}
+
+	yml += "\n"
+	var imgPlaceholders bool
+	if defaultImgPlaceholders == config.imgPlaceholders {
+		imgPlaceholders = defaultImgPlaceholders
+		yml += "#imgPlaceholders: "
+	} else {
+		imgPlaceholders = config.imgPlaceholders
+		yml += "imgPlaceholders: "
+	}
+	if imgPlaceholders {
+		yml += "yes"
+	} else {
+		yml += "no"
+	}
+
+	yml += "\n"
+	if defaultStripImgMetadata == config.stripImgMetadata {
+		yml += "#stripImgMetadata: " + defaultStripImgMetadata.String()
+	} else {
+		yml += "stripImgMetadata: " + config.stripImgMetadata.String()
+	}
+
 	yml += "\n"
 	if defaultServeHost == config.serveHost {
 		yml += "#serveHost: " + defaultServeHost
@@ -491,6 +978,13 @@ func writeConfig(config appConfig) {
 		yml += "servePort: " + strconv.Itoa(config.servePort)
 	}
 
+	yml += "\n"
+	if defaultDeployTarget == config.deployTarget {
+		yml += "#deployTarget: " + defaultDeployTarget.String()
+	} else {
+		yml += "deployTarget: " + config.deployTarget.String()
+	}
+
 	yml += "\n"
 	if config.deployPath != "" {
 		yml += "deployPath: " + config.deployPath
@@ -505,6 +999,13 @@ func writeConfig(config appConfig) {
 		yml += "#deployHost: "
 	}
 
+	yml += "\n"
+	if defaultDeployPort == config.deployPort {
+		yml += "#deployPort: " + strconv.Itoa(defaultDeployPort)
+	} else {
+		yml += "deployPort: " + strconv.Itoa(config.deployPort)
+	}
+
 	yml += "\n"
 	if config.deployUsername != "" {
 		yml += "deployUsername: " + config.deployUsername
@@ -512,9 +1013,99 @@ func writeConfig(config appConfig) {
 		yml += "#deployUsername: "
 	}
 
+	yml += "\n"
+	if config.deployPassword != "" {
+		yml += "deployPassword: " + yamlString(config.deployPassword)
+	} else {
+		yml += "#deployPassword: "
+	}
+
+	yml += "\n"
+	if config.deployKeyFile != "" {
+		yml += "deployKeyFile: " + config.deployKeyFile
+	} else {
+		yml += "#deployKeyFile: "
+	}
+
+	yml += "\n"
+	if config.deployKnownHostsFile != "" {
+		yml += "deployKnownHostsFile: " + config.deployKnownHostsFile
+	} else {
+		yml += "#deployKnownHostsFile: "
+	}
+
+	yml += "\n"
+	if config.deployBucket != "" {
+		yml += "deployBucket: " + config.deployBucket
+	} else {
+		yml += "#deployBucket: "
+	}
+
+	yml += "\n"
+	if config.deployEndpoint != "" {
+		yml += "deployEndpoint: " + config.deployEndpoint
+	} else {
+		yml += "#deployEndpoint: "
+	}
+
+	yml += "\n"
+	if defaultDeployRegion == config.deployRegion {
+		yml += "#deployRegion: " + defaultDeployRegion
+	} else {
+		yml += "deployRegion: " + config.deployRegion
+	}
+
+	yml += "\n"
+	if config.deployAccessKey != "" {
+		yml += "deployAccessKey: " + yamlString(config.deployAccessKey)
+	} else {
+		yml += "#deployAccessKey: "
+	}
+
+	yml += "\n"
+	if config.deploySecretKey != "" {
+		yml += "deploySecretKey: " + yamlString(config.deploySecretKey)
+	} else {
+		yml += "#deploySecretKey: "
+	}
+
+	yml += "\n"
+	if defaultDeployDeleteThreshold == config.deployDeleteThreshold {
+		yml += "#deployDeleteThreshold: " + strconv.Itoa(defaultDeployDeleteThreshold)
+	} else {
+		yml += "deployDeleteThreshold: " + strconv.Itoa(config.deployDeleteThreshold)
+	}
+
+	yml += "\n"
+	if defaultDeployReleases == config.deployReleases {
+		yml += "#deployReleases: " + strconv.Itoa(defaultDeployReleases)
+	} else {
+		yml += "deployReleases: " + strconv.Itoa(config.deployReleases)
+	}
+
+	yml += "\n"
+	if len(config.environments) > 0 {
+		envYml, err := yaml.Marshal(map[string]map[string]map[string]string{"environments": config.environments})
+		check(err)
+		yml += strings.TrimSuffix(string(envYml), "\n")
+	} else {
+		yml += "#environments:\n" +
+			"#    staging:\n" +
+			"#        siteBaseURL: https://staging.example.com\n" +
+			"#        deployPath: /var/www/staging"
+	}
+
 	writeDataToFileIfChanged(configFileName, []byte(yml))
 }
 
+// yamlString returns the given config value as a YAML scalar,
+// quoted if needed (e.g. credentials containing `#` or `: `)
+func yamlString(value string) string {
+	out, err := yaml.Marshal(value)
+	check(err)
+	return strings.TrimSuffix(string(out), "\n")
+}
+
 func printConfig(config appConfig) {
 	sprintln("[ ------ config ------ ]\n")
 
@@ -536,6 +1127,10 @@ func printConfig(config appConfig) {
 		println(" - home page: " + config.homePage)
 	}
 
+	if len(config.languages) > 0 {
+		println(" - languages: " + strings.Join(config.languages, ", "))
+	}
+
 	var generateArchive string
 	if config.generateArchive {
 		generateArchive = "yes"
@@ -552,6 +1147,14 @@ func printConfig(config appConfig) {
 	}
 	println(" - generate tag index: " + generateTagIndex)
 
+	var generateAlbums string
+	if config.generateAlbums {
+		generateAlbums = "yes"
+	} else {
+		generateAlbums = "no"
+	}
+	println(" - generate albums: " + generateAlbums)
+
 	if len(config.generateFeeds) > 0 {
 		println(" - generate feeds: " + strings.Join(config.generateFeeds, ", "))
 		println(fmt.Sprintf(" - feed post count: %d", config.feedPostCnt))
@@ -568,7 +1171,39 @@ func printConfig(config appConfig) {
 	}
 	println(" - enable search: " + enableSearch)
 
+	if config.generateSitemap {
+		var generateRobotsTxt string
+		if config.generateRobotsTxt {
+			generateRobotsTxt = "yes"
+		} else {
+			generateRobotsTxt = "no"
+		}
+		println(" - generate sitemap: yes")
+		println(" - generate robots.txt: " + generateRobotsTxt)
+	} else {
+		println(" - generate sitemap: no")
+	}
+
+	if config.generateAPI {
+		println(" - generate API: yes")
+		println(" - API dir: " + config.apiDir)
+	} else {
+		println(" - generate API: no")
+	}
+
 	println(fmt.Sprintf(" - page size: %d", config.pageSize))
+	println(fmt.Sprintf(" - related post count: %d", config.relatedPostCnt))
+	println(fmt.Sprintf(" - worker count: %d", workerPoolSize(config)))
+
+	println(" - post order: " + config.postOrder.String())
+	if config.postOrder == DateTimePostOrder {
+		println(" - undated posts: " + config.undatedPosts.String())
+	}
+
+	println(" - code highlighting: " + config.codeHighlighting.String())
+	if config.codeHighlighting != NoCodeHighlighting {
+		println(" - code highlight style: " + config.codeHighlightStyle)
+	}
 
 	var resizeOrigImages string
 	if config.resizeOrigImages {
@@ -588,7 +1223,7 @@ func printConfig(config appConfig) {
 
 	println(" - use thumbs: " + usingThumbs)
 
-	println(" - thumb sizes: " + strings.Trim(strings.Join(strings.Fields(fmt.Sprint(config.thumbSizes)), ", "), "[]"))
+	println(" - thumb sizes: " + fmtThumbSizes(config))
 
 	println(fmt.Sprintf(" - thumb threshold: %.2f", config.thumbThreshold))
 
@@ -596,10 +1231,25 @@ func printConfig(config appConfig) {
 
 	println(" - png compression level: " + config.pngCompressionLevel.String())
 
+	println(" - webp variants: " + config.webpVariants.String())
+
+	var usingImgPlaceholders string
+	if config.imgPlaceholders {
+		usingImgPlaceholders = "yes"
+	} else {
+		usingImgPlaceholders = "no"
+	}
+
+	println(" - image placeholders: " + usingImgPlaceholders)
+
+	println(" - strip image metadata: " + config.stripImgMetadata.String())
+
 	println(" - serve host: " + config.serveHost)
 
 	println(fmt.Sprintf(" - serve port: %d", config.servePort))
 
+	println(" - deploy target: " + config.deployTarget.String())
+
 	if config.deployPath != "" {
 		println(" - deploy path: " + config.deployPath)
 	}
@@ -608,9 +1258,51 @@ func printConfig(config appConfig) {
 		println(" - deploy host: " + config.deployHost)
 	}
 
+	if config.deployTarget == SFTPDeployTarget {
+		println(fmt.Sprintf(" - deploy port: %d", config.deployPort))
+	}
+
 	if config.deployUsername != "" {
 		println(" - deploy username: " + config.deployUsername)
 	}
 
+	if config.deployBucket != "" {
+		println(" - deploy bucket: " + config.deployBucket)
+	}
+
+	if config.deployEndpoint != "" {
+		println(" - deploy endpoint: " + config.deployEndpoint)
+	}
+
+	if config.deployTarget == S3DeployTarget {
+		println(" - deploy region: " + config.deployRegion)
+	}
+
+	println(fmt.Sprintf(" - deploy delete threshold: %d", config.deployDeleteThreshold))
+
+	if config.deployReleases > 0 {
+		println(fmt.Sprintf(" - deploy releases: %d", config.deployReleases))
+	}
+
+	if len(config.environments) > 0 {
+		println(" - deploy environments: " + strings.Join(slices.Sorted(maps.Keys(config.environments)), ", "))
+	}
+
+	if config.environment != "" {
+		println(" - deploy environment: " + config.environment)
+	}
+
 	sprintln("[----------------------]")
 }
+
+// fmtThumbSizes formats the thumbnail widths followed by the crop thumbnail sizes (e.g. `480, 960, 480x480`)
+func fmtThumbSizes(config appConfig) string {
+	var sizes []string
+	for _, s := range config.thumbSizes {
+		sizes = append(sizes, strconv.Itoa(s))
+	}
+	for _, cs := range config.cropThumbSizes {
+		sizes = append(sizes, cs.String())
+	}
+	return strings.Join(sizes, ", ")
+}
@@ -34,6 +34,22 @@ const (
 	searchPageFileName                          = "search" + contentFileExtension
 	searchIndexFileName                         = "search.json"
 	searchJSFileName                            = "search.js"
+	galleryJSFileName                           = "gallery.js"
+	albumTemplateFileName                       = "album" + templateFileExtension
+	galleryDirective                            = "gallery"
+	codeHighlightCSSFileName                    = "code-highlight.css"
+	sitemapFileName                             = "sitemap.xml"
+	sitemapPartFileNameFormat                   = "sitemap-%d.xml"
+	sitemapXmlns                                = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	sitemapMaxUrlCnt                            = 50000
+	robotsTxtFileName                           = "robots.txt"
+	ogTypeArticle                               = "article"
+	ogTypeWebsite                               = "website"
+	schemaOrgContext                            = "https://schema.org"
+	schemaOrgTypeBlogPosting                    = "BlogPosting"
+	schemaOrgTypeWebPage                        = "WebPage"
+	schemaOrgTypeOrganization                   = "Organization"
+	defaultRobotsTxtRules                       = "User-agent: *\nAllow: /"
 	directivePlaceholderReplacementFormat       = ":@@@:%s:@@@:"
 	hashTagMarkdownReplacementFormat            = "[#%s](/" + deployTagsDirName + "/%s/)"
 	markdownPagesDirName                        = "pages"
@@ -48,33 +64,90 @@ const (
 	deployArchiveDirName                        = "archive"
 	deployTagsDirName                           = "tags"
 	deployCollectionsDirName                    = "collections"
+	deploySeriesDirName                         = "series"
+	deployAlbumsDirName                         = "albums"
 	metaDataKeyDate                             = "date"
 	metaDataKeyTime                             = "time"
 	metaDataKeyTitle                            = "title"
+	metaDataKeyDraft                            = "draft"
+	metaDataKeyDescription                      = "description"
 	metaDataKeyTags                             = "tags"
 	metaDataKeyCollections                      = "collections"
 	metaDataKeyMetaCollections                  = "meta-collections"
 	metaDataKeyMetaCollection                   = "meta-collection"
+	metaDataKeyLang                             = "lang"
+	metaDataKeyTranslationOf                    = "translation-of"
+	metaDataKeySeries                           = "series"
+	metaDataKeySeriesPart                       = "part"
+	metaDataKeyMediaFocus                       = "media-focus"
 	collectionDirectivePlaceholderFormat        = ":@@@:collection:%s:@@@:"
+	tocDirective                                = "toc"
+	tocDirectivePlaceholderFormat               = ":@@@:toc:%s:@@@:"
+	tocDirectivePropMinLevel                    = "min"
+	tocDirectivePropMaxLevel                    = "max"
+	defaultTocMinLevel                          = 1
+	defaultTocMaxLevel                          = 3
+	headingAnchorTemplateFileName               = "heading-anchor" + templateFileExtension
+	headingIDPrefixSeparator                    = "--"
 	configFileName                              = "config.yml"
+	buildCacheDirName                           = ".mbgen-cache"
+	deployLogDirName                            = "deploy-logs"
+	deployLogFileExtension                      = ".log"
+	deployReleasesDirName                       = "releases"
+	deployCurrentReleaseLinkName                = "current"
+	deployPreviousReleaseLinkName               = "previous"
+	buildCacheManifestFileName                  = "manifest.json"
 	defaultGenerateArchive                      = true
 	defaultGenerateTagIndex                     = true
 	defaultGenerateCollectionIndex              = true
+	defaultGenerateAlbums                       = false
 	defaultEnableSearch                         = true
+	defaultGenerateSitemap                      = false
+	defaultGenerateRobotsTxt                    = true
+	defaultGenerateAPI                          = false
+	defaultAPIDir                               = "api"
+	i18nDirName                                 = "i18n"
+	i18nFileExtension                           = ".yml"
+	hreflangXDefault                            = "x-default"
+	apiIndexFileName                            = "index.json"
+	apiFileExtension                            = ".json"
 	defaultPageSize                             = 10
+	defaultRelatedPostCnt                       = 3
+	defaultWorkerCnt                            = 0 // the number of CPUs
+	defaultPostOrder                            = FileNamePostOrder
+	defaultUndatedPosts                         = FileNameUndatedPostPolicy
+	defaultCodeHighlighting                     = NoCodeHighlighting
+	defaultCodeHighlightStyle                   = "monokai"
 	defaultResizeOrigImages                     = false
 	defaultMaxImgSize                           = 1920
 	minAllowedMaxImgSize                        = 1080
 	minAllowedThumbWidth                        = 320
+	minAllowedCropThumbSize                     = 120
 	minAllowedThumbThreshold                    = 0.3
 	defaultThumbThreshold                       = 0.5
 	defaultJPEGQuality                          = 85
 	minAllowedJPEGQuality                       = 70
 	maxAllowedJPEGQuality                       = 100
 	defaultPNGCompressionLevel                  = DefaultCompression
+	defaultWebPVariants                         = NoWebPVariants
+	webpVariantsAllValue                        = "all"
+	defaultImgPlaceholders                      = false
+	defaultStripImgMetadata                     = NoImgMetadataStrip
+	imgPlaceholderSize                          = 16
+	imgPlaceholderJPEGQuality                   = 60
+	videoPosterFileSuffix                       = ".poster"
+	maxGIFPaletteSize                           = 256
+	maxImgHeaderSize                            = 256 << 10
+	maxMP4MovieBoxSize                          = 64 << 20
 	defaultUseThumbs                            = true
 	defaultServeHost                            = "localhost"
 	defaultServePort                            = 8888
+	defaultDeployTarget                         = RsyncDeployTarget
+	defaultDeployPort                           = 22
+	defaultDeployRegion                         = "us-east-1"
+	defaultDeployDeleteThreshold                = 10
+	defaultDeployReleases                       = 0
+	configEnvVarPrefix                          = "MBGEN_"
 	defaultFeedPostCnt                          = 20
 	defaultFeedPostViewOnWebsiteLinkText        = "View on website ⮵"
 	feedExcerptSentenceCnt                      = 3
@@ -86,6 +159,8 @@ const (
 	feedFileNameAtom                            = "atom.xml"
 	feedFileNameJSON                            = "feed.json"
 	thumbImgFileSuffix                          = "_thumb"
+	mediaFocusPropName                          = "focus"
+	webpFileExtension                           = ".webp"
 	pageHeadIncludePrefix                       = "page-head--"
 	defaultThemeName                            = "pretty-dark"
 	defaultThemeAlias                           = "default"
@@ -93,6 +168,7 @@ const (
 	pageHeadTemplatePlaceholder                 = "{{@ page-head @}}"
 	subTemplatePlaceholder                      = "{{@ sub-template @}}"
 	commandInspectOptionFix                     = "--fix"
+	commandGenerateOptionFull                   = "--full"
 	commandCleanupTargetContent                 = "content"
 	commandCleanupTargetThumbs                  = "thumbs"
 	commandCleanupTargetTags                    = "tags"
@@ -100,11 +176,17 @@ const (
 	commandCleanupTargetCollections             = "collections"
 	commandCleanupTargetCollectionIndex         = "collection-index"
 	commandCleanupTargetArchive                 = "archive"
+	commandCleanupTargetAlbums                  = "albums"
 	commandCleanupTargetSearch                  = "search"
 	commandCleanupTargetMedia                   = "media"
+	commandCleanupTargetAPI                     = "api"
 	commandCleanupOptionDryRun                  = "--dry-run"
+	commandDeployOptionDryRun                   = "--dry-run"
+	commandDeployOptionYes                      = "--yes"
+	commandDeployOptionRollback                 = "--rollback"
 	commandServeOptionAdmin                     = "--admin"
 	commandServeOptionWatchReload               = "--watch-reload"
+	commandServeOptionPreview                   = "--preview"
 	commandThemeActionActivate                  = "activate"
 	commandThemeActionInstall                   = "install"
 	commandThemeActionUpdate                    = "update"
@@ -130,9 +212,15 @@ const (
 var (
 	defaultThumbSizes                    = /* const */ []int{480, 960}
 	thumbImgFileNameRegexp               = /* const */ regexp.MustCompile(`_(\d+)` + thumbImgFileSuffix)
+	cropThumbImgFileNameRegexp           = /* const */ regexp.MustCompile(`_(\d+)x(\d+)(?:_f(\d+)x(\d+))?` + thumbImgFileSuffix)
+	cropThumbSizeRegexp                  = /* const */ regexp.MustCompile(`^(\d+)\s*x\s*(\d+)$`)
+	focalPointRegexp                     = /* const */ regexp.MustCompile(`^(\d{1,3})\s*x\s*(\d{1,3})$`)
+	webpVariantFileNameRegexp            = /* const */ regexp.MustCompile(`(?i)\.(jpe?g|png)` + regexp.QuoteMeta(webpFileExtension) + `$`)
+	videoPosterFileNameRegexp            = /* const */ regexp.MustCompile(`(?i)\.(mp4|mkv|mov)` + regexp.QuoteMeta(videoPosterFileSuffix) + `\.(jpe?g|png|gif)$`)
 	imageFileExtensions                  = /* const */ []string{".jpg", ".jpeg", ".png", ".gif"}
-	thumbImageFileExtensions             = /* const */ []string{".jpg", ".jpeg", ".png"}
+	thumbImageFileExtensions             = /* const */ []string{".jpg", ".jpeg", ".png", ".gif"}
 	videoFileExtensions                  = /* const */ []string{".mp4", ".mkv", ".mov"}
+	mp4VideoFileExtensions               = /* const */ []string{".mp4", ".mov"}
 	metaDataPlaceholderRegexp            = /* const */ regexp.MustCompile(`(?s)^---.*?---`)
 	contentDirectivePlaceholderRegexp    = /* const */ regexp.MustCompile(`{.*}`)
 	whitespacePlaceholderRegexp          = /* const */ regexp.MustCompile(`\s+`)
@@ -141,13 +229,28 @@ var (
 	tagAutoLinkPlaceholderRegexp         = /* const */ regexp.MustCompile(`\[([^\]]+)\]\(\{%\s*tag\s*%\}\)`)
 	tagLinkPlaceholderRegexp             = /* const */ regexp.MustCompile(`{%\s*tag\s*:\s*([\w\s-]+)\s*%}`)
 	searchLinkPlaceholderRegexp          = /* const */ regexp.MustCompile(`{%\s*search\s*:\s*([^{}%]+)\s*%}`)
-	contentLinkPlaceholderRegexp         = /* const */ regexp.MustCompile(`{%\s*([\w-_]+)\s*:\s*([\w-_]+)\s*%}`)
+	contentLinkPlaceholderRegexp         = /* const */ regexp.MustCompile(`{%\s*([\w-_]+)\s*:\s*([\w-_]+)(?:#([^\s%}]+))?\s*%}`)
 	mediaPlaceholderRegexp               = /* const */ regexp.MustCompile(`{\s*media(\([\s\w=,]+\))?\s*([:|][^{}]*)?\s*}`)
+	galleryPlaceholderRegexp             = /* const */ regexp.MustCompile(`{\s*gallery(\([\s\w=,]+\))?\s*([:|][^{}]*)?\s*}`)
 	collectionDirectiveRegexp            = /* const */ regexp.MustCompile(`{\s*collection\s*:\s*([^{}]+?)\s*}`)
 	collectionDirectivePlaceholderRegexp = /* const */ regexp.MustCompile(`:@@@:collection:([^:\s]+):@@@:`)
 	// a collection directive on its own line gets wrapped in a <p> element by markdown rendering
 	// — the wrapper must be stripped along with the placeholder (a <section> inside a <p> is invalid HTML)
 	collectionDirectiveWrappedPlaceholderRegexp = /* const */ regexp.MustCompile(`<p>\s*:@@@:collection:([^:\s]+):@@@:\s*</p>`)
+	tocDirectiveRegexp                          = /* const */ regexp.MustCompile(`{\s*toc\s*(\([\s\w=,]+\))?\s*}`)
+	tocDirectivePlaceholderRegexp               = /* const */ regexp.MustCompile(`:@@@:toc:([\w=,]*):@@@:`)
+	// a toc directive on its own line gets wrapped in a <p> element by markdown rendering
+	// — the wrapper must be stripped along with the placeholder (a list inside a <p> is invalid HTML)
+	tocDirectiveWrappedPlaceholderRegexp = /* const */ regexp.MustCompile(`<p>\s*:@@@:toc:([\w=,]*):@@@:\s*</p>`)
+	apiDirRegexp                         = /* const */ regexp.MustCompile(`^[\w-]+(/[\w-]+)*$`)
+	languageCodeRegexp                   = /* const */ regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+	// root-relative links to (language scoped) content, rendered by content directives at parse time
+	contentLinkRegexp    = /* const */ regexp.MustCompile(`(href=")/(` + deployPageDirName + `|` + deployPostDirName + `)/([^/"#]+)` + contentFileExtension + `(#[^"]*)?"`)
+	langScopedLinkRegexp = /* const */ regexp.MustCompile(`(href=")/(` + deployTagsDirName + `/|` + deployAlbumsDirName + `/|` + searchPageFileName + `\?)`)
+	// headingRegexp matches the headings rendered by markdown (with auto generated IDs)
+	headingRegexp = /* const */ regexp.MustCompile(`(?s)<h([1-6]) id="([^"]+)">(.*?)(</h[1-6]>)`)
+	// fragmentLinkRegexp matches the links to the fragments of the same page (e.g. the toc & heading anchor ones)
+	fragmentLinkRegexp = /* const */ regexp.MustCompile(`href="#([^"]+)"`)
 	// blankLineRunRegexp matches a newline followed by one or more additional
 	// whitespace-only lines; used to collapse runs of blank/whitespace-only
 	// lines produced by Go-template conditionals into a single newline
@@ -162,6 +265,7 @@ var (
 	colPlaceholderRegexp             = /* const */ regexp.MustCompile(`(?s)\{\s*col\s*(\(([\s\w=,]+)\))?\s*\}(.*?)\{/\}`)
 	pWrapperAroundPlaceholdersRegexp = /* const */ regexp.MustCompile(`(?s)<p>\s*((?::@@@:[\w-]+:@@@:\s*(?:<br\s*/?>\s*)?)+)</p>`)
 	brTagRegexp                      = /* const */ regexp.MustCompile(`<br\s*/?>`)
+	htmlTagRegexp                    = /* const */ regexp.MustCompile(`<[^>]*>`)
 	hashTagRegex                     = /* const */ regexp.MustCompile(`#([\p{L}\d][\p{L}\d_-]*)`)
 	relativeURLHrefRegexp            = /* const */ regexp.MustCompile(`href="(/[^"]*)"`)
 	// unparsedDirectiveRegexp matches a single leftover `{...}` directive (no nested braces or
@@ -172,6 +276,12 @@ var (
 	codeSpanRegexp = /* const */ regexp.MustCompile(`(?is)<code\b[^>]*>.*?</\s*code\s*>`)
 )
 
+// the top-level deploy dirs holding the generated content
+var generatedContentDirNames = /* const */ []string{
+	deployPageDirName, deployPostDirName, deployPostsDirName, deployTagsDirName,
+	deployArchiveDirName, deployCollectionsDirName, deploySeriesDirName, deployAlbumsDirName, mediaDirName, resourcesDirName,
+}
+
 //go:embed inject-js/admin.js
 var adminJS string
 
@@ -181,6 +291,9 @@ var watchReloadJS string
 //go:embed inject-js/search.js
 var searchJS string
 
+//go:embed inject-js/gallery.js
+var galleryJS string
+
 //go:embed inject-js/easymde.min.js
 var mdEditorJS string
 
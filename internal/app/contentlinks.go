@@ -0,0 +1,97 @@
+package app
+
+import (
+	"slices"
+	"strings"
+)
+
+// parseContentLinkRef parses a content link placeholder match (see contentLinkPlaceholderRegexp)
+func parseContentLinkRef(m []string) contentLinkRef {
+	return contentLinkRef{Type: contentEntityTypeFromString(m[1]), Id: m[2], Anchor: m[3]}
+}
+
+// contentLinkURI returns the root-relative URI of the given content link
+// (empty for the links of an unknown content entity type)
+func contentLinkURI(ref contentLinkRef) string {
+	if ref.Type == UndefinedContentEntityType {
+		return ""
+	}
+	uri := "/" + strings.ToLower(ref.Type.String()) + "/" + ref.Id + contentFileExtension
+	if ref.Anchor != "" {
+		uri += "#" + ref.Anchor
+	}
+	return uri
+}
+
+// parseContentLinkRefs returns the (distinct) content links of the given content, in order of appearance
+func parseContentLinkRefs(content string) []contentLinkRef {
+	var refs []contentLinkRef
+	for _, m := range contentLinkPlaceholderRegexp.FindAllStringSubmatch(content, -1) {
+		ref := parseContentLinkRef(m)
+		if ref.Type != UndefinedContentEntityType && !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func (ref contentLinkRef) String() string {
+	s := "{%" + strings.ToLower(ref.Type.String()) + ":" + ref.Id
+	if ref.Anchor != "" {
+		s += "#" + ref.Anchor
+	}
+	return s + "%}"
+}
+
+// validateContentLinks checks the content links of the given pages/posts against the parsed ones:
+// the links to pages/posts without a markdown file, as well as the anchors not matching any of the target's
+// heading IDs, are appended to the pages/posts warnings (surfaced via reportContentWarnings);
+// so are the links of the published pages/posts to the posts pending publication (not generated yet);
+// done once all the pages/posts are parsed, as the warnings depend on the other content files
+// (and not just the one of a page/post, which is all its parsing/caching depends on)
+func validateContentLinks(pages []page, posts []post, pendingPosts []post) {
+	headingIds := map[contentEntityType]map[string][]string{Page: {}, Post: {}}
+	for _, p := range pages {
+		headingIds[Page][p.Id] = contentHeadingIds(p.Body)
+	}
+	pendingPostIds := map[string]struct{}{}
+	for _, p := range slices.Concat(posts, pendingPosts) {
+		headingIds[Post][p.Id] = contentHeadingIds(p.Body)
+	}
+	for _, p := range pendingPosts {
+		pendingPostIds[p.Id] = struct{}{}
+	}
+	validate := func(refs []contentLinkRef, published bool, warnings *[]string) {
+		for _, ref := range refs {
+			ids, ok := headingIds[ref.Type][ref.Id]
+			if !ok {
+				*warnings = append(*warnings, "content link references a missing "+strings.ToLower(ref.Type.String())+": "+ref.String())
+				continue
+			}
+			if _, pending := pendingPostIds[ref.Id]; published && pending && ref.Type == Post {
+				*warnings = append(*warnings, "content link references a post pending publication: "+ref.String())
+			}
+			if ref.Anchor != "" && !slices.Contains(ids, ref.Anchor) {
+				*warnings = append(*warnings, "content link references a missing heading anchor: "+ref.String())
+			}
+		}
+	}
+	for i := range pages {
+		validate(pages[i].ContentLinks, true, &pages[i].Warnings)
+	}
+	for i := range posts {
+		validate(posts[i].ContentLinks, true, &posts[i].Warnings)
+	}
+	for i := range pendingPosts {
+		validate(pendingPosts[i].ContentLinks, false, &pendingPosts[i].Warnings)
+	}
+}
+
+// contentHeadingIds returns the heading IDs of the given rendered content
+func contentHeadingIds(body string) []string {
+	var ids []string
+	for _, h := range extractHeadings(body) {
+		ids = append(ids, h.ID)
+	}
+	return ids
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseContentLinkRefs(t *testing.T) {
+	content := "see [the intro]({%post:intro%}), [part 2]({% post:intro#part-2 %}), " +
+		"[again]({%post:intro%}), [about]({%PAGE:about%}) and [nothing]({%foo:bar%})"
+	refs := parseContentLinkRefs(content)
+	expected := []contentLinkRef{
+		{Type: Post, Id: "intro"},
+		{Type: Post, Id: "intro", Anchor: "part-2"},
+		{Type: Page, Id: "about"},
+	}
+	if !slices.Equal(refs, expected) {
+		t.Fatalf("expected %v, got: %v", expected, refs)
+	}
+	verifyStringsEqual(contentLinkURI(refs[1]), "/post/intro.html#part-2", t)
+	verifyStringsEqual(contentLinkURI(refs[2]), "/page/about.html", t)
+	verifyStringsEqual(refs[1].String(), "{%post:intro#part-2%}", t)
+}
+
+func TestContentLinkWithAnchorIsRendered(t *testing.T) {
+	p := parsePost("linking", "---\ntitle: Linking\n---\n[part 2]({%post:intro#part-2%}) [x]({%foo:bar%})", defaultConfig(), testResLoader())
+	verifyStringContains(p.Body, `<a href="/post/intro.html#part-2">part 2</a>`, t)
+	if len(p.Warnings) != 1 {
+		t.Fatalf("expected a single unknown content type warning, got: %v", p.Warnings)
+	}
+	verifyStringContains(p.Warnings[0], "content link references an unknown content type: {%foo:bar%}", t)
+}
+
+func TestValidateContentLinks(t *testing.T) {
+	pages := []page{
+		{Id: "about", ContentLinks: []contentLinkRef{{Type: Post, Id: "intro", Anchor: "part-2"}, {Type: Page, Id: "gone"}}},
+	}
+	posts := []post{
+		{Id: "intro", Body: `<h2 id="part-1">Part 1</h2><p>...</p><h2 id="part-2">Part 2</h2>`},
+		{Id: "linking", ContentLinks: []contentLinkRef{{Type: Post, Id: "intro", Anchor: "part-3"}, {Type: Page, Id: "about"}, {Type: Post, Id: "missing"}}},
+	}
+	validateContentLinks(pages, posts, nil)
+	if len(pages[0].Warnings) != 1 {
+		t.Fatalf("expected a single page warning, got: %v", pages[0].Warnings)
+	}
+	verifyStringsEqual(pages[0].Warnings[0], "content link references a missing page: {%page:gone%}", t)
+	if len(posts[0].Warnings) != 0 {
+		t.Errorf("expected no warnings, got: %v", posts[0].Warnings)
+	}
+	expected := []string{
+		"content link references a missing heading anchor: {%post:intro#part-3%}",
+		"content link references a missing post: {%post:missing%}",
+	}
+	if !slices.Equal(posts[1].Warnings, expected) {
+		t.Errorf("expected %v, got: %v", expected, posts[1].Warnings)
+	}
+}
+
+func TestValidateContentLinksToPendingPosts(t *testing.T) {
+	pages := []page{
+		{Id: "about", ContentLinks: []contentLinkRef{{Type: Post, Id: "draft"}}},
+	}
+	posts := []post{
+		{Id: "published", ContentLinks: []contentLinkRef{{Type: Post, Id: "draft", Anchor: "intro"}}},
+	}
+	pendingPosts := []post{
+		{Id: "draft", Body: `<h2 id="intro">Intro</h2>`, ContentLinks: []contentLinkRef{{Type: Post, Id: "scheduled"}, {Type: Post, Id: "published"}}},
+		{Id: "scheduled"},
+	}
+	validateContentLinks(pages, posts, pendingPosts)
+	for _, warnings := range [][]string{pages[0].Warnings, posts[0].Warnings} {
+		if len(warnings) != 1 {
+			t.Fatalf("expected a single warning, got: %v", warnings)
+		}
+		verifyStringContains(warnings[0], "content link references a post pending publication: {%post:draft", t)
+	}
+	// the links of the posts pending publication are validated, but may reference other pending ones
+	if len(pendingPosts[0].Warnings) != 0 {
+		t.Errorf("expected no warnings, got: %v", pendingPosts[0].Warnings)
+	}
+}
@@ -1,14 +1,98 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 )
 
-// buildDeployOptions returns the ordered rsync deploy stages: one stage per content dir
+// deployTarget is a deploy backend (see the `deployTarget` config option),
+// deploying the generated site to its destination stage by stage (see buildDeployOptions)
+type deployTarget interface {
+	// destination returns the deploy destination along with its path separator
+	destination() (string, rune)
+	// planStage lists the changes the given stage would apply
+	planStage(opts deployOptions) (deployPlan, error)
+	// deployStage deploys the given (planned) stage (the source dir content replaces the destination dir one)
+	deployStage(opts deployOptions, plan deployPlan) error
+	// finish completes the deploy once all the stages are deployed
+	finish() error
+	close() error
+}
+
+// newDeployTarget creates the deploy target configured, validating the config options it requires
+func newDeployTarget(config appConfig) (deployTarget, error) {
+	var target deployTarget
+	var releases releaseStore
+	root := config.deployPath
+	switch config.deployTarget {
+	case SFTPDeployTarget:
+		if config.deployPath == "" || config.deployHost == "" {
+			return nil, fmt.Errorf("the `deployPath` and `deployHost` config options are required by the %s deploy target", config.deployTarget)
+		}
+		store, err := newSFTPDeployStore(config)
+		if err != nil {
+			return nil, err
+		}
+		target, releases = &mirrorDeployTarget{store: store, root: root}, store
+	case S3DeployTarget:
+		if config.deployBucket == "" {
+			return nil, fmt.Errorf("the `deployBucket` config option is required by the %s deploy target", config.deployTarget)
+		}
+		store := newS3DeployStore(newS3Client(config), config.deployBucket)
+		// the deploy path is an (optional) key prefix
+		target = &mirrorDeployTarget{store: store, root: store.rootURI(config.deployPath)}
+	case LocalDeployTarget:
+		if config.deployPath == "" {
+			return nil, fmt.Errorf("the `deployPath` config option is required by the %s deploy target", config.deployTarget)
+		}
+		root = filepath.ToSlash(config.deployPath)
+		target, releases = &mirrorDeployTarget{store: localDeployStore{}, root: root}, localDeployStore{}
+	default:
+		if config.deployPath == "" {
+			return nil, fmt.Errorf("no deploy path specified in the config file")
+		}
+		deployDestination := config.deployPath
+		host := ""
+		if config.deployHost != "" {
+			host = config.deployHost
+			if config.deployUsername != "" {
+				host = config.deployUsername + "@" + host
+			}
+			deployDestination = host + ":" + deployDestination
+		}
+		target = rsyncDeployTarget{dest: deployDestination}
+		if host != "" {
+			releases = shellReleaseStore{host: host}
+		} else {
+			// the releases of a local deploy path are managed directly (without relying on the shell tools)
+			root = filepath.ToSlash(config.deployPath)
+			releases = localDeployStore{}
+		}
+	}
+	if config.deployReleases == 0 {
+		return target, nil
+	}
+	if releases == nil {
+		target.close()
+		return nil, fmt.Errorf("release deploys are not supported by the %s deploy target", config.deployTarget)
+	}
+	rTarget, err := newReleaseDeployTarget(target, releases, root, config.deployReleases, time.Now())
+	if err != nil {
+		target.close()
+		return nil, err
+	}
+	return rTarget, nil
+}
+
+// buildDeployOptions returns the ordered deploy stages: one stage per content dir
 // (ordered so that content that is linked to uploads before the content that links to it,
 // avoiding broken links mid-deploy), followed by a catch-all stage for everything else
 // that excludes the dirs already handled by the dedicated stages
@@ -36,63 +120,475 @@ func buildDeployOptions(source string, destination string, destPathSeparator run
 	})
 }
 
-func rsyncDeploy(destination string) {
+// errDeployAborted is returned when the confirmation of the deletions is declined
+var errDeployAborted = errors.New("deploy aborted: deletions not confirmed")
+
+// errDeployNotConfirmable is returned when the deletions require a confirmation, which can't be asked for
+// (e.g. when deploying from a CI pipeline or a cron job)
+var errDeployNotConfirmable = errors.New("deploy aborted: deletions not confirmed (the standard input is not a terminal) - " +
+	"use the " + commandDeployOptionYes + " flag to skip the confirmation, or raise the `deployDeleteThreshold` config option")
+
+// deployRun holds the options of a single deploy run along with its log
+type deployRun struct {
+	dryRun bool
+	// the number of deletions (across all the stages) above which a confirmation is required
+	deleteThreshold int
+	// asks for the confirmation of the deletions (nil if confirmed up front)
+	confirm func(prompt string) bool
+	// the confirmation can't be asked for (the deploy fails instead of prompting)
+	nonInteractive bool
+	log            strings.Builder
+	started        time.Time
+}
+
+// logf appends a line to the deploy log
+func (r *deployRun) logf(format string, args ...any) {
+	r.log.WriteString(fmt.Sprintf(format, args...) + "\n")
+}
+
+// deploy deploys the generated site to the given target: all the stages are planned first (listing the changes
+// on a dry run, or asking for a confirmation if the deletions exceed the threshold), and then deployed one by one
+func deploy(target deployTarget, run *deployRun) error {
 	if !dirExists(deployDirName) {
-		sprintln("deploy dir does not exist - make sure to run the `mbgen generate` command first")
-	} else {
-		source, err := filepath.Abs(deployDirName)
-		check(err)
-
-		sprintln(
-			" - deploy source: "+source,
-			" - deploy destination: "+destination,
-		)
-
-		destPathSeparator := '/'
-		for i := 0; i < len(destination); i++ {
-			if destination[i] == '\\' {
-				destPathSeparator = '\\'
-				break
-			}
+		return errors.New("deploy dir does not exist - make sure to run the `mbgen generate` command first")
+	}
+	source, err := filepath.Abs(deployDirName)
+	check(err)
+
+	destination, destPathSeparator := target.destination()
+
+	sprintln(
+		" - deploy source: "+source,
+		" - deploy destination: "+destination,
+	)
+	run.logf("source: %s", source)
+	run.logf("destination: %s", destination)
+	run.logf("dry run: %t", run.dryRun)
+
+	var stages []deployOptions
+	for _, dOpts := range buildDeployOptions(source, destination, destPathSeparator) {
+		if dirExists(dOpts.source) {
+			stages = append(stages, dOpts)
 		}
+	}
 
-		deployOpts := buildDeployOptions(source, destination, destPathSeparator)
-
-		for i := 0; i < len(deployOpts); i++ {
-			dOpts := deployOpts[i]
-			if dirExists(dOpts.source) {
-				fmt.Printf("\n - deploy: %s -> %s\n", dOpts.source, dOpts.destination)
-				args := []string{
-					"--archive",
-					"--compress",
-					"--delete",
-					"--no-t",
-					"--no-o",
-					"--no-g",
-					"--no-p",
-					"--progress",
-					"--verbose",
-				}
-				if len(dOpts.exclude) > 0 {
-					for _, exclude := range dOpts.exclude {
-						args = append(args, fmt.Sprintf("--exclude=%s", exclude))
-					}
-				}
-				args = append(args, dOpts.source)
-				args = append(args, dOpts.destination)
-				cmd := exec.Command("rsync", args...)
-				output, err := cmd.Output()
-				check(err)
-				outputLines := strings.Split(string(output), "\n")
-				for _, line := range outputLines {
-					line = strings.TrimSpace(line)
-					if strings.HasPrefix(line, "sent") ||
-						strings.HasPrefix(line, "total") {
-						fmt.Println(" - " + line)
-					}
+	plans := make([]deployPlan, len(stages))
+	deleteCnt := 0
+	for i, dOpts := range stages {
+		plan, err := target.planStage(dOpts)
+		if err != nil {
+			run.logf("\nstage: %s -> %s\n - status: planning failed: %v", dOpts.source, dOpts.destination, err)
+			return err
+		}
+		plans[i] = plan
+		deleteCnt += len(plan.remove)
+		if run.dryRun {
+			fmt.Printf("\n - deploy: %s -> %s [dry run]\n", dOpts.source, dOpts.destination)
+			printDeployPlan(plan)
+		}
+	}
+
+	if run.dryRun {
+		for i, dOpts := range stages {
+			logDeployStage(run, dOpts, plans[i], "dry run (nothing deployed)")
+		}
+		return nil
+	}
+
+	if deleteCnt > run.deleteThreshold && run.confirm != nil {
+		for i, dOpts := range stages {
+			if len(plans[i].remove) > 0 {
+				fmt.Printf("\n - to be deleted: %s\n", dOpts.destination)
+				for _, relPath := range plans[i].remove {
+					fmt.Println("   - " + relPath)
 				}
-				fmt.Printf(" - deploy: %s -> %s [complete]\n", dOpts.source, dOpts.destination)
 			}
 		}
+		if run.nonInteractive {
+			for i, dOpts := range stages {
+				logDeployStage(run, dOpts, plans[i], "aborted (nothing deployed)")
+			}
+			return errDeployNotConfirmable
+		}
+		prompt := fmt.Sprintf("\n%d files are to be deleted (the threshold is %d), continue? [y/N]: ", deleteCnt, run.deleteThreshold)
+		if !run.confirm(prompt) {
+			for i, dOpts := range stages {
+				logDeployStage(run, dOpts, plans[i], "aborted (nothing deployed)")
+			}
+			return errDeployAborted
+		}
+	}
+
+	for i, dOpts := range stages {
+		fmt.Printf("\n - deploy: %s -> %s\n", dOpts.source, dOpts.destination)
+		if err := target.deployStage(dOpts, plans[i]); err != nil {
+			logDeployStage(run, dOpts, plans[i], "failed: "+err.Error())
+			return err
+		}
+		logDeployStage(run, dOpts, plans[i], "complete")
+		fmt.Printf(" - deploy: %s -> %s [complete]\n", dOpts.source, dOpts.destination)
+	}
+	if err := target.finish(); err != nil {
+		run.logf("\nfinish: failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// printDeployPlan prints out the changes of a deploy stage
+func printDeployPlan(plan deployPlan) {
+	for _, change := range []struct {
+		name  string
+		paths []string
+	}{{"add", plan.add}, {"update", plan.update}, {"delete", plan.remove}} {
+		for _, relPath := range change.paths {
+			fmt.Printf("   - %s: %s\n", change.name, relPath)
+		}
+	}
+	fmt.Printf(" - to add: %d, to update: %d, to delete: %d\n", len(plan.add), len(plan.update), len(plan.remove))
+}
+
+// logDeployStage appends the results of a deploy stage to the deploy log
+func logDeployStage(run *deployRun, dOpts deployOptions, plan deployPlan, status string) {
+	run.logf("\nstage: %s -> %s", dOpts.source, dOpts.destination)
+	for _, change := range []struct {
+		name  string
+		paths []string
+	}{{"added", plan.add}, {"updated", plan.update}, {"deleted", plan.remove}} {
+		run.logf(" - %s: %d", change.name, len(change.paths))
+		for _, relPath := range change.paths {
+			run.logf("   - %s", relPath)
+		}
+	}
+	run.logf(" - status: %s", status)
+}
+
+// writeDeployLog writes the log of the given deploy run (along with its exit status) into a new file
+// inside the deploy log dir, returning the file path
+func writeDeployLog(run *deployRun, target deployTargetType, deployErr error) string {
+	exitStatus := "0"
+	if deployErr != nil {
+		exitStatus = "1 (" + deployErr.Error() + ")"
+	}
+	data := fmt.Sprintf("mbgen %s deploy\nstarted: %s\nfinished: %s\ntarget: %s\n%s\nexit status: %s\n",
+		appVersion, run.started.Format(time.RFC3339), time.Now().Format(time.RFC3339), target, run.log.String(), exitStatus)
+	createDirIfNotExists(deployLogDirName)
+	logFileName := "deploy-" + run.started.Format("20060102-150405")
+	logFilePath := filepath.Join(deployLogDirName, logFileName+deployLogFileExtension)
+	// multiple deploys started within the same second
+	for i := 2; fileExists(logFilePath); i++ {
+		logFilePath = filepath.Join(deployLogDirName, fmt.Sprintf("%s-%d%s", logFileName, i, deployLogFileExtension))
+	}
+	writeDataToFileIfChanged(logFilePath, []byte(data))
+	return logFilePath
+}
+
+// rsyncDeployTarget deploys over SSH via the rsync tool (which has to be available in the PATH)
+type rsyncDeployTarget struct {
+	dest string
+}
+
+func (t rsyncDeployTarget) destination() (string, rune) {
+	destPathSeparator := '/'
+	for i := 0; i < len(t.dest); i++ {
+		if t.dest[i] == '\\' {
+			destPathSeparator = '\\'
+			break
+		}
 	}
+	return t.dest, destPathSeparator
+}
+
+// rsyncArgs returns the rsync arguments of the given deploy stage, along with the given extra ones;
+// the files are compared by their checksums (the modification times are not preserved,
+// so they would make all the files appear changed), both when planning and deploying the changes
+func rsyncArgs(dOpts deployOptions, extraArgs ...string) []string {
+	args := []string{
+		"--archive",
+		"--compress",
+		"--delete",
+		"--checksum",
+		"--no-t",
+		"--no-o",
+		"--no-g",
+		"--no-p",
+	}
+	args = append(args, extraArgs...)
+	if len(dOpts.exclude) > 0 {
+		for _, exclude := range dOpts.exclude {
+			args = append(args, fmt.Sprintf("--exclude=%s", exclude))
+		}
+	}
+	args = append(args, dOpts.source)
+	return append(args, dOpts.destination)
+}
+
+// planStage runs rsync in the dry run mode, with the same options as the actual deploy run (see rsyncArgs)
+func (t rsyncDeployTarget) planStage(dOpts deployOptions) (deployPlan, error) {
+	output, err := exec.Command("rsync", rsyncArgs(dOpts, "--dry-run", "--itemize-changes")...).Output()
+	if err != nil {
+		return deployPlan{}, err
+	}
+	return parseRsyncItemizedChanges(string(output)), nil
+}
+
+// parseRsyncItemizedChanges parses the (file) changes listed by the rsync `--itemize-changes` option,
+// e.g. `>f+++++++++ post/new.html`, `>f.s........ index.html` or `*deleting   post/old.html`
+func parseRsyncItemizedChanges(output string) deployPlan {
+	var plan deployPlan
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 13 || line[11] != ' ' {
+			continue
+		}
+		flags, relPath := line[:11], line[12:]
+		switch {
+		case strings.HasPrefix(flags, "*deleting"):
+			if !strings.HasSuffix(relPath, "/") {
+				plan.remove = append(plan.remove, relPath)
+			}
+		case flags[1] != 'f' || !strings.ContainsRune("<>c", rune(flags[0])):
+			// dirs, links, etc. and attribute only changes
+		case strings.Trim(flags[2:], "+") == "":
+			plan.add = append(plan.add, relPath)
+		default:
+			plan.update = append(plan.update, relPath)
+		}
+	}
+	slices.Sort(plan.add)
+	slices.Sort(plan.update)
+	slices.Sort(plan.remove)
+	return plan
+}
+
+func (t rsyncDeployTarget) deployStage(dOpts deployOptions, plan deployPlan) error {
+	cmd := exec.Command("rsync", rsyncArgs(dOpts, "--progress", "--verbose")...)
+	output, err := cmd.Output()
+	if err != nil {
+		return err
+	}
+	outputLines := strings.Split(string(output), "\n")
+	for _, line := range outputLines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "sent") ||
+			strings.HasPrefix(line, "total") {
+			fmt.Println(" - " + line)
+		}
+	}
+	return nil
+}
+
+func (t rsyncDeployTarget) finish() error {
+	return nil
+}
+
+func (t rsyncDeployTarget) close() error {
+	return nil
+}
+
+// deployFileInfo describes a destination file of a deploy store
+type deployFileInfo struct {
+	size    int64
+	modTime time.Time
+	etag    string // the (unquoted) entity tag of an object storage file
+}
+
+// deployStore is a file store the generated site is mirrored to (see mirrorDeployTarget);
+// the destination paths are slash-separated
+type deployStore interface {
+	// list lists the files inside the given destination dir (recursively),
+	// keyed by their (slash-separated) relative paths; a missing dir has no files
+	list(dir string) (map[string]deployFileInfo, error)
+	// upToDate checks whether the given destination file is the same as the given source one
+	upToDate(srcPath string, src fs.FileInfo, dst deployFileInfo) bool
+	upload(srcPath string, src fs.FileInfo, dstPath string) error
+	remove(dstPath string) error
+	// removeEmptyDir removes the given destination dir if it is empty (a no-op for the stores without dirs)
+	removeEmptyDir(dstDir string) error
+	close() error
+}
+
+// mirrorDeployTarget deploys by mirroring the deploy stages to a file store: the new and changed files are uploaded,
+// and the files no longer generated are removed afterward (so that nothing is linked to while it is missing)
+type mirrorDeployTarget struct {
+	store deployStore
+	root  string
+}
+
+func (t *mirrorDeployTarget) destination() (string, rune) {
+	return t.root, '/'
+}
+
+func (t *mirrorDeployTarget) planStage(dOpts deployOptions) (deployPlan, error) {
+	return planDeployStage(dOpts, t.store)
+}
+
+func (t *mirrorDeployTarget) deployStage(dOpts deployOptions, plan deployPlan) error {
+	for _, relPath := range slices.Concat(plan.add, plan.update) {
+		srcPath := filepath.Join(dOpts.source, filepath.FromSlash(relPath))
+		info, err := os.Stat(srcPath)
+		if err != nil {
+			return err
+		}
+		if err := t.store.upload(srcPath, info, joinDeployPath(dOpts.destination, relPath)); err != nil {
+			return err
+		}
+	}
+	removedDirs := map[string]bool{}
+	for _, relPath := range plan.remove {
+		if err := t.store.remove(joinDeployPath(dOpts.destination, relPath)); err != nil {
+			return err
+		}
+		for dir := path.Dir(relPath); dir != "."; dir = path.Dir(dir) {
+			removedDirs[dir] = true
+		}
+	}
+	// the deepest dirs first, so that the parent dirs are empty by the time they are removed
+	dirs := make([]string, 0, len(removedDirs))
+	for dir := range removedDirs {
+		dirs = append(dirs, dir)
+	}
+	slices.SortFunc(dirs, func(a, b string) int { return strings.Count(b, "/") - strings.Count(a, "/") })
+	for _, dir := range dirs {
+		_ = t.store.removeEmptyDir(joinDeployPath(dOpts.destination, dir))
+	}
+	fmt.Printf(" - uploaded: %d, updated: %d, deleted: %d\n", len(plan.add), len(plan.update), len(plan.remove))
+	return nil
+}
+
+func (t *mirrorDeployTarget) finish() error {
+	return nil
+}
+
+func (t *mirrorDeployTarget) close() error {
+	return t.store.close()
+}
+
+// deployPlan lists the changes a deploy stage applies (relative file paths, sorted)
+type deployPlan struct {
+	add    []string
+	update []string
+	remove []string
+}
+
+// planDeployStage compares the source dir of the given deploy stage with its destination dir
+func planDeployStage(dOpts deployOptions, store deployStore) (deployPlan, error) {
+	var plan deployPlan
+	dstFiles, err := store.list(dOpts.destination)
+	if err != nil {
+		return plan, err
+	}
+	srcFiles := map[string]fs.FileInfo{}
+	err = filepath.WalkDir(dOpts.source, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(dOpts.source, p)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if deployPathExcluded(relPath, dOpts.exclude) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		srcFiles[relPath] = info
+		return nil
+	})
+	if err != nil {
+		return plan, err
+	}
+	for relPath, info := range srcFiles {
+		dst, ok := dstFiles[relPath]
+		if !ok {
+			plan.add = append(plan.add, relPath)
+		} else if !store.upToDate(filepath.Join(dOpts.source, filepath.FromSlash(relPath)), info, dst) {
+			plan.update = append(plan.update, relPath)
+		}
+	}
+	for relPath := range dstFiles {
+		if _, ok := srcFiles[relPath]; !ok && !deployPathExcluded(relPath, dOpts.exclude) {
+			plan.remove = append(plan.remove, relPath)
+		}
+	}
+	slices.Sort(plan.add)
+	slices.Sort(plan.update)
+	slices.Sort(plan.remove)
+	return plan, nil
+}
+
+// deployPathExcluded checks whether the given relative path is inside one of the given excluded (top level) dirs
+func deployPathExcluded(relPath string, exclude []string) bool {
+	topDir, _, _ := strings.Cut(relPath, "/")
+	return slices.Contains(exclude, topDir)
+}
+
+// joinDeployPath joins the given (slash-separated) destination path elements
+func joinDeployPath(dir string, relPath string) string {
+	if dir == "" {
+		return relPath
+	}
+	return strings.TrimSuffix(dir, "/") + "/" + relPath
+}
+
+// localDeployStore deploys to a local dir (e.g. a mounted network share)
+type localDeployStore struct{}
+
+func (s localDeployStore) list(dir string) (map[string]deployFileInfo, error) {
+	files := map[string]deployFileInfo{}
+	root := filepath.FromSlash(dir)
+	if !dirExists(root) {
+		return files, nil
+	}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relPath)] = deployFileInfo{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+// upToDate compares the sizes and modification times (the uploaded files get the source modification time)
+func (s localDeployStore) upToDate(srcPath string, src fs.FileInfo, dst deployFileInfo) bool {
+	return src.Size() == dst.size && src.ModTime().Unix() == dst.modTime.Unix()
+}
+
+func (s localDeployStore) upload(srcPath string, src fs.FileInfo, dstPath string) error {
+	dstPath = filepath.FromSlash(dstPath)
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dstPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Chtimes(dstPath, src.ModTime(), src.ModTime())
+}
+
+func (s localDeployStore) remove(dstPath string) error {
+	return os.Remove(filepath.FromSlash(dstPath))
+}
+
+func (s localDeployStore) removeEmptyDir(dstDir string) error {
+	// fails for a non-empty dir
+	return os.Remove(filepath.FromSlash(dstDir))
+}
+
+func (s localDeployStore) close() error {
+	return nil
 }
@@ -0,0 +1,456 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// releaseStore manages the release dirs and the current release symlink of a release-based deploy
+// (the paths are slash separated)
+type releaseStore interface {
+	// releases lists the release ids found in the given releases dir (the oldest first)
+	releases(releasesDir string) ([]string, error)
+	// linkTarget returns the target of the given symlink ("" if it does not exist)
+	linkTarget(link string) (string, error)
+	// copyRelease creates the given release dir, seeding it with the content of the given one (if any)
+	copyRelease(srcDir string, dstDir string) error
+	// switchLink (atomically) points the given symlink to the given target
+	switchLink(link string, target string) error
+	// removeRelease removes the given release dir along with its content
+	removeRelease(dir string) error
+}
+
+// releaseDeployTarget deploys into a new `releases/<id>` dir (seeded with the content of the current release),
+// and switches the `current` symlink to it only once all the stages are deployed (pointing the `previous` symlink
+// to the release it replaces), keeping the configured number of the latest releases (see the `deployReleases` config option);
+// the new release dir is removed if the deploy fails
+type releaseDeployTarget struct {
+	target  deployTarget // deploys to the root dir (the deploy path)
+	store   releaseStore
+	root    string
+	keep    int
+	id      string
+	current string // the current release id ("" if none)
+	seeded  bool
+}
+
+func newReleaseDeployTarget(target deployTarget, store releaseStore, root string, keep int, now time.Time) (*releaseDeployTarget, error) {
+	t := &releaseDeployTarget{target: target, store: store, root: strings.TrimSuffix(root, "/"), keep: keep}
+	releases, err := store.releases(t.releasesDir())
+	if err != nil {
+		return nil, err
+	}
+	t.current, err = t.currentRelease(releases)
+	if err != nil {
+		return nil, err
+	}
+	t.id = now.UTC().Format("20060102150405")
+	// multiple deploys within the same second (the suffix is zero-padded, so that the ids sort in the creation order)
+	for i := 2; slices.Contains(releases, t.id); i++ {
+		t.id = fmt.Sprintf("%s-%03d", now.UTC().Format("20060102150405"), i)
+	}
+	return t, nil
+}
+
+func (t *releaseDeployTarget) releasesDir() string {
+	return t.root + "/" + deployReleasesDirName
+}
+
+func (t *releaseDeployTarget) currentLink() string {
+	return t.root + "/" + deployCurrentReleaseLinkName
+}
+
+func (t *releaseDeployTarget) previousLink() string {
+	return t.root + "/" + deployPreviousReleaseLinkName
+}
+
+// currentRelease returns the id of the release the current release symlink points to
+func (t *releaseDeployTarget) currentRelease(releases []string) (string, error) {
+	return t.linkedRelease(t.currentLink(), releases)
+}
+
+// linkedRelease returns the id of the release the given symlink points to ("" if none of the given releases)
+func (t *releaseDeployTarget) linkedRelease(link string, releases []string) (string, error) {
+	target, err := t.store.linkTarget(link)
+	if err != nil || target == "" {
+		return "", err
+	}
+	if id := path.Base(target); slices.Contains(releases, id) {
+		return id, nil
+	}
+	return "", nil
+}
+
+// releaseDestination returns the destination of the given release dir
+func (t *releaseDeployTarget) releaseDestination(id string) string {
+	destination, destPathSeparator := t.target.destination()
+	return fmt.Sprintf("%s%c%s%c%s", destination, destPathSeparator, deployReleasesDirName, destPathSeparator, id)
+}
+
+func (t *releaseDeployTarget) destination() (string, rune) {
+	_, destPathSeparator := t.target.destination()
+	return t.releaseDestination(t.id), destPathSeparator
+}
+
+// planStage plans the stage against the current release (the new release is seeded with its content)
+func (t *releaseDeployTarget) planStage(dOpts deployOptions) (deployPlan, error) {
+	if t.current != "" {
+		dOpts.destination = t.releaseDestination(t.current) + strings.TrimPrefix(dOpts.destination, t.releaseDestination(t.id))
+	}
+	return t.target.planStage(dOpts)
+}
+
+func (t *releaseDeployTarget) deployStage(dOpts deployOptions, plan deployPlan) error {
+	if !t.seeded {
+		srcDir := ""
+		if t.current != "" {
+			srcDir = t.releasesDir() + "/" + t.current
+			fmt.Printf("\n - seeding release %s with release %s ...\n", t.id, t.current)
+		}
+		t.seeded = true
+		if err := t.store.copyRelease(srcDir, t.releasesDir()+"/"+t.id); err != nil {
+			return t.abort(err)
+		}
+	}
+	if err := t.target.deployStage(dOpts, plan); err != nil {
+		return t.abort(err)
+	}
+	return nil
+}
+
+// abort removes the new release dir of the failed deploy (if created), leaving the current release intact
+func (t *releaseDeployTarget) abort(err error) error {
+	if !t.seeded {
+		return err
+	}
+	if rErr := t.store.removeRelease(t.releasesDir() + "/" + t.id); rErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to remove the release %s: %w", t.id, rErr))
+	}
+	fmt.Println(" - removed failed release: " + t.id)
+	return err
+}
+
+// finish switches the current release symlink to the new release (and the previous release one to the replaced release),
+// and removes the leftovers of the failed deploys along with the releases beyond the ones to keep
+func (t *releaseDeployTarget) finish() error {
+	if err := t.store.switchLink(t.currentLink(), deployReleasesDirName+"/"+t.id); err != nil {
+		return t.abort(err)
+	}
+	fmt.Printf("\n - current release: %s\n", t.id)
+	if t.current != "" {
+		if err := t.store.switchLink(t.previousLink(), deployReleasesDirName+"/"+t.current); err != nil {
+			return err
+		}
+	}
+	releases, err := t.store.releases(t.releasesDir())
+	if err != nil {
+		return err
+	}
+	// the release ids are timestamps, so the ones newer than the replaced release (other than the new one)
+	// are left over from the failed deploys (that failed to remove them)
+	var kept []string
+	for _, id := range releases {
+		if t.current != "" && id > t.current && id != t.id {
+			if err := t.store.removeRelease(t.releasesDir() + "/" + id); err != nil {
+				return err
+			}
+			fmt.Println(" - removed failed release: " + id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	releases = kept
+	for len(releases) > t.keep {
+		if releases[0] != t.id {
+			if err := t.store.removeRelease(t.releasesDir() + "/" + releases[0]); err != nil {
+				return err
+			}
+			fmt.Println(" - removed release: " + releases[0])
+		}
+		releases = releases[1:]
+	}
+	return nil
+}
+
+// rollback switches the current release symlink back to the previous release (the one the previous release symlink
+// points to), removing the current one, returning the ids of both
+func (t *releaseDeployTarget) rollback() (string, string, error) {
+	releases, err := t.store.releases(t.releasesDir())
+	if err != nil {
+		return "", "", err
+	}
+	previous, err := t.linkedRelease(t.previousLink(), releases)
+	if err != nil {
+		return "", "", err
+	}
+	// once rolled back, the previous release symlink points to the current release
+	if t.current == "" || previous == "" || previous == t.current {
+		return "", "", errors.New("no previous release to roll back to")
+	}
+	if err := t.store.switchLink(t.currentLink(), deployReleasesDirName+"/"+previous); err != nil {
+		return "", "", err
+	}
+	if err := t.store.removeRelease(t.releasesDir() + "/" + t.current); err != nil {
+		return "", "", err
+	}
+	return t.current, previous, nil
+}
+
+// rollbackRelease rolls the given (release-based) deploy target back to the previous release
+func rollbackRelease(target deployTarget, run *deployRun) error {
+	rTarget, ok := target.(*releaseDeployTarget)
+	if !ok {
+		return errors.New("rolling back requires the release-based deploys (see the `deployReleases` config option)")
+	}
+	from, to, err := rTarget.rollback()
+	if err != nil {
+		run.logf("rollback: failed: %v", err)
+		return err
+	}
+	sprintln(
+		" - rolled back release: "+from,
+		" - current release: "+to,
+	)
+	run.logf("rollback: %s -> %s", from, to)
+	return nil
+}
+
+func (t *releaseDeployTarget) close() error {
+	return t.target.close()
+}
+
+func (s localDeployStore) releases(releasesDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.FromSlash(releasesDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	var releases []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			releases = append(releases, entry.Name())
+		}
+	}
+	return releases, err
+}
+
+func (s localDeployStore) linkTarget(link string) (string, error) {
+	target, err := os.Readlink(filepath.FromSlash(link))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return filepath.ToSlash(target), err
+}
+
+func (s localDeployStore) copyRelease(srcDir string, dstDir string) error {
+	if err := os.MkdirAll(filepath.FromSlash(dstDir), 0755); err != nil || srcDir == "" {
+		return err
+	}
+	root := filepath.FromSlash(srcDir)
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		// uploading keeps the modification times (compared by the subsequent deploys)
+		return s.upload(p, info, joinDeployPath(dstDir, filepath.ToSlash(relPath)))
+	})
+}
+
+// switchLink creates a temporary symlink, renaming it over the existing one
+func (s localDeployStore) switchLink(link string, target string) error {
+	tmpLink := filepath.FromSlash(link) + ".tmp"
+	_ = os.Remove(tmpLink)
+	if err := os.Symlink(filepath.FromSlash(target), tmpLink); err != nil {
+		return err
+	}
+	return os.Rename(tmpLink, filepath.FromSlash(link))
+}
+
+func (s localDeployStore) removeRelease(dir string) error {
+	return os.RemoveAll(filepath.FromSlash(dir))
+}
+
+func (s *sftpDeployStore) releases(releasesDir string) ([]string, error) {
+	entries, err := s.client.ReadDir(releasesDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	var releases []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			releases = append(releases, entry.Name())
+		}
+	}
+	slices.Sort(releases)
+	return releases, err
+}
+
+func (s *sftpDeployStore) linkTarget(link string) (string, error) {
+	target, err := s.client.ReadLink(link)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return target, err
+}
+
+// copyRelease copies the release on the server (over SSH) if possible, falling back to copying it
+// over SFTP (e.g. for an SFTP-only account)
+func (s *sftpDeployStore) copyRelease(srcDir string, dstDir string) error {
+	if err := s.client.MkdirAll(dstDir); err != nil || srcDir == "" {
+		return err
+	}
+	if s.sshClient != nil {
+		if session, err := s.sshClient.NewSession(); err == nil {
+			err = session.Run("cp -a " + shellQuote(srcDir+"/.") + " " + shellQuote(dstDir))
+			session.Close()
+			if err == nil {
+				return nil
+			}
+		}
+	}
+	walker := s.client.Walk(srcDir)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+		info := walker.Stat()
+		if info.IsDir() {
+			continue
+		}
+		dstPath := joinDeployPath(dstDir, strings.TrimPrefix(walker.Path(), srcDir+"/"))
+		if err := s.copyFile(walker.Path(), dstPath, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies a file on the server, keeping its modification time
+func (s *sftpDeployStore) copyFile(srcPath string, dstPath string, info fs.FileInfo) error {
+	if err := s.client.MkdirAll(path.Dir(dstPath)); err != nil {
+		return err
+	}
+	srcFile, err := s.client.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	dstFile, err := s.client.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		dstFile.Close()
+		return err
+	}
+	if err := dstFile.Close(); err != nil {
+		return err
+	}
+	return s.client.Chtimes(dstPath, info.ModTime(), info.ModTime())
+}
+
+// switchLink creates a temporary symlink, renaming it over the existing one
+// (falling back to a non-atomic replacement if the server does not support the POSIX rename)
+func (s *sftpDeployStore) switchLink(link string, target string) error {
+	tmpLink := link + ".tmp"
+	_ = s.client.Remove(tmpLink)
+	if err := s.client.Symlink(target, tmpLink); err != nil {
+		return err
+	}
+	if err := s.client.PosixRename(tmpLink, link); err == nil {
+		return nil
+	}
+	if err := s.client.Remove(link); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return s.client.Rename(tmpLink, link)
+}
+
+func (s *sftpDeployStore) removeRelease(dir string) error {
+	return s.client.RemoveAll(dir)
+}
+
+// shellReleaseStore manages the releases by running shell commands over SSH (or locally, if no host is given),
+// alongside the rsync deploy target (the local deploy paths are managed by localDeployStore instead)
+type shellReleaseStore struct {
+	host string // `[user@]host`
+}
+
+func (s shellReleaseStore) run(cmd string) (string, error) {
+	var c *exec.Cmd
+	if s.host != "" {
+		c = exec.Command("ssh", s.host, cmd)
+	} else {
+		c = exec.Command("sh", "-c", cmd)
+	}
+	output, err := c.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			err = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+func (s shellReleaseStore) releases(releasesDir string) ([]string, error) {
+	output, err := s.run("if [ -d " + shellQuote(releasesDir) + " ]; then ls -1 " + shellQuote(releasesDir) + "; fi")
+	if err != nil {
+		return nil, err
+	}
+	releases := strings.Fields(output)
+	slices.Sort(releases)
+	return releases, nil
+}
+
+func (s shellReleaseStore) linkTarget(link string) (string, error) {
+	output, err := s.run("if [ -L " + shellQuote(link) + " ]; then readlink " + shellQuote(link) + "; fi")
+	return strings.TrimSpace(output), err
+}
+
+func (s shellReleaseStore) copyRelease(srcDir string, dstDir string) error {
+	cmd := "mkdir -p " + shellQuote(dstDir)
+	if srcDir != "" {
+		cmd += " && cp -a " + shellQuote(srcDir+"/.") + " " + shellQuote(dstDir)
+	}
+	_, err := s.run(cmd)
+	return err
+}
+
+// switchLink creates a temporary symlink, renaming it over the existing one
+// (without following it: `mv -T` of GNU coreutils, or `mv -h` of the BSD one)
+func (s shellReleaseStore) switchLink(link string, target string) error {
+	tmpLink := shellQuote(link + ".tmp")
+	cmd := "if [ -d " + shellQuote(link) + " ] && [ ! -L " + shellQuote(link) + " ]; then echo 'not a symlink: '" + shellQuote(link) + " >&2; exit 1; fi" +
+		" && ln -sfn " + shellQuote(target) + " " + tmpLink +
+		" && { mv -Tf " + tmpLink + " " + shellQuote(link) + " 2>/dev/null || mv -hf " + tmpLink + " " + shellQuote(link) + "; }"
+	_, err := s.run(cmd)
+	return err
+}
+
+func (s shellReleaseStore) removeRelease(dir string) error {
+	_, err := s.run("rm -rf " + shellQuote(dir))
+	return err
+}
+
+// shellQuote quotes the given string as a single (POSIX shell) argument
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// failingDeployTarget fails to deploy the last (root dir) stage
+type failingDeployTarget struct {
+	*mirrorDeployTarget
+}
+
+func (t *failingDeployTarget) deployStage(dOpts deployOptions, plan deployPlan) error {
+	if len(dOpts.exclude) > 0 {
+		return errors.New("connection lost")
+	}
+	return t.mirrorDeployTarget.deployStage(dOpts, plan)
+}
+
+// testReleaseDeploys deploys three releases (keeping two of them), fails to deploy the fourth one,
+// and then rolls back to the previous one
+func testReleaseDeploys(t *testing.T, store interface {
+	deployStore
+	releaseStore
+}, destDir string) {
+	t.Helper()
+	started := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	deployRelease := func(minutes int) {
+		t.Helper()
+		target, err := newReleaseDeployTarget(&mirrorDeployTarget{store: store, root: destDir}, store, destDir, 2, started.Add(time.Duration(minutes)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(deploy(target, &deployRun{}))
+	}
+	currentLink := filepath.Join(destDir, deployCurrentReleaseLinkName)
+
+	deployRelease(0)
+	verifyStringsEqual(string(readDataFromFile(filepath.Join(currentLink, "index.html"))), "home", t)
+
+	writeDeployTestFiles(t, map[string]string{"deploy/index.html": "home v2"})
+	deployRelease(1)
+	writeDeployTestFiles(t, map[string]string{"deploy/index.html": "home v3 (final)"})
+	if err := os.Remove(filepath.Join(deployDirName, "post", "hello.html")); err != nil {
+		t.Fatal(err)
+	}
+	// left over from a failed deploy that failed to remove it
+	check(store.copyRelease("", destDir+"/"+deployReleasesDirName+"/20261017093130"))
+	deployRelease(2)
+	if dirExists(filepath.Join(destDir, deployReleasesDirName, "20261017093130")) {
+		t.Error("expected the failed release leftover to be removed")
+	}
+
+	link, err := os.Readlink(currentLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyStringsEqual(filepath.ToSlash(link), "releases/20261017093200", t)
+	verifyStringsEqual(string(readDataFromFile(filepath.Join(currentLink, "index.html"))), "home v3 (final)", t)
+	if fileExists(filepath.Join(currentLink, "post", "hello.html")) {
+		t.Error("expected the removed post to be deleted from the new release")
+	}
+	if dirExists(filepath.Join(destDir, deployReleasesDirName, "20261017093000")) {
+		t.Error("expected the oldest release to be removed")
+	}
+	releaseDir := filepath.Join(destDir, deployReleasesDirName, "20261017093100")
+	verifyStringsEqual(string(readDataFromFile(filepath.Join(releaseDir, "index.html"))), "home v2", t)
+	if !fileExists(filepath.Join(releaseDir, "post", "hello.html")) || !fileExists(filepath.Join(releaseDir, "media", "post", "hello", "photo.jpg")) {
+		t.Error("expected the previous release to be kept intact")
+	}
+
+	// a failed deploy leaves the current release intact, removing the new one
+	writeDeployTestFiles(t, map[string]string{"deploy/index.html": "home v4 (broken)"})
+	target, err := newReleaseDeployTarget(&failingDeployTarget{&mirrorDeployTarget{store: store, root: destDir}}, store, destDir, 2, started.Add(3*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deploy(target, &deployRun{}); err == nil {
+		t.Fatal("expected the deploy to fail")
+	}
+	if dirExists(filepath.Join(destDir, deployReleasesDirName, "20261017093300")) {
+		t.Error("expected the failed release to be removed")
+	}
+	verifyStringsEqual(string(readDataFromFile(filepath.Join(currentLink, "index.html"))), "home v3 (final)", t)
+
+	target, err = newReleaseDeployTarget(&mirrorDeployTarget{store: store, root: destDir}, store, destDir, 2, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(rollbackRelease(target, &deployRun{}))
+	verifyStringsEqual(string(readDataFromFile(filepath.Join(currentLink, "index.html"))), "home v2", t)
+	if dirExists(filepath.Join(destDir, deployReleasesDirName, "20261017093200")) {
+		t.Error("expected the rolled back release to be removed")
+	}
+	target.current = "20261017093100"
+	if err := rollbackRelease(target, &deployRun{}); err == nil {
+		t.Error("expected no previous release to roll back to")
+	}
+}
+
+func TestReleaseIdsWithinSameSecond(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.ToSlash(tmpDir)
+	now := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	var ids []string
+	for range 11 {
+		target, err := newReleaseDeployTarget(&mirrorDeployTarget{store: localDeployStore{}, root: root}, localDeployStore{}, root, 2, now)
+		check(err)
+		check(os.MkdirAll(filepath.Join(tmpDir, deployReleasesDirName, target.id), 0755))
+		ids = append(ids, target.id)
+	}
+	verifyStringsEqual(ids[1], "20261017093000-002", t)
+	verifyStringsEqual(ids[10], "20261017093000-011", t)
+	// the lexical order (the releases are compared by) is the creation order
+	if !slices.IsSorted(ids) {
+		t.Errorf("expected the release ids to sort in the creation order, got: %v", ids)
+	}
+}
+
+func TestLocalReleaseDeploys(t *testing.T) {
+	tmpDir := setUpDeployTest(t)
+	testReleaseDeploys(t, localDeployStore{}, filepath.ToSlash(filepath.Join(tmpDir, "www")))
+}
+
+func TestSFTPReleaseDeploys(t *testing.T) {
+	tmpDir := setUpDeployTest(t)
+	testReleaseDeploys(t, newTestSFTPDeployStore(t), filepath.ToSlash(filepath.Join(tmpDir, "www")))
+}
+
+func TestShellReleaseStore(t *testing.T) {
+	tmpDir := t.TempDir()
+	store := shellReleaseStore{}
+	releasesDir := filepath.ToSlash(filepath.Join(tmpDir, "it's", deployReleasesDirName))
+	releases, err := store.releases(releasesDir)
+	if err != nil || len(releases) != 0 {
+		t.Fatalf("expected no releases, got: %v (%v)", releases, err)
+	}
+	check(store.copyRelease("", releasesDir+"/1"))
+	check(os.WriteFile(filepath.Join(releasesDir, "1", "index.html"), []byte("home"), 0644))
+	check(store.copyRelease(releasesDir+"/1", releasesDir+"/2"))
+	verifyStringsEqual(string(readDataFromFile(filepath.Join(releasesDir, "2", "index.html"))), "home", t)
+
+	link := filepath.ToSlash(filepath.Join(tmpDir, "it's", deployCurrentReleaseLinkName))
+	check(store.switchLink(link, "releases/1"))
+	check(store.switchLink(link, "releases/2"))
+	target, err := store.linkTarget(link)
+	check(err)
+	verifyStringsEqual(target, "releases/2", t)
+
+	check(store.removeRelease(releasesDir + "/1"))
+	releases, err = store.releases(releasesDir)
+	check(err)
+	if len(releases) != 1 || releases[0] != "2" {
+		t.Errorf("expected a single release left, got: %v", releases)
+	}
+}
+
+func TestLocalRsyncReleaseStore(t *testing.T) {
+	tmpDir := setUpDeployTest(t)
+	config := defaultConfig()
+	config.deployPath = filepath.Join(tmpDir, "www")
+	config.deployReleases = 2
+	target, err := newDeployTarget(config)
+	check(err)
+	defer target.close()
+	// the releases of a local deploy path don't rely on the (GNU specific) shell tools
+	if _, ok := target.(*releaseDeployTarget).store.(localDeployStore); !ok {
+		t.Errorf("expected the local release store for a local rsync deploy path, got: %T", target.(*releaseDeployTarget).store)
+	}
+}
+
+func TestShellReleaseStoreKeepsDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	link := filepath.Join(tmpDir, deployCurrentReleaseLinkName)
+	check(os.MkdirAll(link, 0755))
+	if err := (shellReleaseStore{}).switchLink(filepath.ToSlash(link), "releases/1"); err == nil {
+		t.Error("expected a dir not to be replaced by the current release symlink")
+	}
+	if !dirExists(link) || fileExists(link+".tmp") {
+		t.Error("expected the dir to be left intact")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	verifyStringsEqual(shellQuote("/var/www/it's"), `'/var/www/it'\''s'`, t)
+}
+
+func TestDeployReleasesConfig(t *testing.T) {
+	setUpDeployTest(t)
+	for value, expected := range map[string]int{"0": 0, "1": 0, "2": 2, "5": 5, "-1": 0} {
+		writeDeployTestFiles(t, map[string]string{configFileName: "theme: default\ndeployReleases: " + value + "\n"})
+		if config := readConfig(); config.deployReleases != expected {
+			t.Errorf("unexpected deploy releases for the %s config value: %d", value, config.deployReleases)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3DeployStore deploys to an S3 (compatible) object storage bucket
+type s3DeployStore struct {
+	client *s3.Client
+	bucket string
+}
+
+// newS3Client creates the S3 client configured: the configured access key takes precedence
+// over the default AWS credential chain (environment variables, shared credentials file, etc.),
+// while a custom endpoint (an S3-compatible storage) is accessed with path-style addressing
+func newS3Client(config appConfig) *s3.Client {
+	awsConfig, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(config.deployRegion))
+	check(err)
+	if config.deployAccessKey != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentialsProvider(config.deployAccessKey, config.deploySecretKey, "")
+	}
+	return s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		if config.deployEndpoint != "" {
+			o.BaseEndpoint = aws.String(config.deployEndpoint)
+			o.UsePathStyle = true
+		}
+		// not all the S3-compatible storages support the (newer) default checksums
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+	})
+}
+
+func newS3DeployStore(client *s3.Client, bucket string) *s3DeployStore {
+	return &s3DeployStore{client: client, bucket: bucket}
+}
+
+// rootURI returns the destination root of the given (optional) key prefix, e.g. `s3://bucket/prefix`
+func (s *s3DeployStore) rootURI(prefix string) string {
+	uri := "s3://" + s.bucket
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		uri += "/" + prefix
+	}
+	return uri
+}
+
+// key returns the object key of the given destination path
+func (s *s3DeployStore) key(dstPath string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(dstPath, s.rootURI("")), "/")
+}
+
+func (s *s3DeployStore) list(dir string) (map[string]deployFileInfo, error) {
+	files := map[string]deployFileInfo{}
+	prefix := s.key(dir)
+	if prefix != "" {
+		prefix = strings.TrimSuffix(prefix, "/") + "/"
+	}
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			files[strings.TrimPrefix(aws.ToString(obj.Key), prefix)] = deployFileInfo{
+				size: aws.ToInt64(obj.Size),
+				etag: strings.Trim(aws.ToString(obj.ETag), `"`),
+			}
+		}
+	}
+	return files, nil
+}
+
+// upToDate compares the sizes and the content hashes (the entity tag of an object uploaded in a single part
+// is the MD5 hash of its content)
+func (s *s3DeployStore) upToDate(srcPath string, src fs.FileInfo, dst deployFileInfo) bool {
+	if src.Size() != dst.size {
+		return false
+	}
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false
+	}
+	return hex.EncodeToString(h.Sum(nil)) == dst.etag
+}
+
+func (s *s3DeployStore) upload(srcPath string, src fs.FileInfo, dstPath string) error {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(s.key(dstPath)),
+		Body:          f,
+		ContentLength: aws.Int64(src.Size()),
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(srcPath)); contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	_, err = s.client.PutObject(context.Background(), input)
+	return err
+}
+
+func (s *s3DeployStore) remove(dstPath string) error {
+	_, err := s.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(dstPath)),
+	})
+	return err
+}
+
+func (s *s3DeployStore) removeEmptyDir(dstDir string) error {
+	// an object storage has no dirs
+	return nil
+}
+
+func (s *s3DeployStore) close() error {
+	return nil
+}
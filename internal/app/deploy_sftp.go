@@ -0,0 +1,174 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpDeployStore deploys over SFTP (natively, no external tools required)
+type sftpDeployStore struct {
+	client    *sftp.Client
+	sshClient *ssh.Client // nil if not connected over SSH (e.g. in tests)
+}
+
+// newSFTPDeployStore connects to the configured SFTP server, authenticating with the configured password,
+// the configured private key file (or the default ones) and/or the ssh-agent keys;
+// the server host key has to be listed in the known hosts file
+func newSFTPDeployStore(config appConfig) (*sftpDeployStore, error) {
+	username := config.deployUsername
+	if username == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+		username = u.Username
+	}
+	homeDir, _ := os.UserHomeDir()
+	knownHostsFile := config.deployKnownHostsFile
+	if knownHostsFile == "" {
+		knownHostsFile = filepath.Join(homeDir, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the known hosts file: %w", err)
+	}
+	var authMethods []ssh.AuthMethod
+	if config.deployPassword != "" {
+		authMethods = append(authMethods, ssh.Password(config.deployPassword))
+	}
+	var signers []ssh.Signer
+	keyFiles := []string{config.deployKeyFile}
+	if config.deployKeyFile == "" {
+		keyFiles = []string{
+			filepath.Join(homeDir, ".ssh", "id_ed25519"),
+			filepath.Join(homeDir, ".ssh", "id_ecdsa"),
+			filepath.Join(homeDir, ".ssh", "id_rsa"),
+		}
+	}
+	for _, keyFile := range keyFiles {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			if config.deployKeyFile != "" {
+				return nil, err
+			}
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			// e.g. a passphrase protected key (expected to be loaded into the ssh-agent instead)
+			if config.deployKeyFile != "" {
+				return nil, fmt.Errorf("failed to parse the private key file: %s: %w", keyFile, err)
+			}
+			continue
+		}
+		signers = append(signers, signer)
+	}
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			// the agent is only needed during the SSH handshake
+			defer conn.Close()
+			if agentSigners, err := agent.NewClient(conn).Signers(); err == nil {
+				signers = append(signers, agentSigners...)
+			}
+		}
+	}
+	if len(signers) > 0 {
+		authMethods = append(authMethods, ssh.PublicKeys(signers...))
+	}
+	if len(authMethods) == 0 {
+		return nil, errors.New("no SFTP credentials found: set the deploy password, or provide a private key file (or an ssh-agent)")
+	}
+	sshClient, err := ssh.Dial("tcp", net.JoinHostPort(config.deployHost, strconv.Itoa(config.deployPort)), &ssh.ClientConfig{
+		User:            username,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+	return &sftpDeployStore{client: client, sshClient: sshClient}, nil
+}
+
+func (s *sftpDeployStore) list(dir string) (map[string]deployFileInfo, error) {
+	files := map[string]deployFileInfo{}
+	if _, err := s.client.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return files, nil
+	}
+	walker := s.client.Walk(dir)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return nil, err
+		}
+		info := walker.Stat()
+		if info.IsDir() {
+			continue
+		}
+		relPath := strings.TrimPrefix(walker.Path(), strings.TrimSuffix(dir, "/")+"/")
+		files[relPath] = deployFileInfo{size: info.Size(), modTime: info.ModTime()}
+	}
+	return files, nil
+}
+
+// upToDate compares the sizes and modification times (the uploaded files get the source modification time)
+func (s *sftpDeployStore) upToDate(srcPath string, src fs.FileInfo, dst deployFileInfo) bool {
+	return src.Size() == dst.size && src.ModTime().Unix() == dst.modTime.Unix()
+}
+
+func (s *sftpDeployStore) upload(srcPath string, src fs.FileInfo, dstPath string) error {
+	if err := s.client.MkdirAll(path.Dir(dstPath)); err != nil {
+		return err
+	}
+	srcFile, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	dstFile, err := s.client.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		dstFile.Close()
+		return err
+	}
+	if err := dstFile.Close(); err != nil {
+		return err
+	}
+	return s.client.Chtimes(dstPath, src.ModTime(), src.ModTime())
+}
+
+func (s *sftpDeployStore) remove(dstPath string) error {
+	return s.client.Remove(dstPath)
+}
+
+func (s *sftpDeployStore) removeEmptyDir(dstDir string) error {
+	// fails for a non-empty dir
+	return s.client.RemoveDirectory(dstDir)
+}
+
+func (s *sftpDeployStore) close() error {
+	err := s.client.Close()
+	if s.sshClient != nil {
+		err = errors.Join(err, s.sshClient.Close())
+	}
+	return err
+}
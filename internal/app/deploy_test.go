@@ -1,9 +1,23 @@
 package app
 
 import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
 )
 
 // upload order must ensure no broken links mid-deploy: content that is linked to
@@ -47,3 +61,435 @@ func TestBuildDeployOptions(t *testing.T) {
 		}
 	}
 }
+
+// setUpDeployTest creates a temp working dir with a generated deploy dir, returning the temp dir path
+func setUpDeployTest(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+	writeDeployTestFiles(t, map[string]string{
+		"deploy/index.html":                 "home",
+		"deploy/post/hello.html":            "hello",
+		"deploy/media/post/hello/photo.jpg": "photo",
+		"deploy/resources/css/styles.css":   "styles",
+	})
+	return tmpDir
+}
+
+func writeDeployTestFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for p, content := range files {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLocalDeployTarget(t *testing.T) {
+	tmpDir := setUpDeployTest(t)
+	destDir := filepath.Join(tmpDir, "www")
+
+	config := defaultConfig()
+	config.deployTarget = LocalDeployTarget
+	config.deployPath = destDir
+	target, err := newDeployTarget(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(deploy(target, &deployRun{}))
+	for p, content := range map[string]string{"index.html": "home", "post/hello.html": "hello", "media/post/hello/photo.jpg": "photo", "resources/css/styles.css": "styles"} {
+		verifyStringsEqual(string(readDataFromFile(filepath.Join(destDir, p))), content, t)
+	}
+
+	// a removed post (along with its media) and an updated file
+	if err := os.RemoveAll(filepath.Join(deployDirName, mediaDirName, "post")); err != nil {
+		t.Fatal(err)
+	}
+	writeDeployTestFiles(t, map[string]string{"deploy/index.html": "home v2"})
+	source, _ := filepath.Abs(deployDirName)
+	opts := buildDeployOptions(source, filepath.ToSlash(destDir), '/')
+	plan, err := planDeployStage(opts[len(opts)-1], localDeployStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.add) != 0 || !slices.Equal(plan.update, []string{"index.html"}) || len(plan.remove) != 0 {
+		t.Errorf("unexpected catch-all stage plan: %+v", plan)
+	}
+	check(deploy(target, &deployRun{}))
+	verifyStringsEqual(string(readDataFromFile(filepath.Join(destDir, "index.html"))), "home v2", t)
+	if fileExists(filepath.Join(destDir, "media", "post", "hello", "photo.jpg")) || dirExists(filepath.Join(destDir, "media", "post")) {
+		t.Error("expected the removed media (along with the emptied dirs) to be deleted")
+	}
+	if !fileExists(filepath.Join(destDir, "post", "hello.html")) {
+		t.Error("expected the files of the other stages to be kept")
+	}
+}
+
+// newTestSFTPDeployStore connects to an in-process SFTP server (serving the local file system)
+// in place of an SSH connection
+func newTestSFTPDeployStore(t *testing.T) *sftpDeployStore {
+	t.Helper()
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverReader, serverWriter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	client, err := sftp.NewClientPipe(clientReader, clientWriter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &sftpDeployStore{client: client}
+	t.Cleanup(func() {
+		// the server side is closed first, so that the client doesn't wait for it
+		serverWriter.Close()
+		store.close()
+	})
+	return store
+}
+
+func TestSFTPDeployStore(t *testing.T) {
+	tmpDir := setUpDeployTest(t)
+	destDir := filepath.ToSlash(filepath.Join(tmpDir, "www"))
+
+	target := &mirrorDeployTarget{store: newTestSFTPDeployStore(t), root: destDir}
+	check(deploy(target, &deployRun{}))
+	verifyStringsEqual(string(readDataFromFile(filepath.Join(destDir, "post", "hello.html"))), "hello", t)
+	verifyStringsEqual(string(readDataFromFile(filepath.Join(destDir, "media", "post", "hello", "photo.jpg"))), "photo", t)
+
+	source, _ := filepath.Abs(deployDirName)
+	for _, opts := range buildDeployOptions(source, destDir, '/') {
+		if !dirExists(opts.source) {
+			continue
+		}
+		plan, err := planDeployStage(opts, target.store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan.add)+len(plan.update)+len(plan.remove) != 0 {
+			t.Errorf("%s: expected no changes after deploy, got: %+v", opts.destination, plan)
+		}
+	}
+}
+
+// s3StandIn is a minimal in-memory S3 stand-in server (path-style bucket addressing):
+// just the object listing, upload and removal operations
+type s3StandIn struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	puts    int
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.bucket {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		prefix := r.URL.Query().Get("prefix")
+		var keys []string
+		for k := range s.objects {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		var sb strings.Builder
+		sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?><ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">`)
+		sb.WriteString("<Name>" + s.bucket + "</Name><Prefix>" + prefix + "</Prefix><KeyCount>" + strconv.Itoa(len(keys)) + "</KeyCount><IsTruncated>false</IsTruncated>")
+		for _, k := range keys {
+			sum := md5.Sum(s.objects[k])
+			sb.WriteString("<Contents><Key>" + k + "</Key><Size>" + strconv.Itoa(len(s.objects[k])) + "</Size><ETag>&quot;" + hex.EncodeToString(sum[:]) + "&quot;</ETag></Contents>")
+		}
+		sb.WriteString("</ListBucketResult>")
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(sb.String()))
+	case r.Method == http.MethodPut && key != "":
+		data, _ := io.ReadAll(r.Body)
+		s.objects[key] = data
+		s.puts++
+		sum := md5.Sum(data)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	case r.Method == http.MethodDelete && key != "":
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "NotImplemented", http.StatusNotImplemented)
+	}
+}
+
+func TestS3DeployStore(t *testing.T) {
+	setUpDeployTest(t)
+	standIn := &s3StandIn{bucket: "site", objects: map[string][]byte{"www/stale.html": []byte("stale"), "other/keep.html": []byte("keep")}}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	config := defaultConfig()
+	config.deployTarget = S3DeployTarget
+	config.deployBucket = "site"
+	config.deployPath = "/www/"
+	config.deployEndpoint = server.URL
+	config.deployAccessKey = "test-access-key"
+	config.deploySecretKey = "test-secret-key"
+	target, err := newDeployTarget(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	destination, _ := target.destination()
+	verifyStringsEqual(destination, "s3://site/www", t)
+
+	check(deploy(target, &deployRun{}))
+	expected := []string{"other/keep.html", "www/index.html", "www/media/post/hello/photo.jpg", "www/post/hello.html", "www/resources/css/styles.css"}
+	keys := slices.Sorted(maps.Keys(standIn.objects))
+	if !slices.Equal(keys, expected) {
+		t.Fatalf("expected objects %v, got: %v", expected, keys)
+	}
+	verifyStringsEqual(string(standIn.objects["www/post/hello.html"]), "hello", t)
+
+	// only the changed files are uploaded again
+	writeDeployTestFiles(t, map[string]string{"deploy/post/hello.html": "hello v2"})
+	puts := standIn.puts
+	check(deploy(target, &deployRun{}))
+	if standIn.puts != puts+1 {
+		t.Errorf("expected a single upload, got: %d", standIn.puts-puts)
+	}
+	verifyStringsEqual(string(standIn.objects["www/post/hello.html"]), "hello v2", t)
+}
+
+func TestConfigEnvVarName(t *testing.T) {
+	verifyStringsEqual(configEnvVarName("deployAccessKey"), "MBGEN_DEPLOY_ACCESS_KEY", t)
+	verifyStringsEqual(configEnvVarName("deployTarget"), "MBGEN_DEPLOY_TARGET", t)
+}
+
+func TestDeployConfigFromEnv(t *testing.T) {
+	setUpDeployTest(t)
+	writeDeployTestFiles(t, map[string]string{configFileName: "theme: default\ndeployTarget: sftp\ndeployPath: /var/www\ndeployPassword: from-config\n"})
+	t.Setenv("MBGEN_DEPLOY_TARGET", "s3")
+	t.Setenv("MBGEN_DEPLOY_SECRET_KEY", "from-env")
+	config := readConfig()
+	if config.deployTarget != S3DeployTarget {
+		t.Errorf("expected the s3 deploy target, got: %s", config.deployTarget)
+	}
+	verifyStringsEqual(config.deploySecretKey, "from-env", t)
+	verifyStringsEqual(config.deployPassword, "from-config", t)
+	verifyStringsEqual(config.deployPath, "/var/www", t)
+	verifyStringsEqual(config.deployRegion, defaultDeployRegion, t)
+}
+
+func TestThemeActivationLeavesOutDeployConfigFromEnv(t *testing.T) {
+	setUpDeployTest(t)
+	writeDeployTestFiles(t, map[string]string{
+		configFileName: "theme: default\ndeployTarget: sftp\ndeployPath: /var/www\n" +
+			"deployPassword: \"file#pass: word\"\ndeployAccessKey: file-access-key\n",
+		filepath.Join(themesDirName, "other", "templates", "main.html"): "main",
+	})
+	t.Setenv("MBGEN_DEPLOY_TARGET", "s3")
+	t.Setenv("MBGEN_DEPLOY_PATH", "/from/env")
+	t.Setenv("MBGEN_DEPLOY_BUCKET", "from-env-bucket")
+	t.Setenv("MBGEN_DEPLOY_ACCESS_KEY", "env-access-key")
+	t.Setenv("MBGEN_DEPLOY_SECRET_KEY", "env-secret-key")
+	_theme(readConfig(), commandThemeActionActivate, "other")
+
+	configFile := string(readDataFromFile(configFileName))
+	for _, envValue := range []string{"s3", "/from/env", "from-env-bucket", "env-access-key", "env-secret-key"} {
+		if strings.Contains(configFile, envValue) {
+			t.Errorf("the deploy option set via an environment variable was written to the config file: %s", envValue)
+		}
+	}
+	config := readPersistedConfig()
+	verifyStringsEqual(config.theme, filepath.Join(themesDirName, "other"), t)
+	verifyStringsEqual(config.deployPath, "/var/www", t)
+	if config.deployTarget != SFTPDeployTarget {
+		t.Errorf("expected the sftp deploy target, got: %s", config.deployTarget)
+	}
+	// the credentials set in the config file are kept
+	verifyStringsEqual(config.deployPassword, "file#pass: word", t)
+	verifyStringsEqual(config.deployAccessKey, "file-access-key", t)
+	verifyStringsEqual(config.deploySecretKey, "", t)
+}
+
+func TestReadEnvironmentConfig(t *testing.T) {
+	setUpDeployTest(t)
+	writeDeployTestFiles(t, map[string]string{configFileName: "siteBaseURL: https://example.com\ntheme: default\ndeployPath: /var/www\n" +
+		"deployHost: example.com\ndeployUsername: www\nenvironments:\n" +
+		"  staging:\n    siteBaseURL: https://staging.example.com\n    deployTarget: local\n    deployPath: /tmp/staging\n    generateSitemap: no\n" +
+		"  production:\n    deployDeleteThreshold: 100\n"})
+
+	config := readConfig()
+	verifyStringsEqual(config.siteBaseURL, "https://example.com", t)
+	verifyStringsEqual(config.environment, "", t)
+	if len(config.environments) != 2 {
+		t.Fatalf("expected 2 deploy environments, got: %v", config.environments)
+	}
+
+	staging := readEnvironmentConfig("staging")
+	verifyStringsEqual(staging.environment, "staging", t)
+	verifyStringsEqual(staging.siteBaseURL, "https://staging.example.com", t)
+	verifyStringsEqual(staging.deployPath, "/tmp/staging", t)
+	verifyStringsEqual(staging.deployHost, "example.com", t)
+	if staging.deployTarget != LocalDeployTarget || staging.generateSitemap {
+		t.Errorf("expected the staging overrides to be applied, got: %s, %t", staging.deployTarget, staging.generateSitemap)
+	}
+	if buildInputsHash(staging) == buildInputsHash(config) {
+		t.Error("expected the build inputs hash to depend on the deploy environment")
+	}
+
+	production := readEnvironmentConfig("production")
+	verifyStringsEqual(production.siteBaseURL, "https://example.com", t)
+	if production.deployDeleteThreshold != 100 {
+		t.Errorf("expected the production delete threshold override, got: %d", production.deployDeleteThreshold)
+	}
+
+	// the environments are kept when the config is written back (e.g. on a theme activation)
+	writeConfig(config)
+	verifyStringsEqual(readEnvironmentConfig("staging").siteBaseURL, "https://staging.example.com", t)
+}
+
+func TestEnvironmentConfigOverridesDeployConfigFromEnv(t *testing.T) {
+	setUpDeployTest(t)
+	writeDeployTestFiles(t, map[string]string{configFileName: "theme: default\ndeployPath: /var/www\ndeployHost: example.com\n" +
+		"environments:\n  staging:\n    deployPath: /tmp/staging\n"})
+	t.Setenv("MBGEN_DEPLOY_PATH", "/from/env")
+	t.Setenv("MBGEN_DEPLOY_HOST", "env.example.com")
+
+	// the environment variables only apply to the deploy options the environment doesn't override
+	staging := readEnvironmentConfig("staging")
+	verifyStringsEqual(staging.deployPath, "/tmp/staging", t)
+	verifyStringsEqual(staging.deployHost, "env.example.com", t)
+	verifyStringsEqual(readConfig().deployPath, "/from/env", t)
+}
+
+func TestRsyncArgs(t *testing.T) {
+	dOpts := deployOptions{source: "deploy/post/", destination: "www@example.com:/var/www/post"}
+	// the deploy run compares the file checksums, just like the planning (dry) run,
+	// so that the files it transfers match the planned changes
+	for _, args := range [][]string{rsyncArgs(dOpts, "--progress", "--verbose"), rsyncArgs(dOpts, "--dry-run", "--itemize-changes")} {
+		if !slices.Contains(args, "--checksum") || !slices.Contains(args, "--no-t") {
+			t.Errorf("expected the files to be compared by their checksums: %v", args)
+		}
+		verifyStringsEqual(args[len(args)-1], dOpts.destination, t)
+	}
+}
+
+func TestParseRsyncItemizedChanges(t *testing.T) {
+	output := "sending incremental file list\n" +
+		"*deleting   post/old.html\n" +
+		"*deleting   media/post/old/\n" +
+		".d..t...... post/\n" +
+		">f+++++++++ post/new.html\n" +
+		">f.s....... index.html\n" +
+		">fc........ about.html\n" +
+		"cd+++++++++ media/post/new/\n" +
+		".f...p..... unchanged.html\n" +
+		"\nsent 123 bytes  received 45 bytes\n"
+	plan := parseRsyncItemizedChanges(output)
+	if !slices.Equal(plan.add, []string{"post/new.html"}) ||
+		!slices.Equal(plan.update, []string{"about.html", "index.html"}) ||
+		!slices.Equal(plan.remove, []string{"post/old.html"}) {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestDeployDryRun(t *testing.T) {
+	tmpDir := setUpDeployTest(t)
+	destDir := filepath.Join(tmpDir, "www")
+
+	config := defaultConfig()
+	config.deployTarget = LocalDeployTarget
+	config.deployPath = destDir
+	target, err := newDeployTarget(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := &deployRun{dryRun: true}
+	check(deploy(target, run))
+	if dirExists(destDir) {
+		t.Error("expected nothing to be deployed on a dry run")
+	}
+	verifyStringContains(run.log.String(), "dry run: true", t)
+	verifyStringContains(run.log.String(), " - added: 2\n   - index.html\n   - resources/css/styles.css\n - updated: 0\n - deleted: 0\n - status: dry run (nothing deployed)", t)
+}
+
+func TestDeployDeleteConfirmation(t *testing.T) {
+	tmpDir := setUpDeployTest(t)
+	destDir := filepath.Join(tmpDir, "www")
+
+	config := defaultConfig()
+	config.deployTarget = LocalDeployTarget
+	config.deployPath = destDir
+	target, err := newDeployTarget(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(deploy(target, &deployRun{}))
+	writeDeployTestFiles(t, map[string]string{"deploy/post/other.html": "other"})
+	if err := os.Remove(filepath.Join(deployDirName, "post", "hello.html")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a single deletion, above the threshold: declined
+	prompted := false
+	declined := func(prompt string) bool {
+		prompted = true
+		verifyStringContains(prompt, "1 files are to be deleted (the threshold is 0)", t)
+		return false
+	}
+	if err := deploy(target, &deployRun{deleteThreshold: 0, confirm: declined}); !errors.Is(err, errDeployAborted) {
+		t.Fatalf("expected the deploy to be aborted, got: %v", err)
+	}
+	if !prompted || !fileExists(filepath.Join(destDir, "post", "hello.html")) {
+		t.Fatal("expected the confirmation to be asked for and nothing to be deleted")
+	}
+
+	// above the threshold, non-interactively: failed without prompting
+	prompted = false
+	err = deploy(target, &deployRun{deleteThreshold: 0, confirm: declined, nonInteractive: true})
+	if !errors.Is(err, errDeployNotConfirmable) {
+		t.Fatalf("expected the deploy to fail non-interactively, got: %v", err)
+	}
+	verifyStringContains(err.Error(), commandDeployOptionYes, t)
+	if prompted || !fileExists(filepath.Join(destDir, "post", "hello.html")) {
+		t.Fatal("expected no confirmation to be asked for and nothing to be deleted")
+	}
+
+	// within the threshold: no confirmation asked for
+	prompted = false
+	check(deploy(target, &deployRun{deleteThreshold: 1, confirm: declined}))
+	if prompted {
+		t.Error("expected no confirmation within the threshold")
+	}
+	if fileExists(filepath.Join(destDir, "post", "hello.html")) {
+		t.Error("expected the removed post to be deleted")
+	}
+}
+
+func TestWriteDeployLog(t *testing.T) {
+	setUpDeployTest(t)
+	run := &deployRun{started: time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)}
+	run.logf("source: %s", "/src/deploy")
+	logFilePath := writeDeployLog(run, LocalDeployTarget, errDeployAborted)
+	verifyStringsEqual(logFilePath, filepath.Join(deployLogDirName, "deploy-20261017-093000.log"), t)
+	log := string(readDataFromFile(logFilePath))
+	verifyStringContains(log, "target: local\nsource: /src/deploy\n", t)
+	verifyStringContains(log, "exit status: 1 (deploy aborted: deletions not confirmed)", t)
+	verifyStringsEqual(writeDeployLog(run, LocalDeployTarget, nil), filepath.Join(deployLogDirName, "deploy-20261017-093000-2.log"), t)
+}
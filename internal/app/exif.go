@@ -0,0 +1,322 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+
+	"cloud.google.com/go/civil"
+)
+
+// exifInfo is the EXIF metadata of an image relevant for the site generation
+type exifInfo struct {
+	orientation int            // 1-8 (0 if not specified)
+	captureTime civil.DateTime // the original date/time (falling back to the modification one)
+	camera      string         // the camera make and model
+	hasGPS      bool
+}
+
+// swapsDimensions reports whether the image is rotated by 90 degrees (either way) once the orientation is applied
+func (ei exifInfo) swapsDimensions() bool {
+	return ei.orientation >= 5 && ei.orientation <= 8
+}
+
+const (
+	exifTagMake             = 0x010f
+	exifTagModel            = 0x0110
+	exifTagOrientation      = 0x0112
+	exifTagDateTime         = 0x0132
+	exifTagExifIFD          = 0x8769
+	exifTagGPSIFD           = 0x8825
+	exifTagDateTimeOriginal = 0x9003
+
+	jpegMarkerAPP1 = 0xe1
+	jpegMarkerSOS  = 0xda
+)
+
+var (
+	// the value sizes of the IFD entry types (byte, ascii, short, long, rational, undefined, slong, srational)
+	exifTypeSizes  = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+	jpegExifHeader = []byte("Exif\x00\x00")
+	jpegXMPHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	pngSignature   = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// exifEntry is an IFD entry, along with the position of its value (or value offset) field
+type exifEntry struct {
+	tag      uint16
+	typ      uint16
+	count    uint32
+	valuePos int
+}
+
+// exifTIFF is the TIFF structure the EXIF metadata is stored in
+type exifTIFF struct {
+	data []byte
+	bo   binary.ByteOrder
+}
+
+func newExifTIFF(data []byte) (exifTIFF, bool) {
+	if len(data) < 8 {
+		return exifTIFF{}, false
+	}
+	var bo binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return exifTIFF{}, false
+	}
+	if bo.Uint16(data[2:4]) != 42 {
+		return exifTIFF{}, false
+	}
+	return exifTIFF{data: data, bo: bo}, true
+}
+
+// entries returns the entries of the IFD at the given offset
+func (t exifTIFF) entries(offset uint32) []exifEntry {
+	if offset == 0 || int(offset)+2 > len(t.data) {
+		return nil
+	}
+	n := int(t.bo.Uint16(t.data[offset:]))
+	var entries []exifEntry
+	for i := 0; i < n; i++ {
+		pos := int(offset) + 2 + i*12
+		if pos+12 > len(t.data) {
+			break
+		}
+		entries = append(entries, exifEntry{
+			tag:      t.bo.Uint16(t.data[pos:]),
+			typ:      t.bo.Uint16(t.data[pos+2:]),
+			count:    t.bo.Uint32(t.data[pos+4:]),
+			valuePos: pos + 8,
+		})
+	}
+	return entries
+}
+
+// value returns the value bytes of the given entry (stored inline if not larger than 4 bytes)
+func (t exifTIFF) value(e exifEntry) []byte {
+	size := exifTypeSizes[e.typ] * int(e.count)
+	if size <= 4 {
+		return t.data[e.valuePos : e.valuePos+size]
+	}
+	offset := int(t.bo.Uint32(t.data[e.valuePos:]))
+	if offset+size > len(t.data) || offset+size < offset {
+		return nil
+	}
+	return t.data[offset : offset+size]
+}
+
+func (t exifTIFF) ascii(e exifEntry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(t.value(e)), "\x00"))
+}
+
+func (t exifTIFF) uint(e exifEntry) uint32 {
+	switch v := t.value(e); {
+	case e.typ == 3 && len(v) >= 2:
+		return uint32(t.bo.Uint16(v))
+	case e.typ == 4 && len(v) >= 4:
+		return t.bo.Uint32(v)
+	}
+	return 0
+}
+
+func (t exifTIFF) info() exifInfo {
+	var ei exifInfo
+	var cameraMake, model, dateTime, dateTimeOriginal string
+	for _, e := range t.entries(t.bo.Uint32(t.data[4:8])) {
+		switch e.tag {
+		case exifTagMake:
+			cameraMake = t.ascii(e)
+		case exifTagModel:
+			model = t.ascii(e)
+		case exifTagOrientation:
+			ei.orientation = int(t.uint(e))
+		case exifTagDateTime:
+			dateTime = t.ascii(e)
+		case exifTagExifIFD:
+			for _, e := range t.entries(t.uint(e)) {
+				if e.tag == exifTagDateTimeOriginal {
+					dateTimeOriginal = t.ascii(e)
+				}
+			}
+		case exifTagGPSIFD:
+			ei.hasGPS = len(t.entries(t.uint(e))) > 0
+		}
+	}
+	// the model usually includes the make (e.g. `Canon` / `Canon EOS R6`), but not always (e.g. `Apple` / `iPhone 13`)
+	if cameraMake != "" && !strings.HasPrefix(strings.ToLower(model), strings.ToLower(cameraMake)) {
+		ei.camera = strings.TrimSpace(cameraMake + " " + model)
+	} else {
+		ei.camera = model
+	}
+	for _, dt := range []string{dateTimeOriginal, dateTime} {
+		// `YYYY:MM:DD HH:MM:SS`
+		if len(dt) == 19 {
+			if captureTime, err := civil.ParseDateTime(strings.Replace(dt[:10], ":", "-", 2) + "T" + dt[11:]); err == nil {
+				ei.captureTime = captureTime
+				break
+			}
+		}
+	}
+	return ei
+}
+
+// clearGPS blanks out the GPS IFD (along with the values it refers to) in place,
+// leaving the rest of the metadata intact
+func (t exifTIFF) clearGPS() bool {
+	cleared := false
+	for _, e := range t.entries(t.bo.Uint32(t.data[4:8])) {
+		if e.tag != exifTagGPSIFD {
+			continue
+		}
+		offset := t.uint(e)
+		for _, gpsEntry := range t.entries(offset) {
+			clear(t.value(gpsEntry))
+			clear(t.data[gpsEntry.valuePos-8 : gpsEntry.valuePos+4])
+			cleared = true
+		}
+		if int(offset)+2 <= len(t.data) {
+			t.bo.PutUint16(t.data[offset:], 0)
+		}
+	}
+	return cleared
+}
+
+// forEachJPEGSegment calls the given function for each of the JPEG header segments (the ones preceding the image data)
+// with the segment marker, offset and length (including the marker), returning false if the data isn't a JPEG
+func forEachJPEGSegment(data []byte, fn func(marker byte, offset int, length int)) bool {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return false
+	}
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xff {
+		marker := data[pos+1]
+		if marker == jpegMarkerSOS {
+			break
+		}
+		// the segment length covers the length field itself, so anything shorter is malformed
+		length := 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 4 || pos+length > len(data) {
+			break
+		}
+		fn(marker, pos, length)
+		pos += length
+	}
+	return true
+}
+
+// forEachPNGChunk calls the given function for each of the PNG chunks with the chunk type, offset and length
+// (including the length, type and CRC fields), returning false if the data isn't a PNG
+func forEachPNGChunk(data []byte, fn func(chunkType string, offset int, length int)) bool {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return false
+	}
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := 12 + int(binary.BigEndian.Uint32(data[pos:]))
+		if length < 12 || pos+length > len(data) {
+			break
+		}
+		fn(string(data[pos+4:pos+8]), pos, length)
+		pos += length
+	}
+	return true
+}
+
+// findImgExif returns the EXIF metadata (TIFF) of the given JPEG/PNG image data
+func findImgExif(data []byte) (exifTIFF, bool) {
+	var tiff exifTIFF
+	found := false
+	forEachJPEGSegment(data, func(marker byte, offset int, length int) {
+		if payload := data[offset+4 : offset+length]; !found && marker == jpegMarkerAPP1 && bytes.HasPrefix(payload, jpegExifHeader) {
+			tiff, found = newExifTIFF(payload[len(jpegExifHeader):])
+		}
+	})
+	forEachPNGChunk(data, func(chunkType string, offset int, length int) {
+		if !found && chunkType == "eXIf" {
+			tiff, found = newExifTIFF(data[offset+8 : offset+length-4])
+		}
+	})
+	return tiff, found
+}
+
+// readImgExif reads the EXIF metadata of the given JPEG/PNG image data
+func readImgExif(data []byte) exifInfo {
+	if tiff, ok := findImgExif(data); ok {
+		return tiff.info()
+	}
+	return exifInfo{}
+}
+
+// stripImgExif removes the metadata from the given JPEG/PNG image data (see the stripImgMetadata config option):
+// either the GPS data only (blanked out in place, while the XMP metadata, which may duplicate it, is removed),
+// or all of the EXIF and XMP metadata, returning nil if there's nothing to remove
+func stripImgExif(data []byte, policy imgMetadataStripPolicy) []byte {
+	stripped := bytes.Clone(data)
+	changed := false
+	if policy == GPSImgMetadataStrip {
+		if tiff, ok := findImgExif(stripped); ok && tiff.clearGPS() {
+			changed = true
+			// the PNG chunk CRC covers the (changed) chunk data
+			forEachPNGChunk(stripped, func(chunkType string, offset int, length int) {
+				if chunkType == "eXIf" && length >= 12 {
+					crc := crc32.ChecksumIEEE(stripped[offset+4 : offset+length-4])
+					binary.BigEndian.PutUint32(stripped[offset+length-4:], crc)
+				}
+			})
+		}
+	}
+	var kept []byte
+	removed := false
+	isJPEG := forEachJPEGSegment(stripped, func(marker byte, offset int, length int) {
+		payload := stripped[offset+4 : offset+length]
+		if marker == jpegMarkerAPP1 && (bytes.HasPrefix(payload, jpegXMPHeader) ||
+			(policy == AllImgMetadataStrip && bytes.HasPrefix(payload, jpegExifHeader))) {
+			if kept == nil {
+				kept = append(kept, stripped[:offset]...)
+			}
+			removed = true
+			return
+		}
+		if kept != nil {
+			kept = append(kept, stripped[offset:offset+length]...)
+		}
+	})
+	if isJPEG && removed {
+		// the image data following the header segments
+		headerLen := 2
+		forEachJPEGSegment(stripped, func(marker byte, offset int, length int) {
+			headerLen = offset + length
+		})
+		stripped = append(kept, stripped[headerLen:]...)
+		changed = true
+	}
+	if policy == AllImgMetadataStrip {
+		var kept []byte
+		forEachPNGChunk(stripped, func(chunkType string, offset int, length int) {
+			if kept == nil {
+				kept = append(kept, stripped[:offset]...)
+			}
+			if chunkType == "eXIf" {
+				changed = true
+				return
+			}
+			kept = append(kept, stripped[offset:offset+length]...)
+		})
+		if kept != nil {
+			stripped = kept
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return stripped
+}
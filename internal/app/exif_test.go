@@ -0,0 +1,199 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// gpsTestLatitude is the (easy to spot) GPS latitude value of the test EXIF metadata
+var gpsTestLatitude = []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+
+// exifTestTIFF builds the EXIF metadata (little-endian TIFF) with the camera make and model, orientation,
+// original date/time and GPS latitude
+func exifTestTIFF(orientation int) []byte {
+	data := make([]byte, 170)
+	le := binary.LittleEndian
+	copy(data, "II")
+	le.PutUint16(data[2:], 42)
+	le.PutUint32(data[4:], 8)
+	entry := func(pos int, tag uint16, typ uint16, count uint32, value uint32) {
+		le.PutUint16(data[pos:], tag)
+		le.PutUint16(data[pos+2:], typ)
+		le.PutUint32(data[pos+4:], count)
+		le.PutUint32(data[pos+8:], value)
+	}
+	// IFD0
+	le.PutUint16(data[8:], 5)
+	entry(10, exifTagMake, 2, 6, 74)
+	entry(22, exifTagModel, 2, 10, 80)
+	entry(34, exifTagOrientation, 3, 1, uint32(orientation))
+	entry(46, exifTagExifIFD, 4, 1, 90)
+	entry(58, exifTagGPSIFD, 4, 1, 128)
+	copy(data[74:], "Apple\x00")
+	copy(data[80:], "iPhone 13\x00")
+	// Exif IFD
+	le.PutUint16(data[90:], 1)
+	entry(92, exifTagDateTimeOriginal, 2, 20, 108)
+	copy(data[108:], "2024:05:17 14:30:00\x00")
+	// GPS IFD: the latitude (3 rationals)
+	le.PutUint16(data[128:], 1)
+	entry(130, 0x0002, 5, 3, 146)
+	for i := 0; i < 3; i++ {
+		copy(data[146+i*8:], gpsTestLatitude)
+	}
+	return data
+}
+
+func exifTestJPEG(t *testing.T, width int, height int, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	check(jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+	data := buf.Bytes()
+	xmp := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), "<x:xmpmeta/>"...)
+	var segments []byte
+	for _, payload := range [][]byte{append([]byte("Exif\x00\x00"), exifTestTIFF(orientation)...), xmp} {
+		segments = append(segments, 0xff, jpegMarkerAPP1)
+		segments = binary.BigEndian.AppendUint16(segments, uint16(2+len(payload)))
+		segments = append(segments, payload...)
+	}
+	return append(append([]byte{0xff, 0xd8}, segments...), data[2:]...)
+}
+
+func exifTestPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	check(png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 8, 8))))
+	data := buf.Bytes()
+	tiff := exifTestTIFF(1)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(tiff)))
+	chunk = append(append(chunk, "eXIf"...), tiff...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	// right after the IHDR chunk
+	ihdrEnd := len(pngSignature) + 25
+	return bytes.Join([][]byte{data[:ihdrEnd], chunk, data[ihdrEnd:]}, nil)
+}
+
+func TestReadImgExif(t *testing.T) {
+	ei := readImgExif(exifTestJPEG(t, 60, 40, 6))
+	if ei.orientation != 6 || !ei.swapsDimensions() || !ei.hasGPS {
+		t.Errorf("unexpected EXIF info: %+v", ei)
+	}
+	verifyStringsEqual(ei.camera, "Apple iPhone 13", t)
+	verifyStringsEqual(ei.captureTime.String(), "2024-05-17T14:30:00", t)
+	verifyStringsEqual(readImgExif(exifTestPNG(t)).camera, "Apple iPhone 13", t)
+	if ei := readImgExif([]byte("not an image")); ei != (exifInfo{}) {
+		t.Errorf("expected no EXIF info, got: %+v", ei)
+	}
+}
+
+func TestStripImgExif(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		decode func(r *bytes.Reader) error
+	}{
+		{"jpeg", exifTestJPEG(t, 60, 40, 1), func(r *bytes.Reader) error { _, err := jpeg.Decode(r); return err }},
+		{"png", exifTestPNG(t), func(r *bytes.Reader) error { _, err := png.Decode(r); return err }},
+	} {
+		// GPS only: the rest of the metadata is kept
+		stripped := stripImgExif(tc.data, GPSImgMetadataStrip)
+		if stripped == nil || bytes.Contains(stripped, gpsTestLatitude) || bytes.Contains(stripped, []byte("xmpmeta")) {
+			t.Errorf("%s: expected the GPS data (and XMP metadata) to be removed", tc.name)
+		}
+		if ei := readImgExif(stripped); ei.hasGPS || ei.camera != "Apple iPhone 13" {
+			t.Errorf("%s: unexpected EXIF info once the GPS data is removed: %+v", tc.name, ei)
+		}
+		if err := tc.decode(bytes.NewReader(stripped)); err != nil {
+			t.Errorf("%s: invalid image once the GPS data is removed: %v", tc.name, err)
+		}
+		if stripImgExif(stripped, GPSImgMetadataStrip) != nil {
+			t.Errorf("%s: expected nothing left to remove", tc.name)
+		}
+		// all
+		stripped = stripImgExif(tc.data, AllImgMetadataStrip)
+		if stripped == nil || readImgExif(stripped) != (exifInfo{}) || bytes.Contains(stripped, gpsTestLatitude) {
+			t.Errorf("%s: expected all the metadata to be removed", tc.name)
+		}
+		if err := tc.decode(bytes.NewReader(stripped)); err != nil {
+			t.Errorf("%s: invalid image once the metadata is removed: %v", tc.name, err)
+		}
+		if stripImgExif(stripped, AllImgMetadataStrip) != nil {
+			t.Errorf("%s: expected nothing left to remove", tc.name)
+		}
+	}
+}
+
+func TestMalformedJPEGSegment(t *testing.T) {
+	// segment length fields too short to cover the length field itself
+	for _, segmentLength := range []byte{0, 1} {
+		data := []byte{0xff, 0xd8, 0xff, jpegMarkerAPP1, 0x00, segmentLength, 'E', 'x', 'i', 'f', 0x00, 0x00}
+		if ei := readImgExif(data); ei != (exifInfo{}) {
+			t.Errorf("expected no EXIF info for the segment length %d, got: %+v", segmentLength, ei)
+		}
+		for _, policy := range []imgMetadataStripPolicy{GPSImgMetadataStrip, AllImgMetadataStrip} {
+			if stripped := stripImgExif(data, policy); stripped != nil {
+				t.Errorf("expected nothing to be removed for the segment length %d, got: %v", segmentLength, stripped)
+			}
+		}
+	}
+}
+
+func TestImgExifProcessing(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	mediaDirPath := filepath.Join(deployDirName, mediaDirName, "post", "p")
+	check(os.MkdirAll(mediaDirPath, 0755))
+	imgFilePath := filepath.Join(mediaDirPath, "a.jpg")
+	// a landscape image to be rotated into a portrait one
+	check(os.WriteFile(imgFilePath, exifTestJPEG(t, 60, 40, 6), 0644))
+
+	// the thumbnails (and the recorded dimensions) are oriented
+	config := defaultConfig()
+	config.thumbSizes = []int{30}
+	config.thumbThreshold = 0
+	processImgThumbnails(mediaDirPath, config)
+	m := buildMediaItem("a.jpg", "post/p", filepath.Join("post", "p"), config)
+	if m.Width() != 40 || m.Height() != 60 || m.ThumbWidth(1) != 20 || m.ThumbHeight(1) != 30 {
+		t.Errorf("unexpected image/thumbnail dimensions: %dx%d / %dx%d", m.Width(), m.Height(), m.ThumbWidth(1), m.ThumbHeight(1))
+	}
+	verifyStringsEqual(m.FmtCaptureDate(), "2024-05-17", t)
+	verifyStringsEqual(m.FmtCaptureTime(), "14:30", t)
+	verifyStringsEqual(m.Camera(), "Apple iPhone 13", t)
+
+	// nothing is removed by default
+	if processOriginalMediaFile(imgFilePath, config, true) {
+		t.Error("expected the metadata to be kept by default")
+	}
+	// the orientation is applied to the image once all of the metadata is removed
+	config.stripImgMetadata = AllImgMetadataStrip
+	if !processOriginalMediaFile(imgFilePath, config, true) {
+		t.Error("expected the metadata to be reported")
+	}
+	if !processOriginalMediaFile(imgFilePath, config, false) {
+		t.Error("expected the metadata to be removed")
+	}
+	data := readDataFromFile(imgFilePath)
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	check(err)
+	if readImgExif(data) != (exifInfo{}) || cfg.Width != 40 || cfg.Height != 60 {
+		t.Errorf("unexpected image once the metadata is removed: %dx%d", cfg.Width, cfg.Height)
+	}
+	if processOriginalMediaFile(imgFilePath, config, true) {
+		t.Error("expected nothing left to remove")
+	}
+}
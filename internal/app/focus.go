@@ -0,0 +1,162 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseDirectiveProps parses the given directive properties (e.g. `(focus=30x40)`) into a key -> value map
+func parseDirectiveProps(propStr string) map[string]string {
+	props := make(map[string]string)
+	propStr = strings.Trim(propStr, "()")
+	if propStr != "" {
+		for _, pStr := range strings.Split(propStr, ",") {
+			key, val, _ := strings.Cut(strings.TrimSpace(pStr), "=")
+			props[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+	}
+	return props
+}
+
+// parseRawMetaData parses the YAML metadata (frontmatter) of the given raw markdown content
+// (ahead of the markdown conversion the metadata is otherwise parsed along with),
+// returning nil if there's none (or it's malformed)
+func parseRawMetaData(content string) map[string]interface{} {
+	metaDataContent := metaDataPlaceholderRegexp.FindString(content)
+	if metaDataContent == "" {
+		return nil
+	}
+	metaDataContent = strings.Replace(strings.Trim(metaDataContent, "-"), "\t", "  ", -1)
+	var metaData map[string]interface{}
+	if err := yaml.Unmarshal([]byte(metaDataContent), &metaData); err != nil {
+		return nil
+	}
+	return metaData
+}
+
+// parseMediaFocusMetaData parses the `media-focus` metadata map of a post/page:
+// image file name (with or without extension) -> focal point (e.g. `cover.jpg: 30x40`),
+// returning the malformed entries as warnings
+func parseMediaFocusMetaData(metaData map[string]interface{}) (map[string]focalPoint, []string) {
+	raw := metaData[metaDataKeyMediaFocus]
+	if raw == nil {
+		return nil, nil
+	}
+	focusMap, ok := toStringKeyMap(raw)
+	if !ok {
+		return nil, []string{metaDataKeyMediaFocus + ": malformed metadata (expected a map of image file name to focal point)"}
+	}
+	var warnings []string
+	mediaFocus := make(map[string]focalPoint, len(focusMap))
+	for name, v := range focusMap {
+		s, _ := v.(string)
+		fp, ok := parseFocalPoint(s)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("%s: malformed focal point %v of image \"%s\" (expected: XxY, in percent, e.g. 30x40)", metaDataKeyMediaFocus, v, name))
+			continue
+		}
+		mediaFocus[strings.TrimSpace(name)] = fp
+	}
+	slices.Sort(warnings)
+	return mediaFocus, warnings
+}
+
+// contentMediaFocalPoints returns the focal points the media of the given raw markdown content are referenced with:
+// the ones of the `media-focus` metadata map, along with the `focus` props of the media directives
+// (an empty media file name stands for all the content entity media)
+func contentMediaFocalPoints(content string) map[string][]focalPoint {
+	focalPoints := make(map[string][]focalPoint)
+	add := func(name string, fp focalPoint) {
+		if !slices.Contains(focalPoints[name], fp) {
+			focalPoints[name] = append(focalPoints[name], fp)
+		}
+	}
+	mediaFocus, _ := parseMediaFocusMetaData(parseRawMetaData(content))
+	for name, fp := range mediaFocus {
+		add(name, fp)
+	}
+	// the opening tags of the wrap directives cover the media directives as well
+	for _, dm := range wrapPlaceholderOpeningRegexp.FindAllStringSubmatch(content, -1) {
+		fp, ok := parseFocalPoint(parseDirectiveProps(dm[2])[mediaFocusPropName])
+		if !ok {
+			continue
+		}
+		fileNames, _, _ := splitMediaArg(dm[3])
+		if len(fileNames) == 0 {
+			add("", fp)
+		}
+		for _, fileName := range fileNames {
+			add(fileName, fp)
+		}
+	}
+	return focalPoints
+}
+
+// mediaDirFocalPoints returns the focal points the images of the given media dir are referenced with
+// (image file name or base name -> focal points), read from the markdown content of the corresponding post/page,
+// or of all the posts/pages for the shared media dir (except for the references resolved to their own media)
+func mediaDirFocalPoints(mediaDirPath string) map[string][]focalPoint {
+	mediaBaseDirPath := filepath.Join(deployDirName, mediaDirName)
+	rel, err := filepath.Rel(mediaBaseDirPath, mediaDirPath)
+	if err != nil {
+		return nil
+	}
+	markdownDirNames := map[string]string{deployPageDirName: markdownPagesDirName, deployPostDirName: markdownPostsDirName}
+	if ceType, ceId, ok := strings.Cut(filepath.ToSlash(rel), "/"); ok {
+		markdownDirName, ok := markdownDirNames[ceType]
+		if !ok {
+			return nil
+		}
+		content, err := os.ReadFile(filepath.Join(markdownDirName, ceId+markdownFileExtension))
+		if err != nil {
+			return nil
+		}
+		return contentMediaFocalPoints(string(content))
+	}
+	if rel != sharedMediaDirName {
+		return nil
+	}
+	focalPoints := make(map[string][]focalPoint)
+	for ceType, markdownDirName := range markdownDirNames {
+		if !dirExists(markdownDirName) {
+			continue
+		}
+		mdFiles, err := listFilesByExt(markdownDirName, markdownFileExtension)
+		if err != nil {
+			continue
+		}
+		for _, mdFile := range mdFiles {
+			content, err := os.ReadFile(filepath.Join(markdownDirName, mdFile))
+			if err != nil {
+				continue
+			}
+			ceMediaDirPath := filepath.Join(mediaBaseDirPath, ceType, strings.TrimSuffix(mdFile, markdownFileExtension))
+			for name, fps := range contentMediaFocalPoints(string(content)) {
+				if name == "" || len(expandMediaFileName(name, ceMediaDirPath)) > 0 {
+					continue
+				}
+				for _, fp := range fps {
+					if !slices.Contains(focalPoints[name], fp) {
+						focalPoints[name] = append(focalPoints[name], fp)
+					}
+				}
+			}
+		}
+	}
+	return focalPoints
+}
+
+// cropThumbFileName returns the file name of the crop thumbnail of the given size and focal point
+// (e.g. `cover.jpg_480x480_thumb.jpg`, or `cover.jpg_480x480_f30x40_thumb.jpg` if not centered)
+func cropThumbFileName(imgFile string, cs cropThumbSize, fp focalPoint) string {
+	name := imgFile + "_" + cs.String()
+	if !fp.isCenter() {
+		name += "_f" + fp.String()
+	}
+	return name + thumbImgFileSuffix + strings.ToLower(filepath.Ext(imgFile))
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseFocalPoint(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected focalPoint
+		ok       bool
+	}{
+		{"30x40", focalPoint{x: 30, y: 40}, true},
+		{" 0 x 100 ", focalPoint{x: 0, y: 100}, true},
+		{"101x50", focalPoint{}, false},
+		{"30", focalPoint{}, false},
+		{"", focalPoint{}, false},
+	} {
+		if fp, ok := parseFocalPoint(tc.value); fp != tc.expected || ok != tc.ok {
+			t.Errorf("%q: expected %v (%t), got %v (%t)", tc.value, tc.expected, tc.ok, fp, ok)
+		}
+	}
+}
+
+func TestCropThumbRect(t *testing.T) {
+	square := cropThumbSize{width: 480, height: 480}
+	for _, tc := range []struct {
+		iw, ih   int
+		cs       cropThumbSize
+		fp       focalPoint
+		expected image.Rectangle
+	}{
+		{400, 200, square, focalPoint{x: 50, y: 50}, image.Rect(100, 0, 300, 200)},
+		{400, 200, square, focalPoint{x: 10, y: 50}, image.Rect(0, 0, 200, 200)},
+		{400, 200, square, focalPoint{x: 80, y: 0}, image.Rect(200, 0, 400, 200)},
+		{200, 400, square, focalPoint{x: 50, y: 30}, image.Rect(0, 20, 200, 220)},
+		{600, 300, cropThumbSize{width: 300, height: 200}, focalPoint{x: 50, y: 50}, image.Rect(75, 0, 525, 300)},
+	} {
+		if rect := cropThumbRect(tc.iw, tc.ih, tc.cs, tc.fp); rect != tc.expected {
+			t.Errorf("%dx%d / %s / %s: expected %v, got %v", tc.iw, tc.ih, tc.cs, tc.fp, tc.expected, rect)
+		}
+	}
+}
+
+func TestContentMediaFocalPoints(t *testing.T) {
+	content := "---\ntitle: Test\nmedia-focus:\n  cover.jpg: 30x40\n  back: bad\n---\n" +
+		"{media(focus=10x20):a.jpg,b}\n{with-media(focus=70x80)} text {/}\n{media(focus=30x40):cover.jpg}\n{media:c.jpg}\n"
+	focalPoints := contentMediaFocalPoints(content)
+	expected := map[string][]focalPoint{
+		"cover.jpg": {{x: 30, y: 40}},
+		"a.jpg":     {{x: 10, y: 20}},
+		"b":         {{x: 10, y: 20}},
+		"":          {{x: 70, y: 80}},
+	}
+	if len(focalPoints) != len(expected) {
+		t.Fatalf("unexpected focal points: %v", focalPoints)
+	}
+	for name, fps := range expected {
+		if !slices.Equal(focalPoints[name], fps) {
+			t.Errorf("%q: expected %v, got %v", name, fps, focalPoints[name])
+		}
+	}
+	mediaFocus, warnings := parseMediaFocusMetaData(parseRawMetaData(content))
+	if len(mediaFocus) != 1 || len(warnings) != 1 {
+		t.Errorf("unexpected media focus metadata: %v / %v", mediaFocus, warnings)
+	}
+}
+
+func TestImgCropThumbnails(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	mediaDirPath := filepath.Join(deployDirName, mediaDirName, "post", "p")
+	check(os.MkdirAll(mediaDirPath, 0755))
+	check(os.MkdirAll(markdownPostsDirName, 0755))
+	// the left half is red, the right half is blue
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 200; y++ {
+			if x < 200 {
+				img.Set(x, y, color.NRGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.NRGBA{B: 255, A: 255})
+			}
+		}
+	}
+	f, err := os.Create(filepath.Join(mediaDirPath, "a.png"))
+	check(err)
+	check(png.Encode(f, img))
+	check(f.Close())
+	mdFilePath := filepath.Join(markdownPostsDirName, "p"+markdownFileExtension)
+	check(os.WriteFile(mdFilePath, []byte("{media(focus=10x50):a.png}"), 0644))
+
+	config := defaultConfig()
+	config.thumbSizes = []int{300}
+	config.cropThumbSizes = []cropThumbSize{{width: 120, height: 120}}
+	config.thumbThreshold = 0
+	processImgThumbnails(mediaDirPath, config)
+
+	for _, tc := range []struct {
+		thumbFile string
+		center    color.NRGBA
+	}{
+		{"a.png_120x120_thumb.png", color.NRGBA{}},
+		{"a.png_120x120_f10x50_thumb.png", color.NRGBA{R: 255, A: 255}},
+	} {
+		f, err := os.Open(filepath.Join(mediaDirPath, tc.thumbFile))
+		if err != nil {
+			t.Fatalf("expected a crop thumbnail: %s", tc.thumbFile)
+		}
+		thumbImg, err := png.Decode(f)
+		check(err)
+		check(f.Close())
+		if thumbImg.Bounds().Dx() != 120 || thumbImg.Bounds().Dy() != 120 {
+			t.Errorf("%s: unexpected crop thumbnail dimensions: %v", tc.thumbFile, thumbImg.Bounds())
+		}
+		// the focused crop only covers the (red) left half of the image
+		if c := color.NRGBAModel.Convert(thumbImg.At(60, 60)).(color.NRGBA); tc.center.A > 0 && c != tc.center {
+			t.Errorf("%s: unexpected crop thumbnail center color: %v", tc.thumbFile, c)
+		}
+	}
+
+	m := parseMediaFileNames([]string{"a.png"}, Post, "p", config, true, nil, map[string]focalPoint{"a": {x: 10, y: 50}})[0]
+	verifyStringsEqual(m.CropThumbUri(1), "/media/post/p/a.png_120x120_f10x50_thumb.png", t)
+	verifyStringsEqual(m.Focus(), "10% 50%", t)
+	if m.CropThumbWidth(1) != 120 || m.CropThumbHeight(1) != 120 {
+		t.Errorf("unexpected crop thumbnail dimensions: %dx%d", m.CropThumbWidth(1), m.CropThumbHeight(1))
+	}
+	m = *buildMediaItem("a.png", "post/p", filepath.Join("post", "p"), config)
+	verifyStringsEqual(m.CropThumbUri(1), "/media/post/p/a.png_120x120_thumb.png", t)
+	verifyStringsEqual(m.Focus(), "50% 50%", t)
+	// no crop thumbnails: the first regular thumbnail is used instead
+	if m := buildMediaItem("a.png", "post/p", filepath.Join("post", "p"), defaultConfig()); m.HasCropThumbs() || m.CropThumbUri(1) != m.ThumbUri(1) {
+		t.Errorf("unexpected crop thumbnail fallback: %s", m.CropThumbUri(1))
+	}
+
+	// the crop thumbnails of the focal points no longer referenced (and of the crop sizes no longer configured) are deleted
+	check(os.WriteFile(mdFilePath, []byte("{media(focus=90x50):a.png}"), 0644))
+	config.cropThumbSizes = []cropThumbSize{{width: 150, height: 120}}
+	processImgThumbnails(mediaDirPath, config)
+	for thumbFile, exists := range map[string]bool{
+		"a.png_120x120_thumb.png":        false,
+		"a.png_120x120_f10x50_thumb.png": false,
+		"a.png_150x120_thumb.png":        true,
+		"a.png_150x120_f90x50_thumb.png": true,
+	} {
+		if fileExists(filepath.Join(mediaDirPath, thumbFile)) != exists {
+			t.Errorf("expected the crop thumbnail to exist (%t): %s", exists, thumbFile)
+		}
+	}
+}
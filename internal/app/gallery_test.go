@@ -0,0 +1,109 @@
+package app
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGalleryDirective(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultThemeTemplatesDir := filepath.Join(origDir, "..", "..", "themes", defaultThemeName, "templates")
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	mediaDirPath := filepath.Join(deployDirName, mediaDirName, "post", "p")
+	check(os.MkdirAll(mediaDirPath, 0755))
+	for _, fileName := range []string{"1.png", "2.png", "3.png", "4.png"} {
+		f, err := os.Create(filepath.Join(mediaDirPath, fileName))
+		check(err)
+		check(png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 40, 30))))
+		check(f.Close())
+	}
+
+	resLoader := resourceLoader{
+		config: defaultConfig(),
+		loadTemplate: func(templateFileName string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(defaultThemeTemplatesDir, templateFileName))
+		},
+		loadInclude: func(includeFileName string, level templateIncludeLevel) ([]byte, error) {
+			return nil, nil
+		},
+	}
+	postContent := "{media:1.png}\n\n{gallery(cc=2):2,3 | 2: Second \"image\"}\n\n{gallery}\n\n{gallery:2}"
+
+	config := defaultConfig()
+	p := parsePost("p", postContent, config, resLoader)
+	if len(p.Warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", p.Warnings)
+	}
+	// the explicitly listed images, followed by the ones not listed by any directive, deduplicated
+	var galleryUris []string
+	for _, m := range p.GalleryMedia {
+		galleryUris = append(galleryUris, m.Uri)
+	}
+	verifyStringsEqual(strings.Join(galleryUris, ","), "/media/post/p/2.png,/media/post/p/3.png,/media/post/p/4.png", t)
+	if strings.Count(p.Body, `class="gallery"`) != 3 {
+		t.Errorf("expected three galleries: %s", p.Body)
+	}
+	for _, ec := range []string{
+		`grid-template-columns: repeat(2, 1fr);`,
+		`<a class="gallery-item" href="/media/post/p/2.png"`,
+		`data-caption="Second &#34;image&#34;"`,
+		`src="/resources/gallery.js"`,
+	} {
+		if !strings.Contains(p.Body, ec) {
+			missingExpectedContentError(t, "post body", ec)
+		}
+	}
+	if strings.Contains(p.Body, "gallery-album-link") {
+		t.Errorf("unexpected gallery content: %s", p.Body)
+	}
+
+	// the galleries link to the album page, if generated (for posts only)
+	config.generateAlbums = true
+	p = parsePost("p", postContent, config, resLoader)
+	if strings.Count(p.Body, `href="/`+deployAlbumsDirName+`/p`+contentFileExtension+`"`) != 3 {
+		t.Errorf("expected the galleries to link to the album page: %s", p.Body)
+	}
+	pg := parsePage("p", "{gallery}", config, resLoader)
+	if strings.Contains(pg.Body, "gallery-album-link") {
+		t.Errorf("unexpected page album link: %s", pg.Body)
+	}
+}
+
+func TestWriteGalleryJS(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	galleryJSFilePath := filepath.Join(deployDirName, resourcesDirName, galleryJSFileName)
+	galleryMedia := []media{{Type: Image, Uri: "/media/page/p/1.png"}}
+	writeGalleryJS([]page{{Id: "p", GalleryMedia: galleryMedia}}, []post{{Id: "p"}})
+	if !fileExists(galleryJSFilePath) {
+		t.Error("expected the gallery script to be written for a page gallery")
+	}
+	writeGalleryJS([]page{{Id: "p"}}, []post{{Id: "p"}})
+	if fileExists(galleryJSFilePath) {
+		t.Error("expected the gallery script to be deleted once no longer used")
+	}
+	writeGalleryJS(nil, []post{{Id: "p", GalleryMedia: galleryMedia}})
+	if !fileExists(galleryJSFilePath) {
+		t.Error("expected the gallery script to be written for a post gallery")
+	}
+}
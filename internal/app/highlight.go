@@ -0,0 +1,67 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+)
+
+// contentMarkdownCache holds the content markdown converters per code highlighting mode & style
+var contentMarkdownCache = newSyncCache[string, goldmark.Markdown]()
+
+// isCodeHighlightStyle checks whether the given name is one of the available code highlight styles
+func isCodeHighlightStyle(name string) bool {
+	_, ok := styles.Registry[name]
+	return ok
+}
+
+// contentMarkdown returns the markdown converter used to render the page/post content:
+// the common one, extended with the syntax highlighting of fenced code blocks (as configured);
+// only the fenced code blocks with a (known) language specified are highlighted
+func contentMarkdown(config appConfig) goldmark.Markdown {
+	if config.codeHighlighting == NoCodeHighlighting || config.codeHighlighting == "" {
+		return markdown
+	}
+	cacheKey := config.codeHighlighting.String() + ":" + config.codeHighlightStyle
+	md, ok := contentMarkdownCache.get(cacheKey)
+	if !ok {
+		md = newMarkdown(
+			highlighting.NewHighlighting(
+				highlighting.WithStyle(config.codeHighlightStyle),
+				highlighting.WithFormatOptions(
+					chromahtml.WithClasses(config.codeHighlighting == ClassCodeHighlighting),
+				),
+			),
+		)
+		contentMarkdownCache.set(cacheKey, md)
+	}
+	return md
+}
+
+// renderCodeHighlightCSS renders the stylesheet for the class based code highlighting using the given style
+func renderCodeHighlightCSS(styleName string) []byte {
+	var buf bytes.Buffer
+	err := chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&buf, styles.Get(styleName))
+	check(err)
+	return buf.Bytes()
+}
+
+// writeCodeHighlightCSS writes the code highlight stylesheet into the deploy resources dir
+// (if class based code highlighting is enabled) using the configured style,
+// unless the active theme ships its own one (copied along with the rest of the theme resources)
+func writeCodeHighlightCSS(config appConfig) {
+	if config.codeHighlighting != ClassCodeHighlighting {
+		return
+	}
+	themeCSSFilePath := fmt.Sprintf("%s%c%s%c%s", config.theme, os.PathSeparator, resourcesDirName, os.PathSeparator, codeHighlightCSSFileName)
+	if fileExists(themeCSSFilePath) {
+		return
+	}
+	cssFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, resourcesDirName, os.PathSeparator, codeHighlightCSSFileName)
+	writeDataToFileIfChanged(cssFilePath, renderCodeHighlightCSS(config.codeHighlightStyle))
+}
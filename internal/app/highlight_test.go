@@ -0,0 +1,82 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+const highlightTestContent = "---\ntitle: Code\n---\n\n```go\nfunc main() {\n\n\n\tprintln(\"<hi>\")\n}\n```\n\n```\nplain <code>\n```\n"
+
+func TestCodeHighlightingClass(t *testing.T) {
+	config := defaultConfig()
+	config.codeHighlighting = ClassCodeHighlighting
+	p := parsePost("code", highlightTestContent, config, testResLoader())
+	verifyStringContains(p.Body, `<pre class="chroma"><code>`, t)
+	verifyStringContains(p.Body, `<span class="kd">func</span>`, t)
+	verifyStringContains(p.Body, `&lt;hi&gt;`, t)
+	// fenced code blocks without a language are left as-is
+	verifyStringContains(p.Body, "<pre><code>plain &lt;code&gt;\n</code></pre>", t)
+	if strings.Contains(p.Body, "style=") {
+		t.Error("class based highlighting should not render inline styles")
+	}
+	// blank lines inside the highlighted code must survive the blank line collapsing
+	code := preRegexp.FindString(p.Body)
+	if strings.Count(code, `<span class="line">`) != 5 {
+		t.Errorf("expected 5 highlighted code lines, got: %s", code)
+	}
+	collapsed := string(collapseBlankLines([]byte(p.Body + "\n\n\n<p>x</p>")))
+	verifyStringContains(collapsed, code, t)
+	verifyStringContains(collapsed, "</pre>\n<p>x</p>", t)
+}
+
+func TestCodeHighlightingInline(t *testing.T) {
+	config := defaultConfig()
+	config.codeHighlighting = InlineCodeHighlighting
+	config.codeHighlightStyle = "github"
+	p := parsePost("code", highlightTestContent, config, testResLoader())
+	verifyStringContains(p.Body, `<pre style="`, t)
+	verifyStringContains(p.Body, `<span style="`, t)
+	if strings.Contains(p.Body, `class="chroma"`) {
+		t.Error("inline style based highlighting should not render CSS classes")
+	}
+}
+
+func TestCodeHighlightingDisabled(t *testing.T) {
+	// disabled by default, so that the existing sites keep their code block markup
+	config := defaultConfig()
+	p := parsePost("code", highlightTestContent, config, testResLoader())
+	verifyStringContains(p.Body, "<pre><code class=\"language-go\">func main() {\n\n\n\tprintln(&quot;&lt;hi&gt;&quot;)\n}\n</code></pre>", t)
+}
+
+func TestCodeHighlightCSS(t *testing.T) {
+	if !isCodeHighlightStyle(defaultCodeHighlightStyle) || isCodeHighlightStyle("no-such-style") {
+		t.Error("unexpected code highlight style validation result")
+	}
+	css := string(renderCodeHighlightCSS("github"))
+	verifyStringContains(css, ".chroma {", t)
+	verifyStringContains(css, ".chroma .kd {", t)
+}
+
+func TestWriteCodeHighlightCSS(t *testing.T) {
+	setUpDeployTest(t)
+	config := defaultConfig()
+	config.theme = "theme"
+	config.codeHighlighting = ClassCodeHighlighting
+	cssFilePath := "deploy/resources/" + codeHighlightCSSFileName
+
+	// the stylesheet follows the configured style across generate runs
+	config.codeHighlightStyle = "github"
+	writeCodeHighlightCSS(config)
+	verifyStringsEqual(string(readDataFromFile(cssFilePath)), string(renderCodeHighlightCSS("github")), t)
+	config.codeHighlightStyle = "monokai"
+	writeCodeHighlightCSS(config)
+	verifyStringsEqual(string(readDataFromFile(cssFilePath)), string(renderCodeHighlightCSS("monokai")), t)
+
+	// the stylesheet shipped by the theme is kept
+	writeDeployTestFiles(t, map[string]string{
+		"theme/resources/" + codeHighlightCSSFileName: "theme css",
+		cssFilePath: "theme css",
+	})
+	writeCodeHighlightCSS(config)
+	verifyStringsEqual(string(readDataFromFile(cssFilePath)), "theme css", t)
+}
@@ -0,0 +1,270 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isValidLanguage checks whether the given language code is valid,
+// i.e. it can be used as the (top-level) deploy dir of the language, not clashing with the generated content dirs
+func isValidLanguage(lang string) bool {
+	return languageCodeRegexp.MatchString(lang) && !slices.Contains(generatedContentDirNames, lang)
+}
+
+// siteLanguages returns the languages the site content is processed for:
+// the configured ones (the first one is the default language),
+// or a single unnamed one if the multi-language support is not enabled
+func siteLanguages(config appConfig) []string {
+	if len(config.languages) == 0 {
+		return []string{""}
+	}
+	return config.languages
+}
+
+// contentLanguage returns the language of a post/page with the given `lang` frontmatter value
+func contentLanguage(lang string, config appConfig) string {
+	if lang == "" && len(config.languages) > 0 {
+		return config.languages[0]
+	}
+	return lang
+}
+
+// langURIPrefix returns the URI prefix of the given language (e.g. `/de`):
+// the default language content is generated at the site root, so its prefix is empty
+func langURIPrefix(lang string, config appConfig) string {
+	if lang == "" || len(config.languages) == 0 || lang == config.languages[0] {
+		return ""
+	}
+	return "/" + lang
+}
+
+// localizeOutputFilePath moves the given output file path (inside the deploy dir) into the deploy dir
+// of the language being processed, e.g. `deploy/tags/index.html` -> `deploy/de/tags/index.html`
+func localizeOutputFilePath(outputFilePath string, config appConfig) string {
+	prefix := langURIPrefix(config.lang, config)
+	if prefix == "" {
+		return outputFilePath
+	}
+	relPath, err := filepath.Rel(deployDirName, outputFilePath)
+	check(err)
+	return filepath.Join(deployDirName, prefix[1:], relPath)
+}
+
+// staleLanguageDirs returns the deploy dirs of the languages no longer configured:
+// the top-level deploy dirs named after a language (other than the API dir)
+// holding generated post/page content files; the default language content is generated
+// at the deploy dir root, so a dir named after the default language is stale as well
+func staleLanguageDirs(config appConfig) []string {
+	if !dirExists(deployDirName) {
+		return nil
+	}
+	var langs []string
+	if len(config.languages) > 0 {
+		langs = config.languages[1:]
+	}
+	deployDirEntries, err := os.ReadDir(deployDirName)
+	check(err)
+	var staleDirs []string
+	for _, entry := range deployDirEntries {
+		lang := entry.Name()
+		if !entry.IsDir() || !isValidLanguage(lang) || slices.Contains(langs, lang) ||
+			lang == strings.Split(config.apiDir, "/")[0] {
+			continue
+		}
+		langDirPath := filepath.Join(deployDirName, lang)
+		for _, ceDirName := range []string{deployPostDirName, deployPageDirName} {
+			ceDirPath := filepath.Join(langDirPath, ceDirName)
+			if !dirExists(ceDirPath) {
+				continue
+			}
+			if contentFiles, err := listFilesByExt(ceDirPath, contentFileExtension); err == nil && len(contentFiles) > 0 {
+				staleDirs = append(staleDirs, langDirPath)
+				break
+			}
+		}
+	}
+	return staleDirs
+}
+
+// parseLanguageMetaData validates the `lang` frontmatter value of a post/page:
+// the default language is normalized to an empty value, while an unknown one is reported as a warning
+// (and the content is considered to be in the default language)
+func parseLanguageMetaData(metaData map[string]interface{}, config appConfig, warnings *[]string) string {
+	lang, ok := metaData[metaDataKeyLang].(string)
+	if !ok {
+		return ""
+	}
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if len(config.languages) == 0 || lang == config.languages[0] {
+		return ""
+	}
+	if !slices.Contains(config.languages, lang) {
+		*warnings = append(*warnings, "lang: unknown language: "+lang+" (configured languages: "+strings.Join(config.languages, ", ")+")")
+		return ""
+	}
+	return lang
+}
+
+// loadTranslations loads the template string translations of the given language
+// from the `i18n/<lang>.yml` include files (flat `<string>: <translation>` maps):
+// the global level translations override the theme level ones
+func loadTranslations(lang string, resLoader resourceLoader) map[string]string {
+	translations := map[string]string{}
+	if lang == "" {
+		return translations
+	}
+	for _, level := range []templateIncludeLevel{Theme, Global} {
+		data, err := resLoader.loadInclude(filepath.Join(i18nDirName, lang+i18nFileExtension), level)
+		check(err)
+		if data != nil {
+			levelTranslations := map[string]string{}
+			if err := yaml.Unmarshal(data, &levelTranslations); err != nil {
+				exitWithError("invalid " + lang + " translations file: " + err.Error())
+			}
+			for k, v := range levelTranslations {
+				translations[k] = v
+			}
+		}
+	}
+	return translations
+}
+
+// translate is the template function translating the given string into the language being processed
+// (the template config map carries the translations), falling back to the string itself
+func translate(config map[string]any, str string) string {
+	if translations, ok := config["Translations"].(map[string]string); ok {
+		if translation, ok := translations[str]; ok {
+			return translation
+		}
+	}
+	return str
+}
+
+// translationKey returns the id shared by all the language versions of a post/page:
+// the id of the original one (the one the others are `translation-of`)
+func translationKey(id string, translationOf string) string {
+	if translationOf != "" {
+		return translationOf
+	}
+	return id
+}
+
+// resolveHomePages resolves the home page of each language (language -> page id):
+// the configured home page, or its translation
+func resolveHomePages(pages []page, config appConfig) map[string]string {
+	homePages := map[string]string{}
+	if config.homePage == "" {
+		return homePages
+	}
+	homePageKey := config.homePage
+	for _, p := range pages {
+		if p.Id == config.homePage {
+			homePageKey = translationKey(p.Id, p.TranslationOf)
+			homePages[contentLanguage(p.Lang, config)] = p.Id
+		}
+	}
+	for _, p := range pages {
+		if translationKey(p.Id, p.TranslationOf) == homePageKey {
+			if _, ok := homePages[contentLanguage(p.Lang, config)]; !ok {
+				homePages[contentLanguage(p.Lang, config)] = p.Id
+			}
+		}
+	}
+	return homePages
+}
+
+// linkTranslations links the language versions of the given pages and posts together
+// (listed in the configured language order)
+func linkTranslations(pages []page, posts []post, homePages map[string]string, config appConfig) {
+	if len(config.languages) == 0 {
+		return
+	}
+	type version struct {
+		lang string
+		uri  string
+		set  func([]translationLink)
+	}
+	groups := map[string][]version{}
+	var groupKeys []string
+	add := func(key string, v version) {
+		if _, ok := groups[key]; !ok {
+			groupKeys = append(groupKeys, key)
+		}
+		groups[key] = append(groups[key], v)
+	}
+	for i := range pages {
+		p := &pages[i]
+		lang := contentLanguage(p.Lang, config)
+		uri := langURIPrefix(lang, config) + "/" + deployPageDirName + "/" + p.Id + contentFileExtension
+		if homePages[lang] == p.Id {
+			uri = langURIPrefix(lang, config) + "/"
+		}
+		add(deployPageDirName+"/"+translationKey(p.Id, p.TranslationOf), version{lang, uri, func(links []translationLink) { p.Translations = links }})
+	}
+	for i := range posts {
+		p := &posts[i]
+		lang := contentLanguage(p.Lang, config)
+		uri := langURIPrefix(lang, config) + "/" + deployPostDirName + "/" + p.Id + contentFileExtension
+		add(deployPostDirName+"/"+translationKey(p.Id, p.TranslationOf), version{lang, uri, func(links []translationLink) { p.Translations = links }})
+	}
+	for _, key := range groupKeys {
+		versions := groups[key]
+		if len(versions) < 2 {
+			continue
+		}
+		slices.SortStableFunc(versions, func(a, b version) int {
+			return slices.Index(config.languages, a.lang) - slices.Index(config.languages, b.lang)
+		})
+		var links []translationLink
+		for _, v := range versions {
+			// a single version per language (the first one in the content order)
+			if !slices.ContainsFunc(links, func(l translationLink) bool { return l.Lang == v.lang }) {
+				links = append(links, translationLink{Lang: v.lang, URI: v.uri})
+			}
+		}
+		for _, v := range versions {
+			v.set(links)
+		}
+	}
+}
+
+// localizeContentLinks localizes the root-relative content links of the given rendered content:
+// the links to posts/pages point to the language versions they belong to,
+// while the tag, album and search links point to the ones of the language being processed
+func localizeContentLinks(body string, contentLangs map[string]string, config appConfig) string {
+	if len(config.languages) == 0 {
+		return body
+	}
+	body = contentLinkRegexp.ReplaceAllStringFunc(body, func(match string) string {
+		m := contentLinkRegexp.FindStringSubmatch(match)
+		lang, ok := contentLangs[m[2]+"/"+m[3]]
+		if !ok {
+			return match
+		}
+		return m[1] + langURIPrefix(lang, config) + "/" + m[2] + "/" + m[3] + contentFileExtension + m[4] + `"`
+	})
+	if prefix := langURIPrefix(config.lang, config); prefix != "" {
+		body = langScopedLinkRegexp.ReplaceAllString(body, "${1}"+prefix+"/${2}")
+	}
+	return body
+}
+
+// buildHreflangAlternates builds the `hreflang` alternates out of the given translation links,
+// adding the `x-default` one (pointing to the default language version, if any)
+func buildHreflangAlternates(translations []translationLink, config appConfig) []translationLink {
+	if len(translations) < 2 {
+		return nil
+	}
+	var alternates []translationLink
+	for _, t := range translations {
+		alternates = append(alternates, translationLink{Lang: t.Lang, URI: config.siteBaseURL + t.URI})
+	}
+	if translations[0].Lang == config.languages[0] {
+		alternates = append(alternates, translationLink{Lang: hreflangXDefault, URI: config.siteBaseURL + translations[0].URI})
+	}
+	return alternates
+}
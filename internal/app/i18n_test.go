@@ -0,0 +1,137 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func testLanguageConfig() appConfig {
+	config := defaultConfig()
+	config.languages = []string{"en", "de"}
+	return config
+}
+
+func TestIsValidLanguage(t *testing.T) {
+	for _, lang := range []string{"en", "de", "pt-br", "zh-hant"} {
+		if !isValidLanguage(lang) {
+			t.Errorf("expected language to be valid: %s", lang)
+		}
+	}
+	for _, lang := range []string{"", "e", "EN", "en_US", "english", "../de", deployPostDirName, deployTagsDirName} {
+		if isValidLanguage(lang) {
+			t.Errorf("expected language to be invalid: %s", lang)
+		}
+	}
+}
+
+func TestLangURIPrefix(t *testing.T) {
+	config := testLanguageConfig()
+	verifyStringsEqual(langURIPrefix("en", config), "", t)
+	verifyStringsEqual(langURIPrefix("de", config), "/de", t)
+	verifyStringsEqual(langURIPrefix("", config), "", t)
+	verifyStringsEqual(langURIPrefix("de", defaultConfig()), "", t)
+
+	outputFilePath := filepath.Join(deployDirName, deployTagsDirName, indexPageFileName)
+	config.lang = "en"
+	verifyStringsEqual(localizeOutputFilePath(outputFilePath, config), outputFilePath, t)
+	config.lang = "de"
+	verifyStringsEqual(localizeOutputFilePath(outputFilePath, config), filepath.Join(deployDirName, "de", deployTagsDirName, indexPageFileName), t)
+}
+
+func TestParseLanguageMetaData(t *testing.T) {
+	config := testLanguageConfig()
+	resLoader := testResLoader()
+
+	p := parsePost("hallo", "---\nlang: DE\ntranslation-of: hello\n---\nHallo", config, resLoader)
+	verifyStringsEqual(p.Lang, "de", t)
+	verifyStringsEqual(p.TranslationOf, "hello", t)
+
+	p = parsePost("hello", "---\nlang: en\n---\nHello", config, resLoader)
+	verifyStringsEqual(p.Lang, "", t)
+
+	p = parsePost("bonjour", "---\nlang: fr\n---\nBonjour", config, resLoader)
+	verifyStringsEqual(p.Lang, "", t)
+	if len(p.Warnings) != 1 {
+		t.Fatalf("expected a single unknown language warning, got: %v", p.Warnings)
+	}
+	verifyStringContains(p.Warnings[0], "lang: unknown language: fr", t)
+
+	p = parsePost("hello", "---\nlang: de\n---\nHello", defaultConfig(), resLoader)
+	if p.Lang != "" || len(p.Warnings) != 0 {
+		t.Error("lang should be ignored if no languages are configured")
+	}
+}
+
+func TestLinkTranslations(t *testing.T) {
+	config := testLanguageConfig()
+	config.homePage = "home"
+	config.siteBaseURL = "https://example.com"
+
+	pages := []page{
+		{Id: "home"},
+		{Id: "startseite", Lang: "de", TranslationOf: "home"},
+		{Id: "about"},
+	}
+	posts := []post{
+		{Id: "hallo", Lang: "de", TranslationOf: "hello"},
+		{Id: "hello"},
+		{Id: "solo", Lang: "de"},
+	}
+	homePages := resolveHomePages(pages, config)
+	verifyStringsEqual(homePages["en"], "home", t)
+	verifyStringsEqual(homePages["de"], "startseite", t)
+
+	linkTranslations(pages, posts, homePages, config)
+	expectedPageLinks := []translationLink{{Lang: "en", URI: "/"}, {Lang: "de", URI: "/de/"}}
+	for _, p := range pages[:2] {
+		if len(p.Translations) != 2 || p.Translations[0] != expectedPageLinks[0] || p.Translations[1] != expectedPageLinks[1] {
+			t.Errorf("unexpected page translations of %s: %v", p.Id, p.Translations)
+		}
+	}
+	if pages[2].Translations != nil || posts[2].Translations != nil {
+		t.Error("untranslated content should not link to any translations")
+	}
+	expectedPostLinks := []translationLink{{Lang: "en", URI: "/post/hello.html"}, {Lang: "de", URI: "/de/post/hallo.html"}}
+	for _, p := range posts[:2] {
+		if len(p.Translations) != 2 || p.Translations[0] != expectedPostLinks[0] || p.Translations[1] != expectedPostLinks[1] {
+			t.Errorf("unexpected post translations of %s: %v", p.Id, p.Translations)
+		}
+	}
+
+	alternates := buildHreflangAlternates(posts[0].Translations, config)
+	if len(alternates) != 3 {
+		t.Fatalf("expected 3 hreflang alternates, got: %v", alternates)
+	}
+	verifyStringsEqual(alternates[1].URI, "https://example.com/de/post/hallo.html", t)
+	verifyStringsEqual(alternates[2].Lang, hreflangXDefault, t)
+	verifyStringsEqual(alternates[2].URI, "https://example.com/post/hello.html", t)
+}
+
+func TestLocalizeContentLinks(t *testing.T) {
+	config := testLanguageConfig()
+	contentLangs := map[string]string{"post/hello": "en", "post/hallo": "de", "page/about": "en"}
+	body := `<a href="/post/hello.html">a</a> <a href="/post/hallo.html">b</a> <a href="/page/about.html">c</a> ` +
+		`<a href="/tags/go/">#go</a> <a href="/search.html?q=go">s</a> <a href="/post/missing.html">m</a>`
+
+	config.lang = "en"
+	verifyStringsEqual(localizeContentLinks(body, contentLangs, config),
+		`<a href="/post/hello.html">a</a> <a href="/de/post/hallo.html">b</a> <a href="/page/about.html">c</a> `+
+			`<a href="/tags/go/">#go</a> <a href="/search.html?q=go">s</a> <a href="/post/missing.html">m</a>`, t)
+
+	config.lang = "de"
+	verifyStringsEqual(localizeContentLinks(body, contentLangs, config),
+		`<a href="/post/hello.html">a</a> <a href="/de/post/hallo.html">b</a> <a href="/page/about.html">c</a> `+
+			`<a href="/de/tags/go/">#go</a> <a href="/de/search.html?q=go">s</a> <a href="/post/missing.html">m</a>`, t)
+
+	verifyStringsEqual(localizeContentLinks(body, contentLangs, defaultConfig()), body, t)
+
+	verifyStringsEqual(localizeContentLinks(`<a href="/post/hallo.html#teil-2">b</a>`, contentLangs, config),
+		`<a href="/de/post/hallo.html#teil-2">b</a>`, t)
+}
+
+func TestTranslate(t *testing.T) {
+	config := map[string]any{"Translations": map[string]string{"Load more": "Mehr laden"}}
+	verifyStringsEqual(translate(config, "Load more"), "Mehr laden", t)
+	verifyStringsEqual(translate(config, "Related posts:"), "Related posts:", t)
+	verifyStringsEqual(translate(map[string]any{}, "Load more"), "Load more", t)
+}
@@ -1,13 +1,19 @@
 package app
 
 import (
+	"bytes"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
 	"os"
 	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/HugoSmits86/nativewebp"
 	"github.com/disintegration/imaging"
 )
 
@@ -32,13 +38,15 @@ func processImgThumbnails(mediaDirPath string, config appConfig) {
 				}
 			}
 		}
+		processImgCropThumbnails(mediaDirPath, config)
 		// ==================================================
 		// generate thumbnails
 		// ==================================================
 		imgFiles, err = listFilesByExt(mediaDirPath, thumbImageFileExtensions...)
 		check(err)
 		for _, imgFile := range imgFiles {
-			if strings.Contains(imgFile, thumbImgFileSuffix) {
+			// the video posters are used as is
+			if strings.Contains(imgFile, thumbImgFileSuffix) || videoPosterFileNameRegexp.MatchString(imgFile) {
 				continue
 			}
 			imgFilePath := fmt.Sprintf("%s%c%s", mediaDirPath, os.PathSeparator, imgFile)
@@ -56,13 +64,12 @@ func processImgThumbnails(mediaDirPath string, config appConfig) {
 						}
 					}
 					if imgFileSizeInMb >= config.thumbThreshold {
-						srcImg, err := imaging.Open(imgFilePath)
+						srcImg, err := openResizableImg(imgFilePath)
 						if err != nil {
 							sprintln(" - error opening image for thumbnail generation: "+imgFilePath, err)
 							continue
 						}
-						iw := srcImg.Bounds().Dx()
-						ih := srcImg.Bounds().Dy()
+						iw, ih := srcImg.size()
 						if iw > thSize || ih > thSize {
 							var tw, th int
 							if iw == ih {
@@ -75,13 +82,7 @@ func processImgThumbnails(mediaDirPath string, config appConfig) {
 								th = thSize
 								tw = thSize * iw / ih
 							}
-							thImg := imaging.Resize(srcImg, tw, th, imaging.Lanczos)
-							if imgFileExt == ".jpg" || imgFileExt == ".jpeg" {
-								err = imaging.Save(thImg, thumbFilePath, imaging.JPEGQuality(config.jpegQuality))
-							} else if imgFileExt == ".png" {
-								err = imaging.Save(thImg, thumbFilePath, imaging.PNGCompressionLevel(config.pngCompressionLevel.Value()))
-							}
-							if err != nil {
+							if err := srcImg.saveResized(thumbFilePath, tw, th, config); err != nil {
 								sprintln(" - error generating a thumbnail for image: "+imgFilePath, err)
 								continue
 							}
@@ -102,16 +103,229 @@ func processImgThumbnails(mediaDirPath string, config appConfig) {
 			}
 		}
 	}
+	processImgWebPVariants(mediaDirPath, config)
+}
+
+// processImgCropThumbnails generates the crop thumbnails of the images in the given media dir
+// (see the `thumbSizes` config option): one per crop size for the image center, along with one per crop size
+// for each of the focal points the image is referenced with (see mediaDirFocalPoints),
+// deleting the ones no longer needed
+func processImgCropThumbnails(mediaDirPath string, config appConfig) {
+	focalPoints := mediaDirFocalPoints(mediaDirPath)
+	imgFocalPoints := func(imgFile string) []focalPoint {
+		fps := []focalPoint{{x: 50, y: 50}}
+		for _, name := range []string{"", imgFile, stripExt(imgFile)} {
+			for _, fp := range focalPoints[name] {
+				if !slices.Contains(fps, fp) {
+					fps = append(fps, fp)
+				}
+			}
+		}
+		return fps
+	}
+	// ==================================================
+	// delete any old / no longer needed crop thumbnails
+	// ==================================================
+	imgFiles, err := listFilesByExt(mediaDirPath, thumbImageFileExtensions...)
+	check(err)
+	for _, imgFile := range imgFiles {
+		thm := cropThumbImgFileNameRegexp.FindStringSubmatchIndex(imgFile)
+		if thm == nil {
+			continue
+		}
+		srcFile := imgFile[:thm[0]]
+		var cs cropThumbSize
+		cs.width, _ = strconv.Atoi(imgFile[thm[2]:thm[3]])
+		cs.height, _ = strconv.Atoi(imgFile[thm[4]:thm[5]])
+		fp := focalPoint{x: 50, y: 50}
+		if thm[6] >= 0 {
+			fp.x, _ = strconv.Atoi(imgFile[thm[6]:thm[7]])
+			fp.y, _ = strconv.Atoi(imgFile[thm[8]:thm[9]])
+		}
+		if !slices.Contains(config.cropThumbSizes, cs) || !slices.Contains(imgFocalPoints(srcFile), fp) ||
+			!fileExists(filepath.Join(mediaDirPath, srcFile)) {
+			thumbFilePath := filepath.Join(mediaDirPath, imgFile)
+			deleteFile(thumbFilePath)
+			sprintln(" - deleted an old / no longer needed crop thumbnail: " + thumbFilePath)
+		}
+	}
+	if len(config.cropThumbSizes) == 0 {
+		return
+	}
+	// ==================================================
+	// generate crop thumbnails
+	// ==================================================
+	for _, imgFile := range imgFiles {
+		// the video posters are used as is
+		if strings.Contains(imgFile, thumbImgFileSuffix) || videoPosterFileNameRegexp.MatchString(imgFile) {
+			continue
+		}
+		imgFilePath := filepath.Join(mediaDirPath, imgFile)
+		var srcImg *resizableImg
+		for _, cs := range config.cropThumbSizes {
+			for _, fp := range imgFocalPoints(imgFile) {
+				thumbFilePath := filepath.Join(mediaDirPath, cropThumbFileName(imgFile, cs, fp))
+				if fileExists(thumbFilePath) {
+					continue
+				}
+				if srcImg == nil {
+					img, err := openResizableImg(imgFilePath)
+					if err != nil {
+						sprintln(" - error opening image for crop thumbnail generation: "+imgFilePath, err)
+						break
+					}
+					srcImg = &img
+				}
+				iw, ih := srcImg.size()
+				rect := cropThumbRect(iw, ih, cs, fp)
+				// the crop is only resized down (an image smaller than the crop size is cropped to its aspect only)
+				tw, th := rect.Dx(), rect.Dy()
+				if tw > cs.width {
+					tw, th = cs.width, cs.height
+				}
+				if err := srcImg.cropped(rect).saveResized(thumbFilePath, tw, th, config); err != nil {
+					sprintln(" - error generating a crop thumbnail for image: "+imgFilePath, err)
+					continue
+				}
+				sprintln(
+					" - generated a crop thumbnail: "+thumbFilePath,
+					" - original image: "+imgFilePath,
+					fmt.Sprintf(" - original image dimensions: %dx%d, focal point: %s, thumbnail dimensions: %dx%d", iw, ih, fp, tw, th),
+				)
+			}
+		}
+	}
+}
+
+// cropThumbRect returns the largest area of the crop size aspect out of the image of the given dimensions,
+// centered on the given focal point as much as the image bounds allow
+func cropThumbRect(iw int, ih int, cs cropThumbSize, fp focalPoint) image.Rectangle {
+	cw, ch := iw, ih
+	if iw*cs.height > ih*cs.width {
+		cw = max(1, ih*cs.width/cs.height)
+	} else {
+		ch = max(1, iw*cs.height/cs.width)
+	}
+	x := min(max(0, iw*fp.x/100-cw/2), iw-cw)
+	y := min(max(0, ih*fp.y/100-ch/2), ih-ch)
+	return image.Rect(x, y, x+cw, y+ch)
+}
+
+// processImgWebPVariants generates the WebP variants of the images (and their thumbnails) in the given media dir
+// (see the `webpVariants` config option), regenerating the ones older than their source images,
+// and deleting the ones no longer needed
+func processImgWebPVariants(mediaDirPath string, config appConfig) {
+	if !dirExists(mediaDirPath) {
+		return
+	}
+	srcFileExtensions := config.webpVariants.sourceFileExtensions()
+	// ==================================================
+	// delete any old / no longer needed variants
+	// ==================================================
+	variantFiles, err := listFilesByExt(mediaDirPath, webpFileExtension)
+	check(err)
+	for _, variantFile := range variantFiles {
+		if !webpVariantFileNameRegexp.MatchString(variantFile) {
+			continue
+		}
+		srcFile := strings.TrimSuffix(variantFile, filepath.Ext(variantFile))
+		srcFileExt := strings.ToLower(filepath.Ext(srcFile))
+		if !slices.Contains(srcFileExtensions, srcFileExt) || !fileExists(filepath.Join(mediaDirPath, srcFile)) ||
+			cropThumbImgFileNameRegexp.MatchString(srcFile) {
+			variantFilePath := filepath.Join(mediaDirPath, variantFile)
+			deleteFile(variantFilePath)
+			sprintln(" - deleted an old / no longer needed WebP variant: " + variantFilePath)
+		}
+	}
+	if len(srcFileExtensions) == 0 {
+		return
+	}
+	// ==================================================
+	// generate variants
+	// ==================================================
+	imgFiles, err := listFilesByExt(mediaDirPath, srcFileExtensions...)
+	check(err)
+	for _, imgFile := range imgFiles {
+		// the crop thumbnails are only meant for the fixed-aspect image containers (e.g. the collection shelves)
+		if videoPosterFileNameRegexp.MatchString(imgFile) || cropThumbImgFileNameRegexp.MatchString(imgFile) {
+			continue
+		}
+		imgFilePath := filepath.Join(mediaDirPath, imgFile)
+		variantFilePath := imgFilePath + webpFileExtension
+		if variantInfo, err := os.Stat(variantFilePath); err == nil {
+			if imgInfo, err := os.Stat(imgFilePath); err == nil && !variantInfo.ModTime().Before(imgInfo.ModTime()) {
+				continue
+			}
+		}
+		// the image isn't re-encoded until it changes, if its variant has turned out not to be smaller than the image
+		imgInfo := getMediaInfo(imgFilePath, false)
+		if imgInfo.NoWebP {
+			continue
+		}
+		smaller, err := writeWebPVariant(imgFilePath, variantFilePath)
+		if err != nil {
+			sprintln(" - error generating a WebP variant for image: "+imgFilePath, err)
+			continue
+		}
+		if !smaller {
+			// the variant of the previous version of the image (if any) is no longer valid
+			deleteIfExists(variantFilePath)
+			if imgInfo.Size > 0 {
+				imgInfo.NoWebP = true
+				recordMediaInfo(imgFilePath, imgInfo)
+			}
+			sprintln(" - skipped a WebP variant not smaller than the image: " + imgFilePath)
+			continue
+		}
+		imgFileSizeInMb, _ := getFileSizeInMb(imgFilePath)
+		variantFileSizeInMb, _ := getFileSizeInMb(variantFilePath)
+		sprintln(
+			" - generated a WebP variant: "+variantFilePath,
+			fmt.Sprintf(" - image file size: %.2f MB, WebP variant file size: %.2f MB", imgFileSizeInMb, variantFileSizeInMb),
+		)
+	}
+}
+
+// writeWebPVariant encodes the given image (losslessly) into a WebP file, unless the variant isn't smaller than the image
+// (e.g. for the PNG images of photos or noise-like content), reporting whether it's written
+func writeWebPVariant(imgFilePath string, variantFilePath string) (written bool, err error) {
+	// the encoder panics on some of the (noise-like) images, which mustn't fail the whole generation
+	defer func() {
+		if r := recover(); r != nil {
+			written, err = false, fmt.Errorf("WebP encoding failed: %v", r)
+		}
+	}()
+	imgInfo, err := os.Stat(imgFilePath)
+	if err != nil {
+		return false, err
+	}
+	img, err := imaging.Open(imgFilePath, imaging.AutoOrientation(true))
+	if err != nil {
+		return false, err
+	}
+	var buf bytes.Buffer
+	if err := nativewebp.Encode(&buf, img, nil); err != nil {
+		return false, err
+	}
+	if int64(buf.Len()) >= imgInfo.Size() {
+		return false, nil
+	}
+	return true, os.WriteFile(variantFilePath, buf.Bytes(), 0644)
+}
+
+// isImgThumbnailOrVariant reports whether the given image file is a generated thumbnail or WebP variant
+func isImgThumbnailOrVariant(imgFile string) bool {
+	return thumbImgFileNameRegexp.MatchString(imgFile) || cropThumbImgFileNameRegexp.MatchString(imgFile) ||
+		webpVariantFileNameRegexp.MatchString(imgFile)
 }
 
 func deleteImgThumbnails(imgDirPath string, config appConfig) {
 	if dirExists(imgDirPath) {
-		imgFiles, err := listFilesByExt(imgDirPath, thumbImageFileExtensions...)
+		imgFiles, err := listFilesByExt(imgDirPath, slices.Concat(thumbImageFileExtensions, []string{webpFileExtension})...)
 		check(err)
 		if len(imgFiles) > 0 {
 			for _, imgFile := range imgFiles {
-				thm := thumbImgFileNameRegexp.FindStringSubmatch(imgFile)
-				if thm != nil {
+				if isImgThumbnailOrVariant(imgFile) {
 					thumbFilePath := fmt.Sprintf("%s%c%s", imgDirPath, os.PathSeparator, imgFile)
 					deleteFile(thumbFilePath)
 					sprintln(" - deleted thumbnail: " + thumbFilePath)
@@ -123,7 +337,7 @@ func deleteImgThumbnails(imgDirPath string, config appConfig) {
 
 func processOriginalMediaFiles(config appConfig, dryRun bool) bool {
 	resizeCnt := 0
-	if config.resizeOrigImages && config.maxImgSize > 0 {
+	if (config.resizeOrigImages && config.maxImgSize > 0) || config.stripImgMetadata != NoImgMetadataStrip {
 		deployMediaDir := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, mediaDirName)
 		if dirExists(deployMediaDir) {
 			ceTypeMediaDirs := []string{
@@ -159,9 +373,9 @@ func processOriginalMediaFiles(config appConfig, dryRun bool) bool {
 							}
 							if resizeCnt > 0 {
 								if dryRun {
-									sprintln(" - " + strconv.Itoa(resizeCnt) + " original image(s) exceed the max size")
+									sprintln(" - " + strconv.Itoa(resizeCnt) + " original image(s) to be resized / stripped of metadata")
 								} else {
-									sprintln(" - resized " + strconv.Itoa(resizeCnt) + " original image(s)")
+									sprintln(" - resized / stripped of metadata " + strconv.Itoa(resizeCnt) + " original image(s)")
 								}
 							}
 						}
@@ -186,65 +400,72 @@ func processOriginalMediaFiles(config appConfig, dryRun bool) bool {
 	return resizeCnt > 0
 }
 
+// processOriginalMediaFile resizes the given original image if it exceeds the max size (see the resizeOrigImages
+// and maxImgSize config options), and removes its metadata (see the stripImgMetadata config option),
+// reporting whether the image has been (or, on a dry run, is to be) processed
 func processOriginalMediaFile(mediaFilePath string, config appConfig, dryRun bool) bool {
+	fileExt := strings.ToLower(filepath.Ext(mediaFilePath))
+	if !slices.Contains(thumbImageFileExtensions, fileExt) {
+		return false
+	}
+	resized := resizeOriginalImg(mediaFilePath, config, dryRun)
+	// a resized image is saved without any metadata
+	if config.stripImgMetadata != NoImgMetadataStrip && (dryRun || !resized) {
+		return stripOriginalImgMetadata(mediaFilePath, config, dryRun) || resized
+	}
+	return resized
+}
+
+func resizeOriginalImg(mediaFilePath string, config appConfig, dryRun bool) bool {
 	if config.resizeOrigImages {
-		fileExt := strings.ToLower(filepath.Ext(mediaFilePath))
-		if slices.Contains(thumbImageFileExtensions, fileExt) {
-			maxImgSize := config.maxImgSize
-			if maxImgSize > 0 {
-				origImg, err := imaging.Open(mediaFilePath)
-				if err != nil {
-					sprintln(" - error opening file to check image dimensions: "+mediaFilePath, err)
-				} else {
-					ow := origImg.Bounds().Dx()
-					oh := origImg.Bounds().Dy()
-
-					if ow > maxImgSize || oh > maxImgSize {
+		maxImgSize := config.maxImgSize
+		if maxImgSize > 0 {
+			origImg, err := openResizableImg(mediaFilePath)
+			if err != nil {
+				sprintln(" - error opening file to check image dimensions: "+mediaFilePath, err)
+			} else {
+				ow, oh := origImg.size()
+
+				if ow > maxImgSize || oh > maxImgSize {
+					// ==================================================
+					// calculate the new image dimensions
+					// ==================================================
+					var tw, th int
+					if ow == oh {
+						tw = maxImgSize
+						th = maxImgSize
+					} else if ow > oh {
+						tw = maxImgSize
+						th = maxImgSize * oh / ow
+					} else {
+						th = maxImgSize
+						tw = maxImgSize * ow / oh
+					}
+
+					if dryRun {
 						// ==================================================
-						// calculate the new image dimensions
+						// report the image file that exceeds the max size
+						// ==================================================
+						sprintln(
+							" - original image exceeds the max size: "+mediaFilePath,
+							fmt.Sprintf(" - original image dimensions: %dx%d, expected dimensions: %dx%d", ow, oh, tw, th),
+						)
+						return true
+					} else {
+						// ==================================================
+						// resize and save the image to the original file
+						// ==================================================
+						err = origImg.saveResized(mediaFilePath, tw, th, config)
 						// ==================================================
-						var tw, th int
-						if ow == oh {
-							tw = maxImgSize
-							th = maxImgSize
-						} else if ow > oh {
-							tw = maxImgSize
-							th = maxImgSize * oh / ow
-						} else {
-							th = maxImgSize
-							tw = maxImgSize * ow / oh
-						}
 
-						if dryRun {
-							// ==================================================
-							// report the image file that exceeds the max size
-							// ==================================================
+						if err != nil {
+							sprintln(" - error saving resized image: "+mediaFilePath, err)
+						} else {
 							sprintln(
-								" - original image exceeds the max size: "+mediaFilePath,
-								fmt.Sprintf(" - original image dimensions: %dx%d, expected dimensions: %dx%d", ow, oh, tw, th),
+								" - resized the original image: "+mediaFilePath,
+								fmt.Sprintf(" - original image dimensions: %dx%d, resized dimensions: %dx%d", ow, oh, tw, th),
 							)
 							return true
-						} else {
-							// ==================================================
-							// resize and save the image to the original file
-							// ==================================================
-							newImg := imaging.Resize(origImg, tw, th, imaging.Lanczos)
-							if fileExt == ".jpg" || fileExt == ".jpeg" {
-								err = imaging.Save(newImg, mediaFilePath, imaging.JPEGQuality(config.jpegQuality))
-							} else if fileExt == ".png" {
-								err = imaging.Save(newImg, mediaFilePath, imaging.PNGCompressionLevel(config.pngCompressionLevel.Value()))
-							}
-							// ==================================================
-
-							if err != nil {
-								sprintln(" - error saving resized image: "+mediaFilePath, err)
-							} else {
-								sprintln(
-									" - resized the original image: "+mediaFilePath,
-									fmt.Sprintf(" - original image dimensions: %dx%d, resized dimensions: %dx%d", ow, oh, tw, th),
-								)
-								return true
-							}
 						}
 					}
 				}
@@ -253,3 +474,164 @@ func processOriginalMediaFile(mediaFilePath string, config appConfig, dryRun boo
 	}
 	return false
 }
+
+// resizableImg is an image opened for resizing: GIFs are decoded along with all of their frames,
+// so that the animated ones stay animated once resized
+type resizableImg struct {
+	img  image.Image
+	anim *gif.GIF
+	crop image.Rectangle // the area of the GIF frames to be resized (the whole frames if empty)
+}
+
+func openResizableImg(imgFilePath string) (resizableImg, error) {
+	if strings.ToLower(filepath.Ext(imgFilePath)) != ".gif" {
+		// the EXIF orientation is applied, as the resized images are saved without any metadata
+		img, err := imaging.Open(imgFilePath, imaging.AutoOrientation(true))
+		return resizableImg{img: img}, err
+	}
+	f, err := os.Open(imgFilePath)
+	if err != nil {
+		return resizableImg{}, err
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		return resizableImg{}, err
+	}
+	return resizableImg{anim: anim}, nil
+}
+
+// cropped returns the given area of the image
+func (ri resizableImg) cropped(rect image.Rectangle) resizableImg {
+	if ri.anim != nil {
+		return resizableImg{anim: ri.anim, crop: rect}
+	}
+	return resizableImg{img: imaging.Crop(ri.img, rect)}
+}
+
+// size returns the image dimensions (the logical screen ones for GIFs, as the frames may be smaller)
+func (ri resizableImg) size() (int, int) {
+	if !ri.crop.Empty() {
+		return ri.crop.Dx(), ri.crop.Dy()
+	}
+	if ri.anim != nil {
+		if ri.anim.Config.Width > 0 && ri.anim.Config.Height > 0 {
+			return ri.anim.Config.Width, ri.anim.Config.Height
+		}
+		return ri.anim.Image[0].Bounds().Max.X, ri.anim.Image[0].Bounds().Max.Y
+	}
+	return ri.img.Bounds().Dx(), ri.img.Bounds().Dy()
+}
+
+// saveResized resizes the image to the given dimensions and saves it to the given file
+// (in the format matching the file extension)
+func (ri resizableImg) saveResized(filePath string, width int, height int, config appConfig) error {
+	if ri.anim != nil {
+		return ri.saveResizedGIF(filePath, width, height)
+	}
+	resized := imaging.Resize(ri.img, width, height, imaging.Lanczos)
+	if strings.ToLower(filepath.Ext(filePath)) == ".png" {
+		return imaging.Save(resized, filePath, imaging.PNGCompressionLevel(config.pngCompressionLevel.Value()))
+	}
+	return imaging.Save(resized, filePath, imaging.JPEGQuality(config.jpegQuality))
+}
+
+// saveResizedGIF resizes each of the GIF frames: the frames are composed onto the full-size canvas first
+// (honouring their disposal methods), so that each of the resized frames is a complete one,
+// mapped back onto the palette of the original frame (merged with the ones of the preceding frames,
+// as partial frames may come with the palettes of their own colors only)
+func (ri resizableImg) saveResizedGIF(filePath string, width int, height int) error {
+	w, h := resizableImg{anim: ri.anim}.size()
+	canvas := image.NewNRGBA(image.Rect(0, 0, w, h))
+	resized := &gif.GIF{LoopCount: ri.anim.LoopCount}
+	var palette color.Palette
+	for i, frame := range ri.anim.Image {
+		var disposal byte
+		if i < len(ri.anim.Disposal) {
+			disposal = ri.anim.Disposal[i]
+		}
+		var prevCanvas *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			prevCanvas = imaging.Clone(canvas)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		palette = mergeGIFPalettes(frame.Palette, palette)
+		resizedFrame := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+		var src image.Image = canvas
+		if !ri.crop.Empty() {
+			src = imaging.Crop(canvas, ri.crop)
+		}
+		draw.Draw(resizedFrame, resizedFrame.Bounds(), imaging.Resize(src, width, height, imaging.Lanczos), image.Point{}, draw.Src)
+		resized.Image = append(resized.Image, resizedFrame)
+		var delay int
+		if i < len(ri.anim.Delay) {
+			delay = ri.anim.Delay[i]
+		}
+		resized.Delay = append(resized.Delay, delay)
+		// each of the resized frames replaces the previous one as a whole
+		resized.Disposal = append(resized.Disposal, gif.DisposalBackground)
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = prevCanvas
+		}
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, resized); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, buf.Bytes(), 0644)
+}
+
+// mergeGIFPalettes merges the unique colors of the given palettes (in order, up to the max GIF palette size),
+// along with the transparent color (if there's still room for it)
+func mergeGIFPalettes(palettes ...color.Palette) color.Palette {
+	var merged color.Palette
+	seen := make(map[color.RGBA]bool)
+	for _, p := range slices.Concat(palettes...) {
+		c := color.RGBAModel.Convert(p).(color.RGBA)
+		if !seen[c] && len(merged) < maxGIFPaletteSize {
+			seen[c] = true
+			merged = append(merged, c)
+		}
+	}
+	if !seen[color.RGBA{}] && len(merged) < maxGIFPaletteSize {
+		merged = append(merged, color.RGBA{})
+	}
+	return merged
+}
+
+// stripOriginalImgMetadata removes the metadata from the given original image (see the stripImgMetadata config option):
+// if all of it is to be removed, the EXIF orientation (if any) is applied to the image itself, re-encoding it
+func stripOriginalImgMetadata(imgFilePath string, config appConfig, dryRun bool) bool {
+	data, err := os.ReadFile(imgFilePath)
+	if err != nil {
+		sprintln(" - error reading image file to check its metadata: "+imgFilePath, err)
+		return false
+	}
+	stripped := stripImgExif(data, config.stripImgMetadata)
+	if stripped == nil {
+		return false
+	}
+	if dryRun {
+		sprintln(" - original image contains metadata to be removed (" + config.stripImgMetadata.String() + "): " + imgFilePath)
+		return true
+	}
+	if config.stripImgMetadata == AllImgMetadataStrip && readImgExif(data).orientation > 1 {
+		var img resizableImg
+		img, err = openResizableImg(imgFilePath)
+		if err == nil {
+			w, h := img.size()
+			err = img.saveResized(imgFilePath, w, h, config)
+		}
+	} else {
+		err = os.WriteFile(imgFilePath, stripped, 0644)
+	}
+	if err != nil {
+		sprintln(" - error removing the metadata from image: "+imgFilePath, err)
+		return false
+	}
+	sprintln(" - removed the metadata (" + config.stripImgMetadata.String() + ") from the original image: " + imgFilePath)
+	return true
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestImgWebPVariants(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	mediaDirPath := filepath.Join(deployDirName, mediaDirName, "post", "p")
+	check(os.MkdirAll(mediaDirPath, 0755))
+	img := image.NewNRGBA(image.Rect(0, 0, 600, 400))
+	for x := 0; x < 600; x++ {
+		for y := 0; y < 400; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	f, err := os.Create(filepath.Join(mediaDirPath, "a.png"))
+	check(err)
+	check(png.Encode(f, img))
+	check(f.Close())
+	check(os.WriteFile(filepath.Join(mediaDirPath, "b.jpg"), []byte("not processed"), 0644))
+
+	config := defaultConfig()
+	config.thumbSizes = []int{300}
+	config.thumbThreshold = 0
+	config.webpVariants = PNGWebPVariants
+	processImgThumbnails(mediaDirPath, config)
+
+	for _, variantFile := range []string{"a.png.webp", "a.png_300_thumb.png.webp"} {
+		data := readDataFromFile(filepath.Join(mediaDirPath, variantFile))
+		if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+			t.Errorf("expected a WebP file: %s", variantFile)
+		}
+	}
+	if fileExists(filepath.Join(mediaDirPath, "b.jpg.webp")) {
+		t.Error("expected no WebP variant of a JPEG image (png only)")
+	}
+
+	m := buildMediaItem("a.png", "post/p", filepath.Join("post", "p"), config)
+	if !m.HasWebP() {
+		t.Fatal("expected the WebP variants to be available")
+	}
+	verifyStringsEqual(m.WebPSrcSet(), "/media/post/p/a.png_300_thumb.png.webp 300w, /media/post/p/a.png.webp 1590w", t)
+	verifyStringsEqual(m.SrcSet(), "/media/post/p/a.png_300_thumb.png 300w, /media/post/p/a.png 1590w", t)
+	if listing := mediaDirListing(mediaDirPath); listing != hashStrings(mediaDirListingEntry(t, mediaDirPath, "a.png"), mediaDirListingEntry(t, mediaDirPath, "b.jpg")) {
+		t.Error("expected the thumbnails and WebP variants to be left out of the media dir listing")
+	}
+
+	// the variants are deleted once no longer configured
+	config.webpVariants = NoWebPVariants
+	processImgThumbnails(mediaDirPath, config)
+	if fileExists(filepath.Join(mediaDirPath, "a.png.webp")) || fileExists(filepath.Join(mediaDirPath, "a.png_300_thumb.png.webp")) {
+		t.Error("expected the WebP variants to be deleted")
+	}
+	if buildMediaItem("a.png", "post/p", filepath.Join("post", "p"), config).HasWebP() {
+		t.Error("expected no WebP variants")
+	}
+
+	// a variant not smaller than its image (e.g. a noise-like one) isn't used,
+	// while the JPEG images aren't encoded at all (their lossless variants are nearly always larger)
+	noise := rand.New(rand.NewSource(1))
+	for x := 0; x < 600; x++ {
+		for y := 0; y < 400; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(noise.Intn(16)), G: uint8(noise.Intn(16)), B: uint8(noise.Intn(16)), A: 255})
+		}
+	}
+	f, err = os.Create(filepath.Join(mediaDirPath, "c.png"))
+	check(err)
+	check(png.Encode(f, img))
+	check(f.Close())
+	f, err = os.Create(filepath.Join(mediaDirPath, "d.jpg"))
+	check(err)
+	check(jpeg.Encode(f, img, &jpeg.Options{Quality: 50}))
+	check(f.Close())
+	config.webpVariants = webpVariantsPolicyFromString(webpVariantsAllValue)
+	processImgThumbnails(mediaDirPath, config)
+	if !fileExists(filepath.Join(mediaDirPath, "a.png.webp")) {
+		t.Error("expected a WebP variant of the PNG image")
+	}
+	if fileExists(filepath.Join(mediaDirPath, "c.png.webp")) {
+		t.Error("expected no WebP variant not smaller than the PNG image")
+	}
+	if !getMediaInfo(filepath.Join(mediaDirPath, "c.png"), false).NoWebP {
+		t.Error("expected the PNG image info to record the WebP variant not being smaller")
+	}
+	if fileExists(filepath.Join(mediaDirPath, "d.jpg.webp")) || getMediaInfo(filepath.Join(mediaDirPath, "d.jpg"), false).NoWebP {
+		t.Error("expected the JPEG image not to be encoded")
+	}
+	if buildMediaItem("d.jpg", "post/p", filepath.Join("post", "p"), config).HasWebP() {
+		t.Error("expected no WebP variants of the JPEG image")
+	}
+
+	// thumbnails cleanup deletes the variants along with the thumbnails
+	deleteImgThumbnails(mediaDirPath, config)
+	entries, err := os.ReadDir(mediaDirPath)
+	check(err)
+	if len(entries) != 4 {
+		t.Errorf("expected only the original images to be left, got: %v", entries)
+	}
+}
+
+func TestWriteWebPVariantEncoderFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	// the encoder fails on the (full range) noise images
+	noise := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	for x := 0; x < 300; x++ {
+		for y := 0; y < 200; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(noise.Intn(256)), G: uint8(noise.Intn(256)), B: uint8(noise.Intn(256)), A: 255})
+		}
+	}
+	imgFilePath := filepath.Join(tmpDir, "noise.png")
+	f, err := os.Create(imgFilePath)
+	check(err)
+	check(png.Encode(f, img))
+	check(f.Close())
+	if written, err := writeWebPVariant(imgFilePath, imgFilePath+webpFileExtension); written || err == nil {
+		t.Errorf("expected the encoding failure to be reported, got: %t, %v", written, err)
+	}
+}
+
+func mediaDirListingEntry(t *testing.T, mediaDirPath string, fileName string) string {
+	t.Helper()
+	info, err := os.Stat(filepath.Join(mediaDirPath, fileName))
+	check(err)
+	return fileName + "|" + strconv.FormatInt(info.Size(), 10) + "|" + strconv.FormatInt(info.ModTime().UnixNano(), 10)
+}
+
+func TestImgGIFResizing(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	mediaDirPath := filepath.Join(deployDirName, mediaDirName, "post", "p")
+	check(os.MkdirAll(mediaDirPath, 0755))
+	// a full red frame followed by a partial blue one (with a palette of its own)
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	frame1 := image.NewPaletted(image.Rect(0, 0, 600, 400), color.Palette{red})
+	frame2 := image.NewPaletted(image.Rect(0, 0, 200, 200), color.Palette{blue})
+	gifFilePath := filepath.Join(mediaDirPath, "a.gif")
+	f, err := os.Create(gifFilePath)
+	check(err)
+	check(gif.EncodeAll(f, &gif.GIF{Image: []*image.Paletted{frame1, frame2}, Delay: []int{10, 20}, Disposal: []byte{gif.DisposalNone, gif.DisposalNone}}))
+	check(f.Close())
+
+	decodeGIF := func(filePath string) *gif.GIF {
+		f, err := os.Open(filePath)
+		check(err)
+		defer f.Close()
+		anim, err := gif.DecodeAll(f)
+		check(err)
+		return anim
+	}
+	verifyRGBA := func(c color.Color, expected color.RGBA) {
+		t.Helper()
+		if color.RGBAModel.Convert(c) != expected {
+			t.Errorf("unexpected color: %v (expected: %v)", c, expected)
+		}
+	}
+
+	// animated thumbnails
+	config := defaultConfig()
+	config.thumbSizes = []int{300}
+	config.thumbThreshold = 0
+	processImgThumbnails(mediaDirPath, config)
+	th := decodeGIF(filepath.Join(mediaDirPath, "a.gif_300_thumb.gif"))
+	if len(th.Image) != 2 || th.Delay[0] != 10 || th.Delay[1] != 20 {
+		t.Fatalf("expected an animated thumbnail, got %d frame(s) with delays: %v", len(th.Image), th.Delay)
+	}
+	if b := th.Image[1].Bounds(); b.Dx() != 300 || b.Dy() != 200 {
+		t.Errorf("unexpected thumbnail frame dimensions: %dx%d", b.Dx(), b.Dy())
+	}
+	// the partial frame is composed onto the preceding one
+	verifyRGBA(th.Image[1].At(20, 20), blue)
+	verifyRGBA(th.Image[1].At(250, 150), red)
+	if m := buildMediaItem("a.gif", "post/p", filepath.Join("post", "p"), config); m.ThumbUri(1) != "/media/post/p/a.gif_300_thumb.gif" {
+		t.Errorf("unexpected thumbnail URI: %s", m.ThumbUri(1))
+	}
+
+	// original image resizing
+	config.resizeOrigImages = true
+	config.maxImgSize = 450
+	if !processOriginalMediaFile(gifFilePath, config, true) {
+		t.Error("expected the GIF to be reported as exceeding the max size")
+	}
+	if decodeGIF(gifFilePath).Config.Width != 600 {
+		t.Error("expected the GIF not to be resized on a dry run")
+	}
+	if !processOriginalMediaFile(gifFilePath, config, false) {
+		t.Error("expected the GIF to be resized")
+	}
+	resized := decodeGIF(gifFilePath)
+	if len(resized.Image) != 2 || resized.Config.Width != 450 || resized.Config.Height != 300 {
+		t.Errorf("unexpected resized GIF: %d frame(s), %dx%d", len(resized.Image), resized.Config.Width, resized.Config.Height)
+	}
+	if processOriginalMediaFile(gifFilePath, config, true) {
+		t.Error("expected the resized GIF not to exceed the max size")
+	}
+}
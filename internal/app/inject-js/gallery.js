@@ -0,0 +1,135 @@
+// the gallery script is included along with every gallery directive, so it's only initialized once per page
+if (!window.galleryLightbox) {
+    window.galleryLightbox = (function () {
+        let lightboxEl;
+        let imgEl;
+        let captionEl;
+        let counterEl;
+        let items = [];
+        let currIdx = 0;
+        let lastFocusEl;
+
+        function createLightbox() {
+            lightboxEl = document.createElement('div');
+            lightboxEl.id = 'gallery-lightbox';
+            lightboxEl.setAttribute('role', 'dialog');
+            lightboxEl.setAttribute('aria-modal', 'true');
+            lightboxEl.setAttribute('tabindex', '-1');
+            lightboxEl.style.display = 'none';
+            lightboxEl.innerHTML =
+                '<button type="button" class="gallery-lightbox-close" aria-label="Close">&times;</button>' +
+                '<button type="button" class="gallery-lightbox-prev" aria-label="Previous">&lsaquo;</button>' +
+                '<figure>' +
+                    '<img alt="Image" sizes="100vw" />' +
+                    '<figcaption></figcaption>' +
+                '</figure>' +
+                '<button type="button" class="gallery-lightbox-next" aria-label="Next">&rsaquo;</button>' +
+                '<div class="gallery-lightbox-counter"></div>';
+            imgEl = lightboxEl.querySelector('img');
+            captionEl = lightboxEl.querySelector('figcaption');
+            counterEl = lightboxEl.querySelector('.gallery-lightbox-counter');
+            lightboxEl.querySelector('.gallery-lightbox-close').addEventListener('click', close);
+            lightboxEl.querySelector('.gallery-lightbox-prev').addEventListener('click', function () {
+                show(currIdx - 1);
+            });
+            lightboxEl.querySelector('.gallery-lightbox-next').addEventListener('click', function () {
+                show(currIdx + 1);
+            });
+            // a click on the backdrop (outside the image and the buttons) closes the lightbox
+            lightboxEl.addEventListener('click', function (e) {
+                if (e.target === lightboxEl || e.target.tagName === 'FIGURE') {
+                    close();
+                }
+            });
+            document.body.appendChild(lightboxEl);
+        }
+
+        function show(idx) {
+            currIdx = (idx + items.length) % items.length;
+            const item = items[currIdx];
+            // the srcset lets the browser pick the thumbnail fitting the viewport (the original image being the largest one)
+            imgEl.removeAttribute('srcset');
+            imgEl.src = item.href;
+            if (item.dataset.srcset) {
+                imgEl.srcset = item.dataset.srcset;
+            }
+            const caption = item.dataset.caption || '';
+            captionEl.textContent = caption;
+            captionEl.style.display = caption ? '' : 'none';
+            counterEl.textContent = items.length > 1 ? (currIdx + 1) + ' / ' + items.length : '';
+            const multi = items.length > 1;
+            lightboxEl.querySelector('.gallery-lightbox-prev').style.display = multi ? '' : 'none';
+            lightboxEl.querySelector('.gallery-lightbox-next').style.display = multi ? '' : 'none';
+        }
+
+        function open(galleryEl, itemEl) {
+            if (!lightboxEl) {
+                createLightbox();
+            }
+            items = Array.from(galleryEl.querySelectorAll('a.gallery-item'));
+            lastFocusEl = itemEl;
+            show(items.indexOf(itemEl));
+            lightboxEl.style.display = '';
+            document.body.classList.add('gallery-lightbox-open');
+            lightboxEl.focus();
+        }
+
+        function close() {
+            lightboxEl.style.display = 'none';
+            imgEl.removeAttribute('srcset');
+            imgEl.removeAttribute('src');
+            document.body.classList.remove('gallery-lightbox-open');
+            if (lastFocusEl) {
+                lastFocusEl.focus();
+            }
+        }
+
+        function isOpen() {
+            return lightboxEl && lightboxEl.style.display !== 'none';
+        }
+
+        document.addEventListener('click', function (e) {
+            if (e.defaultPrevented || e.button !== 0 || e.metaKey || e.ctrlKey || e.shiftKey || e.altKey) {
+                return;
+            }
+            const itemEl = e.target.closest('a.gallery-item');
+            if (!itemEl) {
+                return;
+            }
+            const galleryEl = itemEl.closest('.gallery');
+            if (!galleryEl) {
+                return;
+            }
+            e.preventDefault();
+            open(galleryEl, itemEl);
+        });
+
+        document.addEventListener('keydown', function (e) {
+            if (!isOpen()) {
+                return;
+            }
+            switch (e.key) {
+                case 'ArrowLeft':
+                    show(currIdx - 1);
+                    break;
+                case 'ArrowRight':
+                    show(currIdx + 1);
+                    break;
+                case 'Home':
+                    show(0);
+                    break;
+                case 'End':
+                    show(items.length - 1);
+                    break;
+                case 'Escape':
+                    close();
+                    break;
+                default:
+                    return;
+            }
+            e.preventDefault();
+        });
+
+        return { open: open, close: close };
+    })();
+}
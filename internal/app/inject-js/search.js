@@ -6,6 +6,10 @@ let pageSize;
 let searchResults;
 let searchIndex;
 
+// the search page lives at the root of its language dir (e.g. `/de/search.html`),
+// so the search index and the content files are resolved against it
+const searchBaseUri = window.location.pathname.substring(0, window.location.pathname.lastIndexOf('/') + 1);
+
 const searchHelpToggleLabelDisabled = '(?)';
 const searchHelpToggleLabelEnabled = 'Hide Help';
 const searchHelpContent =
@@ -36,7 +40,7 @@ function search(query) {
     if (query) {
         if (!searchIndex) {
             const xhr = new XMLHttpRequest();
-            xhr.open('GET', '/search.json', false);
+            xhr.open('GET', searchBaseUri + 'search.json', false);
             xhr.send();
             if (xhr.readyState === XMLHttpRequest.DONE) {
                 if (xhr.status === 200) {
@@ -171,7 +175,7 @@ function renderNextSearchResultsPage() {
 
 function processContent(typeId) {
     const xhr = new XMLHttpRequest();
-    xhr.open('GET', '/' + typeId + '.html', false);
+    xhr.open('GET', searchBaseUri + typeId + '.html', false);
     xhr.send();
     if (xhr.readyState === XMLHttpRequest.DONE) {
         if (xhr.status === 200) {
@@ -179,7 +183,7 @@ function processContent(typeId) {
             lDoc.documentElement.innerHTML = xhr.responseText;
             const lMainEl = lDoc.getElementsByTagName('main')[0];
             const lHeaderEl = lMainEl.getElementsByTagName('header')[0];
-            lHeaderEl.innerHTML += '<span class="links"><a href="/' + typeId + '.html" class="permalink"><i class="fa-solid fa-link"></i></a></span>';
+            lHeaderEl.innerHTML += '<span class="links"><a href="' + searchBaseUri + typeId + '.html" class="permalink"><i class="fa-solid fa-link"></i></a></span>';
             searchResultsEl.innerHTML += lMainEl.innerHTML;
         } else {
             console.error("Failed to load content for: " + typeId);
@@ -209,7 +213,7 @@ window.onload = function() {
             event.preventDefault();
             const query = searchQueryEl.value;
             if (query) {
-                window.history.pushState({}, '', '/search.html?q=' + encodeURIComponent(query));
+                window.history.pushState({}, '', searchBaseUri + 'search.html?q=' + encodeURIComponent(query));
                 search(query);
             }
         }
@@ -220,7 +224,7 @@ window.onload = function() {
         event.preventDefault();
         const query = searchQueryEl.value;
         if (query) {
-            window.history.pushState({}, '', '/search.html?q=' + encodeURIComponent(query));
+            window.history.pushState({}, '', searchBaseUri + 'search.html?q=' + encodeURIComponent(query));
             search(query);
         }
     });
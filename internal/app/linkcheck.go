@@ -0,0 +1,187 @@
+package app
+
+import (
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// linkAttrRegexp matches the href/src attribute values of the generated markup
+var linkAttrRegexp = regexp.MustCompile(`(?i)\s(?:href|src)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// linkSchemeRegexp matches the links with a URI scheme (e.g. `https:`, `mailto:`, `data:`)
+var linkSchemeRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// brokenLinks lists the broken links found in the generated output (in order of appearance),
+// grouped by the source they originate from: the markdown file of a post/page,
+// or the generated file itself (for the links rendered by the templates/listings)
+type brokenLinks map[string][]string
+
+// findBrokenLinks scans the href/src attributes of the generated (.html) files in the deploy dir,
+// resolving the internal links against the file system; the home pages (language -> page id)
+// are used to attribute the links of the generated index files to their source markdown files
+func findBrokenLinks(config appConfig, homePages map[string]string) brokenLinks {
+	broken := brokenLinks{}
+	if !dirExists(deployDirName) {
+		return broken
+	}
+	type fileLinks struct {
+		source string
+		links  []brokenLink
+	}
+	var entitySources, otherSources []fileLinks
+	err := filepath.WalkDir(deployDirName, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), contentFileExtension) {
+			return err
+		}
+		links := findBrokenFileLinks(path, string(readDataFromFile(path)))
+		if len(links) == 0 {
+			return nil
+		}
+		if source := linkSourceMarkdownFile(path, config, homePages); source != "" {
+			entitySources = append(entitySources, fileLinks{source, links})
+		} else {
+			otherSources = append(otherSources, fileLinks{filepath.ToSlash(path), links})
+		}
+		return nil
+	})
+	check(err)
+	// the post content is also rendered into the listing pages (home, tags, archive, etc.),
+	// so a broken link target already attributed to a markdown file is not reported again for those
+	// (the same relative link may resolve to different targets in different files)
+	attributed := map[string]bool{}
+	for _, fl := range entitySources {
+		for _, bl := range fl.links {
+			attributed[bl.target] = true
+			if !slices.Contains(broken[fl.source], bl.link) {
+				broken[fl.source] = append(broken[fl.source], bl.link)
+			}
+		}
+	}
+	for _, fl := range otherSources {
+		for _, bl := range fl.links {
+			if !attributed[bl.target] && !slices.Contains(broken[fl.source], bl.link) {
+				broken[fl.source] = append(broken[fl.source], bl.link)
+			}
+		}
+	}
+	return broken
+}
+
+// brokenLink is a broken internal link along with the (file system) target it resolves to
+type brokenLink struct {
+	link   string
+	target string
+}
+
+// findBrokenFileLinks returns the (distinct) broken internal links of the given generated file
+func findBrokenFileLinks(filePath string, markup string) []brokenLink {
+	var broken []brokenLink
+	for _, match := range linkAttrRegexp.FindAllStringSubmatch(markup, -1) {
+		link := match[1] + match[2]
+		if target, ok := resolveInternalLink(filePath, link); ok && !linkTargetExists(target) &&
+			!slices.ContainsFunc(broken, func(bl brokenLink) bool { return bl.link == link }) {
+			broken = append(broken, brokenLink{link: link, target: target})
+		}
+	}
+	return broken
+}
+
+// resolveInternalLink resolves the given link (found in the given generated file) to a file system path,
+// returning false for the links that are not to be checked (external, same page, mailto, data URIs, etc.)
+func resolveInternalLink(filePath string, link string) (string, bool) {
+	link = strings.TrimSpace(link)
+	if link == "" || strings.HasPrefix(link, "#") || strings.HasPrefix(link, "//") ||
+		linkSchemeRegexp.MatchString(link) || strings.Contains(link, "{{") {
+		return "", false
+	}
+	if i := strings.IndexAny(link, "?#"); i >= 0 {
+		link = link[:i]
+	}
+	if link == "" {
+		return "", false
+	}
+	p, err := url.PathUnescape(link)
+	if err != nil {
+		p = link
+	}
+	var target string
+	if strings.HasPrefix(p, "/") {
+		target = filepath.Join(deployDirName, filepath.FromSlash(p))
+	} else {
+		target = filepath.Join(filepath.Dir(filePath), filepath.FromSlash(p))
+	}
+	if strings.HasSuffix(p, "/") {
+		target = filepath.Join(target, indexPageFileName)
+	}
+	return target, true
+}
+
+// linkTargetExists checks whether the given link target exists (a dir is resolved to its index file)
+func linkTargetExists(target string) bool {
+	info, err := os.Stat(target)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		return fileExists(filepath.Join(target, indexPageFileName))
+	}
+	return true
+}
+
+// linkSourceMarkdownFile returns the markdown file the given generated post/page/home page originates from
+// (empty for the other generated files)
+func linkSourceMarkdownFile(filePath string, config appConfig, homePages map[string]string) string {
+	relPath, err := filepath.Rel(deployDirName, filePath)
+	check(err)
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	lang := ""
+	if len(parts) > 1 && len(config.languages) > 0 && parts[0] != config.languages[0] && slices.Contains(config.languages, parts[0]) {
+		lang = parts[0]
+		parts = parts[1:]
+	}
+	switch {
+	case len(parts) == 1 && parts[0] == indexPageFileName:
+		if config.homePage == "" {
+			return ""
+		}
+		homePage := config.homePage
+		if id, ok := homePages[contentLanguage(lang, config)]; ok {
+			homePage = id
+		}
+		return markdownPagesDirName + "/" + homePage + markdownFileExtension
+	case len(parts) == 2 && parts[0] == deployPostDirName:
+		return markdownPostsDirName + "/" + strings.TrimSuffix(parts[1], contentFileExtension) + markdownFileExtension
+	case len(parts) == 2 && parts[0] == deployPageDirName:
+		return markdownPagesDirName + "/" + strings.TrimSuffix(parts[1], contentFileExtension) + markdownFileExtension
+	}
+	return ""
+}
+
+// reportBrokenLinks prints out the broken links found (grouped by source, sorted),
+// returning the number of the broken links
+func reportBrokenLinks(broken brokenLinks) int {
+	if len(broken) == 0 {
+		return 0
+	}
+	sources := make([]string, 0, len(broken))
+	cnt := 0
+	for source, links := range broken {
+		sources = append(sources, source)
+		cnt += len(links)
+	}
+	sort.Strings(sources)
+	sprintln(" - broken links found:")
+	for _, source := range sources {
+		println("   - " + source + ":")
+		for _, link := range broken[source] {
+			println("     - " + link)
+		}
+	}
+	return cnt
+}
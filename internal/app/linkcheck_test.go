@@ -0,0 +1,84 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestResolveInternalLink(t *testing.T) {
+	filePath := filepath.Join(deployDirName, deployPostDirName, "hello.html")
+	for _, link := range []string{"", "#top", "https://example.com/", "//cdn.example.com/x.js", "mailto:me@example.com", "data:image/png;base64,xyz", "?page=2"} {
+		if _, ok := resolveInternalLink(filePath, link); ok {
+			t.Errorf("expected link to be skipped: %q", link)
+		}
+	}
+	target, ok := resolveInternalLink(filePath, "/tags/go/?x=1#list")
+	if !ok {
+		t.Fatal("expected a root-relative link to be resolved")
+	}
+	verifyStringsEqual(filepath.ToSlash(target), "deploy/tags/go/index.html", t)
+	target, _ = resolveInternalLink(filePath, "../media/hello/my%20photo.jpg")
+	verifyStringsEqual(filepath.ToSlash(target), "deploy/media/hello/my photo.jpg", t)
+}
+
+func TestFindBrokenLinks(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "mbgen-check-links-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(origDir)
+
+	files := map[string]string{
+		"deploy/index.html":               `<a href="/post/hello.html">hello</a> <a href="/page/missing.html">x</a>`,
+		"deploy/post/hello.html":          `<a href="/post/missing.html">m</a> <img src="../media/hello/a.jpg"> <img src='/media/hello/gone.jpg'> <a href="/tags/go/">go</a> <img src="img/b.png">`,
+		"deploy/media/hello/a.jpg":        "",
+		"deploy/tags/go/index.html":       `<a href="/post/missing.html">m</a> <a href="/archive/">archive</a> <img src="img/b.png">`,
+		"deploy/de/index.html":            `<a href="/de/post/nope.html">nope</a>`,
+		"deploy/de/post/hallo.html":       `<a href="https://example.com/">ext</a> <a href="#top">top</a>`,
+		"deploy/resources/css/styles.css": `body { background: url("/missing.png"); }`,
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := defaultConfig()
+	config.languages = []string{"en", "de"}
+	config.homePage = "home"
+	broken := findBrokenLinks(config, map[string]string{"en": "home", "de": "startseite"})
+
+	expected := brokenLinks{
+		"pages/home.md":       {"/page/missing.html"},
+		"pages/startseite.md": {"/de/post/nope.html"},
+		"posts/hello.md":      {"/post/missing.html", "/media/hello/gone.jpg", "img/b.png"},
+		// the link already attributed to posts/hello.md is not reported again for the listing,
+		// unlike the same relative link resolving to another (missing) target there
+		"deploy/tags/go/index.html": {"/archive/", "img/b.png"},
+	}
+	if len(broken) != len(expected) {
+		t.Fatalf("expected %d sources, got: %v", len(expected), broken)
+	}
+	for source, links := range expected {
+		if !slices.Equal(broken[source], links) {
+			t.Errorf("%s: expected %v, got: %v", source, links, broken[source])
+		}
+	}
+	if cnt := reportBrokenLinks(broken); cnt != 7 {
+		t.Errorf("expected 7 broken links, got: %d", cnt)
+	}
+}
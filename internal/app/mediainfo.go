@@ -0,0 +1,168 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/disintegration/imaging"
+)
+
+// mediaInfo is the intrinsic info of a media file: its dimensions (and duration, for videos) along with
+// (optionally, for images) a tiny blurred placeholder and the dominant (average) colour,
+// recorded for the file size and modification time it has been read for
+type mediaInfo struct {
+	Size        int64   `json:"size"`
+	ModTime     int64   `json:"modTime"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	Duration    float64 `json:"duration,omitempty"`    // in seconds
+	Placeholder string  `json:"placeholder,omitempty"` // a base64 encoded JPEG data URI
+	Color       string  `json:"color,omitempty"`       // `#rrggbb`
+	CaptureTime string  `json:"captureTime,omitempty"` // the EXIF (original) date/time (`YYYY-MM-DDTHH:MM:SS`)
+	Camera      string  `json:"camera,omitempty"`      // the EXIF camera make and model
+	NoWebP      bool    `json:"noWebP,omitempty"`      // the (lossless) WebP variant isn't smaller than the image (see writeWebPVariant)
+}
+
+// recordedMediaInfo is the media info recorded by the last build (see the build cache manifest) along with the one
+// read since, reused by the commands parsing the content outside of the generate runs (e.g. inspect, stats, cleanup,
+// or serve), loaded on first use
+var recordedMediaInfo struct {
+	once  sync.Once
+	cache *syncCache[string, mediaInfo]
+}
+
+func getRecordedMediaInfo() *syncCache[string, mediaInfo] {
+	recordedMediaInfo.once.Do(func() {
+		recordedMediaInfo.cache = newSyncCache[string, mediaInfo]()
+		maps.Copy(recordedMediaInfo.cache.m, readBuildManifestMedia())
+	})
+	return recordedMediaInfo.cache
+}
+
+// getMediaInfo returns the info of the given media file (the zero value if it can't be read):
+// the one recorded by the previous build is reused (see the build cache) as long as the file hasn't changed since,
+// so that the media files are only read once they're added or modified
+func getMediaInfo(mediaFilePath string, placeholder bool) mediaInfo {
+	fi, err := os.Stat(mediaFilePath)
+	if err != nil {
+		return mediaInfo{}
+	}
+	size, modTime := fi.Size(), fi.ModTime().UnixNano()
+	var recorded mediaInfo
+	var ok bool
+	if activeBuildCache != nil {
+		if recorded, ok = activeBuildCache.media.get(mediaFilePath); !ok {
+			recorded, ok = activeBuildCache.prevMedia[mediaFilePath]
+		}
+	} else {
+		recorded, ok = getRecordedMediaInfo().get(mediaFilePath)
+	}
+	sameFile := ok && recorded.Size == size && recorded.ModTime == modTime
+	if sameFile && (!placeholder || recorded.Placeholder != "") {
+		if activeBuildCache != nil {
+			activeBuildCache.media.set(mediaFilePath, recorded)
+		}
+		return recorded
+	}
+	var info mediaInfo
+	if slices.Contains(videoFileExtensions, strings.ToLower(filepath.Ext(mediaFilePath))) {
+		info, err = readVideoInfo(mediaFilePath)
+	} else {
+		info, err = readImageInfo(mediaFilePath, placeholder)
+	}
+	if err != nil {
+		sprintln(" - error reading media file info: "+mediaFilePath, err)
+		return mediaInfo{}
+	}
+	info.Size, info.ModTime = size, modTime
+	// the info (re)read for the placeholder keeps the WebP variant record of the (unchanged) file
+	if sameFile {
+		info.NoWebP = recorded.NoWebP
+	}
+	recordMediaInfo(mediaFilePath, info)
+	return info
+}
+
+// recordMediaInfo records the info of the given media file: in the build cache during the generate runs
+// (persisted along with the build cache manifest), or along with the recorded info otherwise
+func recordMediaInfo(mediaFilePath string, info mediaInfo) {
+	if activeBuildCache != nil {
+		activeBuildCache.media.set(mediaFilePath, info)
+	} else {
+		getRecordedMediaInfo().set(mediaFilePath, info)
+	}
+}
+
+// readImageInfo reads the EXIF metadata and the dimensions of the given image file (from its header only,
+// unless the placeholder is requested, in which case the image is decoded to compute both the placeholder
+// and the dominant colour), with the EXIF orientation applied
+func readImageInfo(imgFilePath string, placeholder bool) (mediaInfo, error) {
+	f, err := os.Open(imgFilePath)
+	if err != nil {
+		return mediaInfo{}, err
+	}
+	defer f.Close()
+	header, err := io.ReadAll(io.LimitReader(f, maxImgHeaderSize))
+	if err != nil {
+		return mediaInfo{}, err
+	}
+	exif := readImgExif(header)
+	var info mediaInfo
+	if !exif.captureTime.IsZero() {
+		info.CaptureTime = exif.captureTime.String()
+	}
+	info.Camera = exif.camera
+	if !placeholder {
+		cfg, _, err := image.DecodeConfig(io.MultiReader(bytes.NewReader(header), f))
+		if err != nil {
+			return mediaInfo{}, err
+		}
+		info.Width, info.Height = cfg.Width, cfg.Height
+		if exif.swapsDimensions() {
+			info.Width, info.Height = cfg.Height, cfg.Width
+		}
+		return info, nil
+	}
+	img, err := imaging.Open(imgFilePath, imaging.AutoOrientation(true))
+	if err != nil {
+		return mediaInfo{}, err
+	}
+	info.Width, info.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	var buf bytes.Buffer
+	// the placeholder is meant to be stretched (and further blurred) by the browser, so a few pixels are enough
+	phImg := imaging.Blur(imaging.Fit(img, imgPlaceholderSize, imgPlaceholderSize, imaging.Box), 0.5)
+	if err := imaging.Encode(&buf, phImg, imaging.JPEG, imaging.JPEGQuality(imgPlaceholderJPEGQuality)); err != nil {
+		return mediaInfo{}, err
+	}
+	info.Placeholder = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	c := imaging.Resize(img, 1, 1, imaging.Box).NRGBAAt(0, 0)
+	info.Color = fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	return info, nil
+}
+
+// readVideoInfo reads the duration and the dimensions of the given video file from its container metadata
+// (MP4/MOV only: the info of the other video files is left empty)
+func readVideoInfo(videoFilePath string) (mediaInfo, error) {
+	if !slices.Contains(mp4VideoFileExtensions, strings.ToLower(filepath.Ext(videoFilePath))) {
+		return mediaInfo{}, nil
+	}
+	f, err := os.Open(videoFilePath)
+	if err != nil {
+		return mediaInfo{}, err
+	}
+	defer f.Close()
+	mi, err := readMP4Info(f)
+	if err != nil {
+		return mediaInfo{}, err
+	}
+	return mediaInfo{Width: mi.width, Height: mi.height, Duration: mi.duration}, nil
+}
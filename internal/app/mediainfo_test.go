@@ -0,0 +1,153 @@
+package app
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMediaInfo(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	mediaDirPath := filepath.Join(deployDirName, mediaDirName, "post", "p")
+	check(os.MkdirAll(mediaDirPath, 0755))
+	img := image.NewNRGBA(image.Rect(0, 0, 600, 400))
+	for x := 0; x < 600; x++ {
+		for y := 0; y < 400; y++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	imgFilePath := filepath.Join(mediaDirPath, "a.png")
+	f, err := os.Create(imgFilePath)
+	check(err)
+	check(png.Encode(f, img))
+	check(f.Close())
+	check(os.WriteFile(configFileName, []byte("theme: test\n"), 0o644))
+
+	config := defaultConfig()
+	config.thumbSizes = []int{300, 960}
+	config.thumbThreshold = 0
+	processImgThumbnails(mediaDirPath, config)
+
+	// dimensions only
+	m := buildMediaItem("a.png", "post/p", filepath.Join("post", "p"), config)
+	if m.Width() != 600 || m.Height() != 400 || !m.HasDimensions() {
+		t.Errorf("unexpected image dimensions: %dx%d", m.Width(), m.Height())
+	}
+	if m.ThumbWidth(1) != 300 || m.ThumbHeight(1) != 200 {
+		t.Errorf("unexpected thumbnail dimensions: %dx%d", m.ThumbWidth(1), m.ThumbHeight(1))
+	}
+	// no thumbnail larger than the image itself: the original one is used instead
+	if m.ThumbWidth(2) != 600 || m.ThumbHeight(2) != 400 {
+		t.Errorf("unexpected fallback thumbnail dimensions: %dx%d", m.ThumbWidth(2), m.ThumbHeight(2))
+	}
+	if m.Placeholder() != "" || m.Color() != "" {
+		t.Error("expected no placeholder unless enabled")
+	}
+
+	// placeholders
+	config.imgPlaceholders = true
+	loadBuildCache(config, false)
+	m = buildMediaItem("a.png", "post/p", filepath.Join("post", "p"), config)
+	if !strings.HasPrefix(m.Placeholder(), "data:image/jpeg;base64,") {
+		t.Errorf("unexpected image placeholder: %s", m.Placeholder())
+	}
+	verifyStringsEqual(m.Color(), "#c86432", t)
+	saveBuildCache()
+
+	// the cached info is reused as long as the image file hasn't changed
+	loadBuildCache(config, false)
+	info, ok := activeBuildCache.prevMedia[imgFilePath]
+	if !ok || info.Width != 600 || info.Placeholder == "" {
+		t.Fatalf("expected the image info to be carried over: %+v", info)
+	}
+	info.Color = "#000000"
+	activeBuildCache.prevMedia[imgFilePath] = info
+	verifyStringsEqual(getMediaInfo(imgFilePath, true).Color, "#000000", t)
+	saveBuildCache()
+
+	// full rebuild: nothing is reused
+	loadBuildCache(config, true)
+	if len(activeBuildCache.prevMedia) != 0 {
+		t.Error("expected no cached image info on a full rebuild")
+	}
+	verifyStringsEqual(getMediaInfo(imgFilePath, true).Color, "#c86432", t)
+	saveBuildCache()
+
+	// the recorded info is reused outside of the generate runs as well
+	manifestFilePath := filepath.Join(buildCacheDirName, buildCacheManifestFileName)
+	var manifest buildManifest
+	check(json.Unmarshal(readDataFromFile(manifestFilePath), &manifest))
+	info = manifest.Media[imgFilePath]
+	info.Color = "#000000"
+	manifest.Media[imgFilePath] = info
+	data, err := json.Marshal(manifest)
+	check(err)
+	writeDataToFile(manifestFilePath, data)
+	recordedMediaInfo.once = sync.Once{}
+	verifyStringsEqual(getMediaInfo(imgFilePath, true).Color, "#000000", t)
+	check(os.Chtimes(imgFilePath, time.Now(), time.Now()))
+	verifyStringsEqual(getMediaInfo(imgFilePath, true).Color, "#c86432", t)
+}
+
+func TestVideoPosterAndInfo(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	mediaDirPath := filepath.Join(deployDirName, mediaDirName, "post", "p")
+	check(os.MkdirAll(mediaDirPath, 0755))
+	check(os.WriteFile(filepath.Join(mediaDirPath, "clip.mp4"), mp4TestFile(1000, 83500, 1280, 720), 0644))
+	check(os.WriteFile(filepath.Join(mediaDirPath, "other.mkv"), []byte("not parsed"), 0644))
+	poster := image.NewNRGBA(image.Rect(0, 0, 64, 36))
+	f, err := os.Create(filepath.Join(mediaDirPath, "clip.mp4.poster.png"))
+	check(err)
+	check(png.Encode(f, poster))
+	check(f.Close())
+
+	config := defaultConfig()
+	allMedia := listAllMedia(Post, "p", nil)
+	if len(allMedia) != 2 || allMedia[0] != "clip.mp4" || allMedia[1] != "other.mkv" {
+		t.Errorf("expected the video poster not to be listed as a media item, got: %v", allMedia)
+	}
+	mediaItems := parseMediaFileNames(allMedia, Post, "p", config, false, nil, nil)
+	if len(mediaItems) != 2 {
+		t.Fatalf("expected 2 media items, got: %d", len(mediaItems))
+	}
+	m := mediaItems[0]
+	verifyStringsEqual(m.Poster(), "/media/post/p/clip.mp4.poster.png", t)
+	verifyStringsEqual(m.DurationString(), "1:24", t)
+	if m.Width() != 1280 || m.Height() != 720 {
+		t.Errorf("unexpected video dimensions: %dx%d", m.Width(), m.Height())
+	}
+	// no container metadata read for MKV videos
+	m = mediaItems[1]
+	if m.Poster() != "" || m.Duration() != 0 || m.HasDimensions() {
+		t.Errorf("unexpected video info: %+v", m)
+	}
+	// explicitly referenced posters are skipped too
+	if mediaItems := parseMediaFileNames([]string{"clip.mp4.poster.png"}, Post, "p", config, true, nil, nil); len(mediaItems) != 0 {
+		t.Errorf("expected no media items, got: %+v", mediaItems)
+	}
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"strings"
+	"time"
+)
+
+type jsonLDOrganization struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+type jsonLDDocument struct {
+	Context          string              `json:"@context"`
+	Type             string              `json:"@type"`
+	Headline         string              `json:"headline,omitempty"`
+	Name             string              `json:"name,omitempty"`
+	Description      string              `json:"description,omitempty"`
+	URL              string              `json:"url,omitempty"`
+	MainEntityOfPage string              `json:"mainEntityOfPage,omitempty"`
+	Image            string              `json:"image,omitempty"`
+	DatePublished    string              `json:"datePublished,omitempty"`
+	DateModified     string              `json:"dateModified,omitempty"`
+	Keywords         string              `json:"keywords,omitempty"`
+	InLanguage       string              `json:"inLanguage,omitempty"`
+	Publisher        *jsonLDOrganization `json:"publisher,omitempty"`
+}
+
+// toPlainText converts the given markdown content to plain text (markup stripped, whitespace collapsed)
+func toPlainText(markdownContent string) string {
+	var buf bytes.Buffer
+	err := markdown.Convert([]byte(markdownContent), &buf)
+	check(err)
+	return htmlToPlainText(buf.String())
+}
+
+// htmlToPlainText strips all the tags from the given HTML content,
+// unescapes the HTML entities and collapses whitespace
+func htmlToPlainText(htmlContent string) string {
+	text := html.UnescapeString(htmlTagRegexp.ReplaceAllString(htmlContent, " "))
+	return strings.TrimSpace(whitespacePlaceholderRegexp.ReplaceAllString(text, " "))
+}
+
+func formatMetaDataTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// buildEntityMetaData fills in the values common to posts and pages, including the JSON-LD document
+func buildEntityMetaData(meta entityMetaData, ceType contentEntityType, ceId string, uri string, keywords []string, config appConfig) *entityMetaData {
+	meta.SiteName = config.siteName
+	if meta.Title == "" {
+		meta.Title = config.siteName
+	}
+	if config.siteBaseURL != "" {
+		meta.CanonicalURL = config.siteBaseURL + uri
+		if image := getLeadImage(ceType, ceId, config); image != nil {
+			meta.Image = config.siteBaseURL + image.Uri
+		}
+	}
+	doc := jsonLDDocument{
+		Context:       schemaOrgContext,
+		Description:   meta.Description,
+		URL:           meta.CanonicalURL,
+		Image:         meta.Image,
+		DatePublished: meta.PublishedTime,
+		DateModified:  meta.ModifiedTime,
+		Keywords:      strings.Join(keywords, ", "),
+		InLanguage:    config.lang,
+	}
+	if ceType == Post {
+		doc.Type = schemaOrgTypeBlogPosting
+		doc.Headline = meta.Title
+		doc.MainEntityOfPage = meta.CanonicalURL
+	} else {
+		doc.Type = schemaOrgTypeWebPage
+		doc.Name = meta.Title
+	}
+	if config.siteName != "" {
+		doc.Publisher = &jsonLDOrganization{Type: schemaOrgTypeOrganization, Name: config.siteName}
+	}
+	// json.Marshal escapes `<`, `>` and `&`, so the document is safe to embed into a script tag
+	jsonLD, err := json.Marshal(doc)
+	check(err)
+	meta.JSONLD = string(jsonLD)
+	return &meta
+}
+
+// buildPostMetaData builds the structured metadata of the given single post
+func buildPostMetaData(p post, config appConfig) *entityMetaData {
+	meta := entityMetaData{
+		Type:          ogTypeArticle,
+		Title:         htmlToPlainText(p.Title),
+		Description:   p.Description,
+		PublishedTime: formatMetaDataTime(p.publishTime()),
+		ModifiedTime:  formatMetaDataTime(postLastMod(p)),
+	}
+	if meta.Title == "" && p.HasDateOrTime() {
+		meta.Title = strings.TrimSpace(p.FmtDate() + " " + p.FmtTime())
+	}
+	if meta.Description == "" {
+		meta.Description = toPlainText(buildExcerptMarkdown(p.FeedContent))
+	}
+	meta.Alternates = buildHreflangAlternates(p.Translations, config)
+	uri := langURIPrefix(config.lang, config) + "/" + deployPostDirName + "/" + p.Id + contentFileExtension
+	return buildEntityMetaData(meta, Post, p.Id, uri, p.Tags, config)
+}
+
+// buildPageMetaData builds the structured metadata of the given single page
+func buildPageMetaData(p page, config appConfig) *entityMetaData {
+	meta := entityMetaData{
+		Type:         ogTypeWebsite,
+		Title:        htmlToPlainText(p.Title),
+		Description:  p.Description,
+		ModifiedTime: formatMetaDataTime(p.modTime),
+	}
+	if meta.Description == "" {
+		meta.Description = toPlainText(buildExcerptMarkdown(p.excerptContent))
+	}
+	meta.Alternates = buildHreflangAlternates(p.Translations, config)
+	uri := langURIPrefix(config.lang, config) + "/" + deployPageDirName + "/" + p.Id + contentFileExtension
+	if p.Id == config.homePage {
+		uri = langURIPrefix(config.lang, config) + "/"
+		if meta.Description == "" {
+			meta.Description = config.siteDescription
+		}
+	}
+	return buildEntityMetaData(meta, Page, p.Id, uri, nil, config)
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+func TestPostMetaData(t *testing.T) {
+	config := defaultConfig()
+	config.siteName = testSiteName
+	config.siteBaseURL = "https://example.com"
+
+	p := post{
+		Id:          "hello",
+		Title:       "Hello <em>World</em>",
+		FeedContent: "First **sentence** here. Second one follows.",
+		Date:        civil.Date{Year: 2024, Month: 3, Day: 5},
+		Tags:        []string{"Go", "Misc"},
+	}
+	meta := buildPostMetaData(p, config)
+	verifyStringsEqual(meta.Type, ogTypeArticle, t)
+	verifyStringsEqual(meta.Title, "Hello World", t)
+	verifyStringsEqual(meta.Description, "First sentence here. Second one follows.", t)
+	verifyStringsEqual(meta.CanonicalURL, "https://example.com/post/hello.html", t)
+	verifyStringsEqual(meta.PublishedTime, civil.DateTime{Date: p.Date}.In(time.Local).Format(time.RFC3339), t)
+	for _, expected := range []string{
+		`"@context":"https://schema.org"`,
+		`"@type":"BlogPosting"`,
+		`"headline":"Hello World"`,
+		`"mainEntityOfPage":"https://example.com/post/hello.html"`,
+		`"keywords":"Go, Misc"`,
+		`"publisher":{"@type":"Organization","name":"` + testSiteName + `"}`,
+	} {
+		verifyStringContains(meta.JSONLD, expected, t)
+	}
+
+	p.Description = "Custom description"
+	verifyStringsEqual(buildPostMetaData(p, config).Description, "Custom description", t)
+
+	config.siteBaseURL = ""
+	if meta := buildPostMetaData(p, config); meta.CanonicalURL != "" || strings.Contains(meta.JSONLD, `"url"`) {
+		t.Error("canonical URL should be omitted without siteBaseURL")
+	}
+}
+
+func TestPageMetaData(t *testing.T) {
+	config := defaultConfig()
+	config.siteName = testSiteName
+	config.siteBaseURL = "https://example.com"
+	config.homePage = "home"
+
+	meta := buildPageMetaData(page{Id: "about", Title: "About", excerptContent: "About *this* site."}, config)
+	verifyStringsEqual(meta.Type, ogTypeWebsite, t)
+	verifyStringsEqual(meta.CanonicalURL, "https://example.com/page/about.html", t)
+	verifyStringsEqual(meta.Description, "About this site.", t)
+	verifyStringContains(meta.JSONLD, `"@type":"WebPage"`, t)
+	verifyStringContains(meta.JSONLD, `"name":"About"`, t)
+
+	meta = buildPageMetaData(page{Id: "home"}, config)
+	verifyStringsEqual(meta.CanonicalURL, "https://example.com/", t)
+	verifyStringsEqual(meta.Title, testSiteName, t)
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// mp4Info is the basic metadata of an MP4/MOV (ISO base media / QuickTime file format) container
+type mp4Info struct {
+	duration float64 // in seconds
+	width    int     // the dimensions of the (first) video track
+	height   int
+}
+
+var errMP4NoMovieBox = errors.New("no movie metadata (moov box) found")
+
+// readMP4Info reads the basic metadata of an MP4/MOV file from its movie box (`moov`):
+// the top-level boxes are skipped over (the media data in particular), so only the movie box itself is read
+func readMP4Info(r io.ReadSeeker) (mp4Info, error) {
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF {
+				return mp4Info{}, errMP4NoMovieBox
+			}
+			return mp4Info{}, err
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[:4]))
+		boxType := string(hdr[4:8])
+		hdrLen := int64(8)
+		if size == 1 {
+			// 64-bit box size
+			var largeSize [8]byte
+			if _, err := io.ReadFull(r, largeSize[:]); err != nil {
+				return mp4Info{}, err
+			}
+			size = int64(binary.BigEndian.Uint64(largeSize[:]))
+			hdrLen = 16
+		}
+		if size != 0 && size < hdrLen {
+			return mp4Info{}, fmt.Errorf("invalid %q box size: %d", boxType, size)
+		}
+		if boxType == "moov" {
+			var data []byte
+			var err error
+			if size == 0 {
+				// the last box, extending to the end of the file
+				data, err = io.ReadAll(io.LimitReader(r, maxMP4MovieBoxSize))
+			} else if size-hdrLen > maxMP4MovieBoxSize {
+				return mp4Info{}, fmt.Errorf("the movie box is too large: %d bytes", size)
+			} else {
+				data = make([]byte, size-hdrLen)
+				_, err = io.ReadFull(r, data)
+			}
+			if err != nil {
+				return mp4Info{}, err
+			}
+			return parseMP4MovieBox(data), nil
+		}
+		if size == 0 {
+			return mp4Info{}, errMP4NoMovieBox
+		}
+		if _, err := r.Seek(size-hdrLen, io.SeekCurrent); err != nil {
+			return mp4Info{}, err
+		}
+	}
+}
+
+// parseMP4MovieBox reads the duration from the movie header (`mvhd`) and the dimensions from the header (`tkhd`)
+// of the first video track (the one with the `vide` media handler)
+func parseMP4MovieBox(moov []byte) mp4Info {
+	var info mp4Info
+	forEachMP4Box(moov, func(boxType string, body []byte) {
+		switch boxType {
+		case "mvhd":
+			var timescale, duration uint64
+			if len(body) >= 32 && body[0] == 1 {
+				timescale = uint64(binary.BigEndian.Uint32(body[20:24]))
+				duration = binary.BigEndian.Uint64(body[24:32])
+			} else if len(body) >= 20 {
+				timescale = uint64(binary.BigEndian.Uint32(body[12:16]))
+				duration = uint64(binary.BigEndian.Uint32(body[16:20]))
+			}
+			if timescale > 0 {
+				info.duration = float64(duration) / float64(timescale)
+			}
+		case "trak":
+			if info.width > 0 && info.height > 0 {
+				return
+			}
+			var width, height int
+			video := false
+			forEachMP4Box(body, func(boxType string, body []byte) {
+				switch boxType {
+				case "tkhd":
+					// the (16.16 fixed-point) track width and height conclude the track header
+					if len(body) >= 84 {
+						width = int(binary.BigEndian.Uint32(body[len(body)-8:]) >> 16)
+						height = int(binary.BigEndian.Uint32(body[len(body)-4:]) >> 16)
+					}
+				case "mdia":
+					forEachMP4Box(body, func(boxType string, body []byte) {
+						if boxType == "hdlr" && len(body) >= 12 && string(body[8:12]) == "vide" {
+							video = true
+						}
+					})
+				}
+			})
+			if video {
+				info.width, info.height = width, height
+			}
+		}
+	})
+	return info
+}
+
+// forEachMP4Box calls the given function for each of the boxes contained in the given data
+// (stopping at the first malformed one)
+func forEachMP4Box(data []byte, fn func(boxType string, body []byte)) {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[:4]))
+		boxType := string(data[4:8])
+		hdrLen := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			hdrLen = 16
+		}
+		if size < hdrLen || size > uint64(len(data)) {
+			return
+		}
+		fn(boxType, data[hdrLen:size])
+		data = data[size:]
+	}
+}
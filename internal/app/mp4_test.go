@@ -0,0 +1,81 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func mp4Box(boxType string, body ...[]byte) []byte {
+	data := bytes.Join(body, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	return append(append(box, boxType...), data...)
+}
+
+// mp4TestFile builds a minimal MP4 file: an audio track followed by a video one, with the movie box after the media data
+func mp4TestFile(timescale uint32, duration uint32, width int, height int) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], timescale)
+	binary.BigEndian.PutUint32(mvhd[16:20], duration)
+	track := func(handler string, width int, height int) []byte {
+		tkhd := make([]byte, 84)
+		binary.BigEndian.PutUint32(tkhd[76:80], uint32(width)<<16)
+		binary.BigEndian.PutUint32(tkhd[80:84], uint32(height)<<16)
+		hdlr := make([]byte, 24)
+		copy(hdlr[8:12], handler)
+		return mp4Box("trak", mp4Box("tkhd", tkhd), mp4Box("mdia", mp4Box("mdhd", make([]byte, 24)), mp4Box("hdlr", hdlr)))
+	}
+	return bytes.Join([][]byte{
+		mp4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2mp41")),
+		mp4Box("mdat", make([]byte, 4096)),
+		mp4Box("moov", mp4Box("mvhd", mvhd), track("soun", 0, 0), track("vide", width, height)),
+	}, nil)
+}
+
+func TestReadMP4Info(t *testing.T) {
+	info, err := readMP4Info(bytes.NewReader(mp4TestFile(1000, 83500, 1280, 720)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.duration != 83.5 || info.width != 1280 || info.height != 720 {
+		t.Errorf("unexpected MP4 info: %+v", info)
+	}
+
+	// 64-bit box size
+	data := mp4TestFile(600, 1200, 640, 480)
+	moovIdx := bytes.Index(data, []byte("moov")) - 4
+	moov := data[moovIdx:]
+	largeMoov := append(binary.BigEndian.AppendUint32(nil, 1), "moov"...)
+	largeMoov = binary.BigEndian.AppendUint64(largeMoov, uint64(len(moov)+8))
+	largeMoov = append(largeMoov, moov[8:]...)
+	info, err = readMP4Info(bytes.NewReader(append(data[:moovIdx:moovIdx], largeMoov...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.duration != 2 || info.width != 640 || info.height != 480 {
+		t.Errorf("unexpected MP4 info: %+v", info)
+	}
+
+	// no movie box
+	if _, err := readMP4Info(bytes.NewReader(data[:moovIdx])); err != errMP4NoMovieBox {
+		t.Errorf("expected the missing movie box error, got: %v", err)
+	}
+	// truncated movie box
+	if _, err := readMP4Info(bytes.NewReader(data[:len(data)-10])); err == nil {
+		t.Error("expected an error for a truncated movie box")
+	}
+}
+
+func TestMediaDurationString(t *testing.T) {
+	for _, tc := range []struct {
+		duration float64
+		expected string
+	}{
+		{0, ""},
+		{4.4, "0:04"},
+		{83.5, "1:24"},
+		{3725, "1:02:05"},
+	} {
+		verifyStringsEqual(media{Type: Video, duration: tc.duration}.DurationString(), tc.expected, t)
+	}
+}
@@ -19,7 +19,7 @@ import (
 	"time"
 )
 
-func listenAndServe(addr string, admin bool, watch chan watchReloadData, config appConfig, resLoader resourceLoader) {
+func listenAndServe(addr string, admin bool, preview bool, watch chan watchReloadData, config appConfig, resLoader resourceLoader) {
 	if !dirExists(deployDirName) {
 		exitWithError(deployDirName + " directory not found")
 	}
@@ -98,9 +98,17 @@ func listenAndServe(addr string, admin bool, watch chan watchReloadData, config
 			if !specificResourceRequested {
 				filePath += indexPageFileName
 			}
+			var data []byte
 			if fileExists(filePath) {
-				data, err := os.ReadFile(filePath)
-				check(err)
+				data = readDataFromFile(filePath)
+			} else if preview {
+				// posts pending publication are never generated, render those on the fly instead
+				if postFileName, ok := strings.CutPrefix(path, "/"+deployPostDirName+"/"); ok && !strings.Contains(postFileName, "/") {
+					data = renderPendingPostPreview(strings.TrimSuffix(postFileName, contentFileExtension), config, resLoader)
+				}
+			}
+			if data != nil {
+				var err error
 				if strings.HasSuffix(filePath, contentFileExtension) {
 					html := string(data)
 					if admin {
@@ -185,7 +193,16 @@ func listenAndServe(addr string, admin bool, watch chan watchReloadData, config
 				writeDataToFile(mdContentFilePath, body)
 				processAndHandleStats(config, resLoader, true)
 				contentFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, ceType, os.PathSeparator, ceId+contentFileExtension)
-				content := readDataFromFile(contentFilePath)
+				var content []byte
+				if fileExists(contentFilePath) {
+					content = readDataFromFile(contentFilePath)
+				} else if ceType == deployPostDirName {
+					content = renderPendingPostPreview(ceId, config, resLoader)
+				}
+				if content == nil {
+					http.Error(writer, "Not found: "+ceType+"/"+ceId, http.StatusNotFound)
+					return
+				}
 				content = content[strings.Index(string(content), mainOpeningTag)+len(mainOpeningTag):]
 				content = content[:strings.Index(string(content), mainClosingTag)]
 				_, err = writer.Write(content)
@@ -369,7 +386,7 @@ func listenAndServe(addr string, admin bool, watch chan watchReloadData, config
 }
 
 func listMediaResponse(writer http.ResponseWriter, mediaFileNames []string, ceType contentEntityType, ceId string, config appConfig, resLoader resourceLoader) {
-	allMedia := parseMediaFileNames(mediaFileNames, ceType, ceId, config, false, nil)
+	allMedia := parseMediaFileNames(mediaFileNames, ceType, ceId, config, false, nil, nil)
 	if allMedia != nil {
 		inlineMediaTemplate := compileMediaTemplate(resLoader)
 		var inlineMediaMarkupBuffer bytes.Buffer
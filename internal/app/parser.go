@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"html"
+	"maps"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -11,6 +12,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/civil"
 	"github.com/google/uuid"
@@ -21,22 +24,35 @@ import (
 	gmhtml "github.com/yuin/goldmark/renderer/html"
 )
 
-var mainTemplateMarkup /* const */ string
-
-var markdown = /* const */ goldmark.New(
-	goldmark.WithExtensions(
-		meta.Meta,
-		extension.Strikethrough,
-		extension.DefinitionList,
-		extension.Table,
-		extension.Linkify,
-		// TODO: support an option to include extension.CJK (?)
-	),
-	goldmark.WithRendererOptions(
-		gmhtml.WithHardWraps(),
-	),
+var (
+	mainTemplateMarkup/* const */ string
+	mainTemplateMarkupMutex sync.Mutex
 )
 
+var markdown = /* const */ newMarkdown()
+
+// newMarkdown creates a markdown converter with the common set of extensions, along with the given additional ones
+func newMarkdown(extensions ...goldmark.Extender) goldmark.Markdown {
+	return goldmark.New(
+		goldmark.WithExtensions(
+			append([]goldmark.Extender{
+				meta.Meta,
+				extension.Strikethrough,
+				extension.DefinitionList,
+				extension.Table,
+				extension.Linkify,
+				// TODO: support an option to include extension.CJK (?)
+			}, extensions...)...,
+		),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+		),
+		goldmark.WithRendererOptions(
+			gmhtml.WithHardWraps(),
+		),
+	)
+}
+
 func parsePages(config appConfig, resLoader resourceLoader, thumbHandler imageThumbnailHandler, useCache bool) []page {
 	if !dirExists(markdownPagesDirName) {
 		return nil
@@ -51,9 +67,10 @@ func parsePages(config appConfig, resLoader resourceLoader, thumbHandler imageTh
 
 	sprintln(" - parsing pages ...")
 
-	var pages []page
-	for _, pageEntry := range markdownPageDirEntries {
-		pageEntryInfo, err := pageEntry.Info()
+	// the pages are parsed (and their thumbnails generated) in parallel, then collected in the dir entry order
+	parsedPages := make([]*page, len(markdownPageDirEntries))
+	forEachParallel(len(markdownPageDirEntries), workerPoolSize(config), func(i int) {
+		pageEntryInfo, err := markdownPageDirEntries[i].Info()
 		check(err)
 		if !pageEntryInfo.IsDir() {
 			pageEntryFileName := pageEntryInfo.Name()
@@ -63,8 +80,8 @@ func parsePages(config appConfig, resLoader resourceLoader, thumbHandler imageTh
 				if ce != nil {
 					page := ce.(page)
 					page.skipProcessing = true
-					pages = append(pages, page)
-					continue
+					parsedPages[i] = &page
+					return
 				}
 			}
 			pageEntryPath := fmt.Sprintf("%s%c%s", markdownPagesDirName, os.PathSeparator, pageEntryFileName)
@@ -72,19 +89,45 @@ func parsePages(config appConfig, resLoader resourceLoader, thumbHandler imageTh
 			check(err)
 			pageId := pageEntryFileName[:len(pageEntryFileName)-len(filepath.Ext(pageEntryFileName))]
 			pageMediaDirPath := fmt.Sprintf("%s%c%s%c%s%c%s", deployDirName, os.PathSeparator, mediaDirName, os.PathSeparator, deployPageDirName, os.PathSeparator, pageId)
+			// the thumbnails are handled regardless of the build cache, so that the deleted ones get regenerated
+			// (they're left out of the media listing the build cache hash is computed with)
 			handleThumbnails(pageMediaDirPath, config, thumbHandler)
+			buildCacheHash := contentEntityBuildCacheHash(content, pageMediaDirPath)
+			if ce := getContentEntityFromBuildCache(Page, pageEntryFileName, buildCacheHash); ce != nil {
+				page := ce.(page)
+				page.modTime = pageEntryModTime
+				parsedPages[i] = &page
+				return
+			}
 			page := parsePage(pageId, string(content), config, resLoader)
+			page.modTime = pageEntryModTime
+			addContentEntityToBuildCache(pageEntryFileName, buildCacheHash, page)
 			if useCache {
 				addContentEntityToCache(pageEntryFileName, pageEntryModTime, page)
 			}
-			pages = append(pages, page)
+			parsedPages[i] = &page
+		}
+	})
+
+	var pages []page
+	for _, page := range parsedPages {
+		if page != nil {
+			pages = append(pages, *page)
 		}
 	}
 
 	return pages
 }
 
+// parsePosts parses the posts to be published, leaving out the ones pending publication
+// (drafts and posts scheduled in the future); see parseAllPosts to get those as well
 func parsePosts(config appConfig, resLoader resourceLoader, thumbHandler imageThumbnailHandler, useCache bool) []post {
+	posts, _ := splitPendingPosts(parseAllPosts(config, resLoader, thumbHandler, useCache), time.Now())
+	return posts
+}
+
+// parseAllPosts parses all the posts, including the ones pending publication
+func parseAllPosts(config appConfig, resLoader resourceLoader, thumbHandler imageThumbnailHandler, useCache bool) []post {
 	if !dirExists(markdownPostsDirName) {
 		return nil
 	}
@@ -102,9 +145,10 @@ func parsePosts(config appConfig, resLoader resourceLoader, thumbHandler imageTh
 
 	sprintln(" - parsing posts ...")
 
-	var posts []post
-	for _, postEntry := range markdownPostDirEntries {
-		postEntryInfo, err := postEntry.Info()
+	// the posts are parsed (and their thumbnails generated) in parallel, then collected in the dir entry order
+	parsedPosts := make([]*post, len(markdownPostDirEntries))
+	forEachParallel(len(markdownPostDirEntries), workerPoolSize(config), func(i int) {
+		postEntryInfo, err := markdownPostDirEntries[i].Info()
 		check(err)
 		if !postEntryInfo.IsDir() {
 			postEntryFileName := postEntryInfo.Name()
@@ -114,8 +158,8 @@ func parsePosts(config appConfig, resLoader resourceLoader, thumbHandler imageTh
 				if ce != nil {
 					post := ce.(post)
 					post.skipProcessing = true
-					posts = append(posts, post)
-					continue
+					parsedPosts[i] = &post
+					return
 				}
 			}
 			postEntryPath := fmt.Sprintf("%s%c%s", markdownPostsDirName, os.PathSeparator, postEntryFileName)
@@ -123,27 +167,48 @@ func parsePosts(config appConfig, resLoader resourceLoader, thumbHandler imageTh
 			check(err)
 			postId := postEntryFileName[:len(postEntryFileName)-len(filepath.Ext(postEntryFileName))]
 			postMediaDirPath := fmt.Sprintf("%s%c%s%c%s%c%s", deployDirName, os.PathSeparator, mediaDirName, os.PathSeparator, deployPostDirName, os.PathSeparator, postId)
+			// the thumbnails are handled regardless of the build cache, so that the deleted ones get regenerated
+			// (they're left out of the media listing the build cache hash is computed with)
 			handleThumbnails(postMediaDirPath, config, thumbHandler)
+			buildCacheHash := contentEntityBuildCacheHash(content, postMediaDirPath)
+			if ce := getContentEntityFromBuildCache(Post, postEntryFileName, buildCacheHash); ce != nil {
+				post := ce.(post)
+				post.modTime = postEntryModTime
+				parsedPosts[i] = &post
+				return
+			}
 			post := parsePost(postId, string(content), config, resLoader)
+			post.modTime = postEntryModTime
+			addContentEntityToBuildCache(postEntryFileName, buildCacheHash, post)
 			if useCache {
 				addContentEntityToCache(postEntryFileName, postEntryModTime, post)
 			}
-			posts = append(posts, post)
+			parsedPosts[i] = &post
+		}
+	})
+
+	var posts []post
+	for _, post := range parsedPosts {
+		if post != nil {
+			posts = append(posts, *post)
 		}
 	}
 
-	return posts
+	return sortPosts(posts, config)
 }
 
 func parsePage(pageId string, content string, config appConfig, resLoader resourceLoader) page {
-	page := page{Id: pageId}
-	content, rawBodyContent, cdPhReps, warnings := parseContentDirectives(Page, pageId, content, config, resLoader)
+	page := page{Id: pageId, ContentLinks: parseContentLinkRefs(content)}
+	content, rawBodyContent, cdPhReps, galleryMedia, warnings := parseContentDirectives(Page, pageId, content, config, resLoader)
+	page.excerptContent = rawBodyContent
+	page.GalleryMedia = galleryMedia
 	var buf bytes.Buffer
 	context := parser.NewContext()
-	err := markdown.Convert([]byte(content), &buf, parser.WithContext(context))
+	err := contentMarkdown(config).Convert([]byte(content), &buf, parser.WithContext(context))
 	check(err)
 	page.Body = strings.TrimSpace(buf.String())
 	page.Body = handleContentDirectivePlaceholderReplacements(page.Body, cdPhReps)
+	page.Body, warnings = renderHeadings(page.Body, resLoader, warnings)
 	page.Warnings = appendUnparsedDirectiveWarnings(warnings, page.Body)
 	metaData := meta.Get(context)
 	rawTitle := ""
@@ -154,9 +219,16 @@ func parsePage(pageId string, content string, config appConfig, resLoader resour
 		}
 		page.Title = title
 	}
+	if description, ok := metaData[metaDataKeyDescription].(string); ok {
+		page.Description = strings.TrimSpace(description)
+	}
 	if metaCollection, ok := metaData[metaDataKeyMetaCollection].(string); ok {
 		page.MetaCollection = strings.TrimSpace(metaCollection)
 	}
+	page.Lang = parseLanguageMetaData(metaData, config, &page.Warnings)
+	if translationOf, ok := metaData[metaDataKeyTranslationOf].(string); ok {
+		page.TranslationOf = strings.TrimSpace(translationOf)
+	}
 	// collect the (deduplicated) collection URIs embedded via {collection:...} directives
 	// — derived from the rendered body placeholders, which are deterministic and cache-safe
 	for _, m := range collectionDirectivePlaceholderRegexp.FindAllStringSubmatch(page.Body, -1) {
@@ -182,14 +254,17 @@ func parsePost(postId string, content string, config appConfig, resLoader resour
 		content = metaDataPlaceholderRegexp.ReplaceAllString(content, metadataContent)
 	}
 	// ================================================================================
-	content, rawBodyContent, cdPhReps, warnings := parseContentDirectives(Post, postId, content, config, resLoader)
+	post.ContentLinks = parseContentLinkRefs(content)
+	content, rawBodyContent, cdPhReps, galleryMedia, warnings := parseContentDirectives(Post, postId, content, config, resLoader)
 	post.FeedContent = rawBodyContent // store cleaned markdown for feed generation
+	post.GalleryMedia = galleryMedia
 	var buf bytes.Buffer
 	context := parser.NewContext()
-	err := markdown.Convert([]byte(content), &buf, parser.WithContext(context))
+	err := contentMarkdown(config).Convert([]byte(content), &buf, parser.WithContext(context))
 	check(err)
 	post.Body = strings.TrimSpace(buf.String())
 	post.Body = handleContentDirectivePlaceholderReplacements(post.Body, cdPhReps)
+	post.Body, warnings = renderHeadings(post.Body, resLoader, warnings)
 	post.Warnings = appendUnparsedDirectiveWarnings(warnings, post.Body)
 	metaData := meta.Get(context)
 	if date, ok := metaData[metaDataKeyDate].(string); ok {
@@ -213,6 +288,21 @@ func parsePost(postId string, content string, config appConfig, resLoader resour
 		}
 		post.Title = title
 	}
+	if description, ok := metaData[metaDataKeyDescription].(string); ok {
+		post.Description = strings.TrimSpace(description)
+	}
+	post.Lang = parseLanguageMetaData(metaData, config, &post.Warnings)
+	if translationOf, ok := metaData[metaDataKeyTranslationOf].(string); ok {
+		post.TranslationOf = strings.TrimSpace(translationOf)
+	}
+	post.Series, post.SeriesPart = parseSeriesMetaData(metaData, &post.Warnings)
+	if draft, ok := metaData[metaDataKeyDraft]; ok {
+		if d, ok := draft.(bool); ok {
+			post.Draft = d
+		} else {
+			post.Warnings = append(post.Warnings, fmt.Sprintf("draft: malformed value: %v (expected true or false)", draft))
+		}
+	}
 	tags := metaData[metaDataKeyTags]
 	if tags != nil {
 		ti := tags.([]interface{})
@@ -285,6 +375,8 @@ func parseCollectionsMetaData(metaData map[string]interface{}, postId string, co
 		return nil, nil
 	}
 	var warnings []string
+	// the malformed focal points are reported along with the content directive warnings
+	mediaFocus, _ := parseMediaFocusMetaData(metaData)
 	collMap, ok := toStringKeyMap(raw)
 	if !ok {
 		warnings = append(warnings, "collections: malformed metadata (expected a map of collection name to item list)")
@@ -342,7 +434,7 @@ func parseCollectionsMetaData(metaData map[string]interface{}, postId string, co
 			refs = append(refs, postCollectionRef{
 				Collection: collTitle,
 				Item:       itemTitle,
-				Media:      resolveCollectionRefMedia(imageRefs, itemTitle, collTitle, postId, config, &warnings),
+				Media:      resolveCollectionRefMedia(imageRefs, itemTitle, collTitle, postId, config, mediaFocus, &warnings),
 			})
 		}
 	}
@@ -352,7 +444,7 @@ func parseCollectionsMetaData(metaData map[string]interface{}, postId string, co
 // resolveCollectionRefMedia resolves collection item image references
 // against the post's media dir with a shared-media fallback (same semantics as explicit `{media:...}` references);
 // warning on (and skipping) references that don't resolve to an existing image file
-func resolveCollectionRefMedia(imageRefs []string, itemTitle string, collTitle string, postId string, config appConfig, warnings *[]string) []media {
+func resolveCollectionRefMedia(imageRefs []string, itemTitle string, collTitle string, postId string, config appConfig, mediaFocus map[string]focalPoint, warnings *[]string) []media {
 	if len(imageRefs) == 0 {
 		return nil
 	}
@@ -374,7 +466,7 @@ func resolveCollectionRefMedia(imageRefs []string, itemTitle string, collTitle s
 			*warnings = append(*warnings, fmt.Sprintf("collections: unresolved image reference \"%s\" (item \"%s\", collection \"%s\")", ref, itemTitle, collTitle))
 			continue
 		}
-		for _, m := range parseMediaFileNames([]string{ref}, Post, postId, config, true, nil) {
+		for _, m := range parseMediaFileNames([]string{ref}, Post, postId, config, true, nil, mediaFocus) {
 			if m.Type != Image {
 				*warnings = append(*warnings, fmt.Sprintf("collections: image reference \"%s\" is not an image (item \"%s\", collection \"%s\")", ref, itemTitle, collTitle))
 				continue
@@ -391,7 +483,9 @@ func handleThumbnails(mediaDirPath string, config appConfig, thumbHandler imageT
 	}
 }
 
-func parseContentDirectives(ceType contentEntityType, ceId string, content string, config appConfig, resLoader resourceLoader) (string, string, map[string]string, []string) {
+// parseContentDirectives returns the content with its directives replaced by placeholders, the raw body content,
+// the placeholder replacements, the media of its gallery directives (deduplicated) and the directive warnings
+func parseContentDirectives(ceType contentEntityType, ceId string, content string, config appConfig, resLoader resourceLoader) (string, string, map[string]string, []media, []string) {
 	rawBodyContent := metaDataPlaceholderRegexp.ReplaceAllString(content, "")
 	rawBodyContent = contentDirectivePlaceholderRegexp.ReplaceAllString(rawBodyContent, "")
 	rawBodyContent = whitespacePlaceholderRegexp.ReplaceAllString(rawBodyContent, " ")
@@ -399,6 +493,7 @@ func parseContentDirectives(ceType contentEntityType, ceId string, content strin
 
 	phReps := make(map[string]string)
 	var expListMedia []string
+	var galleryMedia []media
 	var warnings []string
 
 	// extract {cols}...{//} blocks first so inner {col}...{/} tokens don't get
@@ -411,19 +506,24 @@ func parseContentDirectives(ceType contentEntityType, ceId string, content strin
 		content = strings.Replace(content, m[0], ph, 1)
 	}
 
-	content = processInnerDirectives(content, ceType, ceId, config, resLoader, phReps, &expListMedia, &warnings)
+	mediaFocus, focusWarnings := parseMediaFocusMetaData(parseRawMetaData(content))
+	warnings = append(warnings, focusWarnings...)
+
+	content = processInnerDirectives(content, ceType, ceId, config, resLoader, mediaFocus, phReps, &expListMedia, &galleryMedia, &warnings)
 
 	for _, cb := range colsBlocks {
-		rendered := processColsBlock(cb, ceType, ceId, config, resLoader, phReps, &expListMedia, &warnings)
+		rendered := processColsBlock(cb, ceType, ceId, config, resLoader, mediaFocus, phReps, &expListMedia, &galleryMedia, &warnings)
 		phReps[cb.ph] = rendered
 	}
 
-	return content, rawBodyContent, phReps, warnings
+	return content, rawBodyContent, phReps, galleryMedia, warnings
 }
 
 // resolveDirectiveMedia parses a media/with-media directive argument into rendered media items,
 // collecting any malformed-caption warnings; an empty file list means "all media".
-func resolveDirectiveMedia(mediaArg string, directive string, ceType contentEntityType, ceId string, config appConfig, expListMedia *[]string, warnings *[]string) []media {
+// The focal point of the directive (the `focus` prop, if any) applies to all of its media,
+// overriding the one set via the `media-focus` metadata map.
+func resolveDirectiveMedia(mediaArg string, directive string, focus string, ceType contentEntityType, ceId string, config appConfig, mediaFocus map[string]focalPoint, expListMedia *[]string, warnings *[]string) []media {
 	fileNames, captions, malformed := splitMediaArg(mediaArg)
 	for _, bad := range malformed {
 		*warnings = append(*warnings, "malformed caption \""+strings.TrimSpace(bad)+"\" in {"+directive+"} directive (expected: name: caption)")
@@ -432,10 +532,43 @@ func resolveDirectiveMedia(mediaArg string, directive string, ceType contentEnti
 	if !isExplicit {
 		fileNames = listAllMedia(ceType, ceId, *expListMedia)
 	}
-	return parseMediaFileNames(fileNames, ceType, ceId, config, isExplicit, captions)
+	if focus != "" {
+		fp, ok := parseFocalPoint(focus)
+		if ok {
+			mediaFocus = maps.Clone(mediaFocus)
+			if mediaFocus == nil {
+				mediaFocus = make(map[string]focalPoint)
+			}
+			for _, fileName := range fileNames {
+				mediaFocus[fileName] = fp
+			}
+		} else {
+			*warnings = append(*warnings, "malformed focal point \""+focus+"\" in {"+directive+"} directive (expected: XxY, in percent, e.g. 30x40)")
+		}
+	}
+	return parseMediaFileNames(fileNames, ceType, ceId, config, isExplicit, captions, mediaFocus)
 }
 
-func processInnerDirectives(content string, ceType contentEntityType, ceId string, config appConfig, resLoader resourceLoader, phReps map[string]string, expListMedia *[]string, warnings *[]string) string {
+func processInnerDirectives(content string, ceType contentEntityType, ceId string, config appConfig, resLoader resourceLoader, mediaFocus map[string]focalPoint, phReps map[string]string, expListMedia *[]string, galleryMedia *[]media, warnings *[]string) string {
+	contentLinkPlaceholders := contentLinkPlaceholderRegexp.FindAllStringSubmatch(content, -1)
+	if contentLinkPlaceholders != nil {
+		for _, clp := range contentLinkPlaceholders {
+			placeholder := clp[0]
+			ref := parseContentLinkRef(clp)
+			if ref.Type == UndefinedContentEntityType {
+				*warnings = append(*warnings, "content link references an unknown content type: "+placeholder)
+			}
+			link := contentLinkURI(ref)
+			if ref.Anchor != "" {
+				// substituted once the content is converted to HTML,
+				// so that the anchor is not picked up as a hashtag
+				ph := fmt.Sprintf(directivePlaceholderReplacementFormat, uuid.New().String())
+				phReps[ph] = link
+				link = ph
+			}
+			content = strings.Replace(content, placeholder, link, 1)
+		}
+	}
 	content = hashTagRegex.ReplaceAllStringFunc(content, func(match string) string {
 		tag := match[1:] // strip leading '#'; regex guarantees '#' + tag chars
 		return fmt.Sprintf(hashTagMarkdownReplacementFormat, tag, normalizeURIString(tag))
@@ -459,26 +592,6 @@ func processInnerDirectives(content string, ceType contentEntityType, ceId strin
 			content = strings.Replace(content, placeholder, link, 1)
 		}
 	}
-	contentLinkPlaceholders := contentLinkPlaceholderRegexp.FindAllStringSubmatch(content, -1)
-	if contentLinkPlaceholders != nil {
-		for _, clp := range contentLinkPlaceholders {
-			placeholder := clp[0]
-			entityType := strings.ToLower(clp[1])
-			entryId := clp[2]
-			var linkCeType contentEntityType
-			switch entityType {
-			case "page":
-				linkCeType = Page
-			case "post":
-				linkCeType = Post
-			}
-			var link string
-			if linkCeType != UndefinedContentEntityType {
-				link = "/" + strings.ToLower(linkCeType.String()) + "/" + entryId + contentFileExtension
-			}
-			content = strings.Replace(content, placeholder, link, 1)
-		}
-	}
 	searchLinkPlaceholders := searchLinkPlaceholderRegexp.FindAllStringSubmatch(content, -1)
 	if searchLinkPlaceholders != nil {
 		for _, slp := range searchLinkPlaceholders {
@@ -506,6 +619,11 @@ func processInnerDirectives(content string, ceType contentEntityType, ceId strin
 		}
 		return fmt.Sprintf(collectionDirectivePlaceholderFormat, uri)
 	})
+	// toc directives resolve to deterministic placeholders as well, rendered once the whole content
+	// is converted to HTML (the table of contents is built from the rendered headings)
+	content = tocDirectiveRegexp.ReplaceAllStringFunc(content, func(match string) string {
+		return fmt.Sprintf(tocDirectivePlaceholderFormat, parseTocDirectiveProps(tocDirectiveRegexp.FindStringSubmatch(match)[1], warnings))
+	})
 	wrapPlaceholders := wrapPlaceholderRegexp.FindAllStringSubmatch(content, -1)
 	if wrapPlaceholders != nil {
 		sortContentDirectivePlaceholders(wrapPlaceholders)
@@ -524,6 +642,15 @@ func processInnerDirectives(content string, ceType contentEntityType, ceId strin
 			}
 		}
 	}
+	galleryPlaceholders := galleryPlaceholderRegexp.FindAllStringSubmatch(content, -1)
+	if galleryPlaceholders != nil {
+		sortContentDirectivePlaceholders(galleryPlaceholders)
+		for _, gp := range galleryPlaceholders {
+			if fileNames, _, _ := splitMediaArg(gp[2]); fileNames != nil {
+				*expListMedia = append(*expListMedia, fileNames...)
+			}
+		}
+	}
 	if wrapPlaceholders != nil {
 		for _, wp := range wrapPlaceholders {
 			placeholder := wp[0]
@@ -541,19 +668,11 @@ func processInnerDirectives(content string, ceType contentEntityType, ceId strin
 				mediaArg := wp[3]
 				text := strings.TrimSpace(wp[4])
 				var buf bytes.Buffer
-				err := markdown.Convert([]byte(text), &buf)
+				err := contentMarkdown(config).Convert([]byte(text), &buf)
 				check(err)
 				text = strings.TrimSpace(buf.String())
-				props := make(map[string]string)
-				if propStr != "" {
-					for _, pStr := range strings.Split(propStr, ",") {
-						prop := strings.Split(strings.TrimSpace(pStr), "=")
-						key := strings.TrimSpace(prop[0])
-						val := strings.TrimSpace(prop[1])
-						props[key] = val
-					}
-				}
-				allMedia := resolveDirectiveMedia(mediaArg, directive, ceType, ceId, config, expListMedia, warnings)
+				props := parseDirectiveProps(propStr)
+				allMedia := resolveDirectiveMedia(mediaArg, directive, props[mediaFocusPropName], ceType, ceId, config, mediaFocus, expListMedia, warnings)
 				var contentDirectiveMarkupBuffer bytes.Buffer
 				err = contentDirectiveTemplate.Execute(&contentDirectiveMarkupBuffer, contentDirectiveData{
 					Text:  text,
@@ -572,16 +691,8 @@ func processInnerDirectives(content string, ceType contentEntityType, ceId strin
 			placeholder := mp[0]
 			propStr := strings.Trim(mp[1], "()")
 			mediaArg := mp[2]
-			props := make(map[string]string)
-			if propStr != "" {
-				for _, pStr := range strings.Split(propStr, ",") {
-					prop := strings.Split(strings.TrimSpace(pStr), "=")
-					key := strings.TrimSpace(prop[0])
-					val := strings.TrimSpace(prop[1])
-					props[key] = val
-				}
-			}
-			allMedia := resolveDirectiveMedia(mediaArg, "media", ceType, ceId, config, expListMedia, warnings)
+			props := parseDirectiveProps(propStr)
+			allMedia := resolveDirectiveMedia(mediaArg, "media", props[mediaFocusPropName], ceType, ceId, config, mediaFocus, expListMedia, warnings)
 			if allMedia != nil {
 				inlineMediaTemplate := compileMediaTemplate(resLoader)
 				var inlineMediaMarkupBuffer bytes.Buffer
@@ -598,6 +709,44 @@ func processInnerDirectives(content string, ceType contentEntityType, ceId strin
 			}
 		}
 	}
+	if galleryPlaceholders != nil {
+		for _, gp := range galleryPlaceholders {
+			placeholder := gp[0]
+			props := parseDirectiveProps(gp[1])
+			// the gallery (along with its lightbox) lists the images only
+			allMedia := slices.DeleteFunc(resolveDirectiveMedia(gp[2], galleryDirective, props[mediaFocusPropName], ceType, ceId, config, mediaFocus, expListMedia, warnings), func(m media) bool {
+				return !m.Type.Image()
+			})
+			if len(allMedia) == 0 {
+				content = strings.Replace(content, placeholder, "", 1)
+				continue
+			}
+			galleryTemplate, err := compileContentDirectiveTemplate(galleryDirective, resLoader)
+			if err != nil {
+				println(" - failed to process " + galleryDirective + " directive for " + ceId + ": " + err.Error())
+				continue
+			}
+			data := contentDirectiveData{
+				Media: allMedia,
+				Props: props,
+			}
+			// the album page (listing all the gallery media of the post) is only generated for posts
+			if ceType == Post && config.generateAlbums {
+				data.AlbumUri = "/" + deployAlbumsDirName + "/" + ceId + contentFileExtension
+			}
+			var galleryMarkupBuffer bytes.Buffer
+			err = galleryTemplate.Execute(&galleryMarkupBuffer, data)
+			check(err)
+			ph := fmt.Sprintf(directivePlaceholderReplacementFormat, uuid.New().String())
+			phReps[ph] = strings.TrimSpace(galleryMarkupBuffer.String())
+			content = strings.Replace(content, placeholder, ph, 1)
+			for _, m := range allMedia {
+				if !slices.ContainsFunc(*galleryMedia, func(gm media) bool { return gm.Uri == m.Uri }) {
+					*galleryMedia = append(*galleryMedia, m)
+				}
+			}
+		}
+	}
 	embedMediaPlaceholders := embedMediaPlaceholderRegexp.FindAllStringSubmatch(content, -1)
 	if embedMediaPlaceholders != nil {
 		for _, emp := range embedMediaPlaceholders {
@@ -632,7 +781,7 @@ func processInnerDirectives(content string, ceType contentEntityType, ceId strin
 	return content
 }
 
-func processColsBlock(cb colsBlock, ceType contentEntityType, ceId string, config appConfig, resLoader resourceLoader, phReps map[string]string, expListMedia *[]string, warnings *[]string) string {
+func processColsBlock(cb colsBlock, ceType contentEntityType, ceId string, config appConfig, resLoader resourceLoader, mediaFocus map[string]focalPoint, phReps map[string]string, expListMedia *[]string, galleryMedia *[]media, warnings *[]string) string {
 	weights, weightsErr := parseColsWeights(cb.weights)
 	if weightsErr != "" {
 		println(" - " + weightsErr + " for " + ceId + "; falling back to equal widths")
@@ -642,7 +791,7 @@ func processColsBlock(cb colsBlock, ceType contentEntityType, ceId string, confi
 	// pre-process inner directives on the whole cols body first so that nested
 	// {/} closers (e.g., from {with-media}...{/}) become UUID placeholders and
 	// don't confuse the {col}...{/} matcher's lazy quantifier downstream
-	inner := processInnerDirectives(cb.inner, ceType, ceId, config, resLoader, phReps, expListMedia, warnings)
+	inner := processInnerDirectives(cb.inner, ceType, ceId, config, resLoader, mediaFocus, phReps, expListMedia, galleryMedia, warnings)
 
 	colMatches := colPlaceholderRegexp.FindAllStringSubmatch(inner, -1)
 	if len(colMatches) == 0 {
@@ -662,7 +811,7 @@ func processColsBlock(cb colsBlock, ceType contentEntityType, ceId string, confi
 		align := parseColAlign(propsStr, ceId)
 
 		var buf bytes.Buffer
-		err := markdown.Convert([]byte(strings.TrimSpace(body)), &buf)
+		err := contentMarkdown(config).Convert([]byte(strings.TrimSpace(body)), &buf)
 		check(err)
 		// Goldmark wraps standalone UUID placeholders in `<p>...</p>`, and with
 		// WithHardWraps multiple consecutive placeholders collapse into a single
@@ -844,7 +993,7 @@ func listAllMedia(contentEntityType contentEntityType, contentEntityId string, s
 		imageFiles, err := listFilesByExt(mediaDirPath, imageFileExtensions...)
 		check(err)
 		for _, image := range imageFiles {
-			if !skip(image) && !strings.Contains(image, thumbImgFileSuffix) {
+			if !skip(image) && !strings.Contains(image, thumbImgFileSuffix) && !videoPosterFileNameRegexp.MatchString(image) {
 				allMedia = append(allMedia, image)
 			}
 		}
@@ -863,7 +1012,7 @@ func listSharedMedia() []string {
 		imageFiles, err := listFilesByExt(mediaDirPath, imageFileExtensions...)
 		check(err)
 		for _, image := range imageFiles {
-			if !strings.Contains(image, thumbImgFileSuffix) {
+			if !strings.Contains(image, thumbImgFileSuffix) && !videoPosterFileNameRegexp.MatchString(image) {
 				allMedia = append(allMedia, image)
 			}
 		}
@@ -874,28 +1023,76 @@ func listSharedMedia() []string {
 func buildMediaItem(mediaFileName string, uriSubPath string, dirSubPath string, config appConfig) *media {
 	mediaUri := "/" + mediaDirName + "/" + uriSubPath + "/" + mediaFileName
 	mediaFileExt := strings.ToLower(filepath.Ext(mediaFileName))
+	mediaDirPath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, mediaDirName, os.PathSeparator, dirSubPath)
 	if slices.Contains(imageFileExtensions, mediaFileExt) {
-		var thumbs []thumb
+		// the WebP variants are only exposed if available for the image and all of its thumbnails
+		webp := slices.Contains(config.webpVariants.sourceFileExtensions(), mediaFileExt) &&
+			fileExists(fmt.Sprintf("%s%c%s", mediaDirPath, os.PathSeparator, mediaFileName+webpFileExtension))
+		imgInfo := getMediaInfo(fmt.Sprintf("%s%c%s", mediaDirPath, os.PathSeparator, mediaFileName), config.imgPlaceholders)
+		var thumbs, webpThumbs []thumb
 		for _, thSize := range config.thumbSizes {
 			thFileSuffix := "_" + strconv.Itoa(thSize) + thumbImgFileSuffix + mediaFileExt
 			thumbFile := mediaFileName + thFileSuffix
-			thumbFilePath := fmt.Sprintf("%s%c%s%c%s%c%s",
-				deployDirName, os.PathSeparator,
-				mediaDirName, os.PathSeparator,
-				dirSubPath, os.PathSeparator, thumbFile)
+			thumbFilePath := fmt.Sprintf("%s%c%s", mediaDirPath, os.PathSeparator, thumbFile)
 			if fileExists(thumbFilePath) {
-				thumbs = append(thumbs, thumb{Uri: "/" + mediaDirName + "/" + uriSubPath + "/" + thumbFile, Size: thSize})
+				thInfo := getMediaInfo(thumbFilePath, false)
+				thumbs = append(thumbs, thumb{Uri: "/" + mediaDirName + "/" + uriSubPath + "/" + thumbFile, Size: thSize, Width: thInfo.Width, Height: thInfo.Height})
+				webp = webp && fileExists(thumbFilePath+webpFileExtension)
 			} else {
-				thumbs = append(thumbs, thumb{Uri: mediaUri, Size: thSize})
+				thumbs = append(thumbs, thumb{Uri: mediaUri, Size: thSize, Width: imgInfo.Width, Height: imgInfo.Height})
 			}
 		}
-		return &media{Type: Image, Uri: mediaUri, thumbs: thumbs}
+		m := &media{Type: Image, Uri: mediaUri, thumbs: thumbs,
+			width: imgInfo.Width, height: imgInfo.Height, placeholder: imgInfo.Placeholder, color: imgInfo.Color, camera: imgInfo.Camera}
+		if captureTime, err := civil.ParseDateTime(imgInfo.CaptureTime); err == nil {
+			m.captureTime = captureTime
+		}
+		m.setCropThumbs(mediaFileName, uriSubPath, dirSubPath, config)
+		if webp {
+			m.webpUri = mediaUri + webpFileExtension
+			for _, th := range thumbs {
+				webpThumbs = append(webpThumbs, thumb{Uri: th.Uri + webpFileExtension, Size: th.Size, Width: th.Width, Height: th.Height})
+			}
+			m.webpThumbs = webpThumbs
+		}
+		return m
 	} else if slices.Contains(videoFileExtensions, mediaFileExt) {
-		return &media{Type: Video, Uri: mediaUri}
+		videoInfo := getMediaInfo(fmt.Sprintf("%s%c%s", mediaDirPath, os.PathSeparator, mediaFileName), false)
+		m := &media{Type: Video, Uri: mediaUri, width: videoInfo.Width, height: videoInfo.Height, duration: videoInfo.Duration}
+		// a sibling image named after the video (e.g. `clip.mp4.poster.jpg`) is its poster
+		for _, ext := range imageFileExtensions {
+			posterFile := mediaFileName + videoPosterFileSuffix + ext
+			if fileExists(fmt.Sprintf("%s%c%s", mediaDirPath, os.PathSeparator, posterFile)) {
+				m.posterUri = "/" + mediaDirName + "/" + uriSubPath + "/" + posterFile
+				break
+			}
+		}
+		return m
 	}
 	return nil
 }
 
+// setCropThumbs sets the crop thumbnails of the image (see the `thumbSizes` config option)
+// for its focal point, falling back to the image itself for the ones not (yet) generated
+func (m *media) setCropThumbs(mediaFileName string, uriSubPath string, dirSubPath string, config appConfig) {
+	fp := focalPoint{x: 50, y: 50}
+	if m.focus != nil {
+		fp = *m.focus
+	}
+	mediaDirPath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, mediaDirName, os.PathSeparator, dirSubPath)
+	m.cropThumbs = nil
+	for _, cs := range config.cropThumbSizes {
+		thumbFile := cropThumbFileName(mediaFileName, cs, fp)
+		thumbFilePath := fmt.Sprintf("%s%c%s", mediaDirPath, os.PathSeparator, thumbFile)
+		if fileExists(thumbFilePath) {
+			thInfo := getMediaInfo(thumbFilePath, false)
+			m.cropThumbs = append(m.cropThumbs, thumb{Uri: "/" + mediaDirName + "/" + uriSubPath + "/" + thumbFile, Size: cs.width, Width: thInfo.Width, Height: thInfo.Height})
+		} else {
+			m.cropThumbs = append(m.cropThumbs, thumb{Uri: m.Uri, Size: cs.width, Width: m.width, Height: m.height})
+		}
+	}
+}
+
 // splitMediaArg parses the inner argument of a `{media...}` / `{with-media...}` directive.
 // The argument is `[: ]file1,file2,...` (a comma-separated file list, optionally empty for all media)
 // optionally followed by `|`-separated caption specs:
@@ -1008,7 +1205,7 @@ func expandMediaFileName(name string, dir string) []string {
 	return matches
 }
 
-func parseMediaFileNames(mediaFileNames []string, contentEntityType contentEntityType, contentEntityId string, config appConfig, isExplicit bool, captions map[string]string) []media {
+func parseMediaFileNames(mediaFileNames []string, contentEntityType contentEntityType, contentEntityId string, config appConfig, isExplicit bool, captions map[string]string, mediaFocus map[string]focalPoint) []media {
 	var allMedia []media
 	ceType := strings.ToLower(contentEntityType.String())
 	contentUriSubPath := ceType + "/" + contentEntityId
@@ -1016,27 +1213,35 @@ func parseMediaFileNames(mediaFileNames []string, contentEntityType contentEntit
 	mediaDirBasePath := filepath.Join(deployDirName, mediaDirName)
 	contentDir := filepath.Join(mediaDirBasePath, contentDirSubPath)
 	sharedDir := filepath.Join(mediaDirBasePath, sharedMediaDirName)
-	// attach a caption to a resolved media item by exact file name first, then base name
+	// attach a caption (and a focal point) to a resolved media item by exact file name first, then base name
 	// (so a base-name caption applies to every file sharing that base name)
-	attach := func(m *media, fileName string) {
+	attach := func(m *media, fileName string, uriSubPath string, dirSubPath string) {
 		if caption, ok := captions[fileName]; ok {
 			m.Caption = caption
 		} else if caption, ok := captions[stripExt(fileName)]; ok {
 			m.Caption = caption
 		}
+		fp, ok := mediaFocus[fileName]
+		if !ok {
+			fp, ok = mediaFocus[stripExt(fileName)]
+		}
+		if ok && m.Type == Image {
+			m.focus = &fp
+			m.setCropThumbs(fileName, uriSubPath, dirSubPath, config)
+		}
 	}
 	hasSupportedExt := func(name string) bool {
 		ext := strings.ToLower(filepath.Ext(name))
 		return slices.Contains(imageFileExtensions, ext) || slices.Contains(videoFileExtensions, ext)
 	}
 	for _, mediaFileName := range mediaFileNames {
-		if strings.Contains(mediaFileName, thumbImgFileSuffix) {
+		if strings.Contains(mediaFileName, thumbImgFileSuffix) || videoPosterFileNameRegexp.MatchString(mediaFileName) {
 			continue
 		}
 		if !isExplicit {
 			// names came from listAllMedia — already actual content-specific file names
 			if m := buildMediaItem(mediaFileName, contentUriSubPath, contentDirSubPath, config); m != nil {
-				attach(m, mediaFileName)
+				attach(m, mediaFileName, contentUriSubPath, contentDirSubPath)
 				allMedia = append(allMedia, *m)
 			}
 			continue
@@ -1049,7 +1254,7 @@ func parseMediaFileNames(mediaFileNames []string, contentEntityType contentEntit
 				uriSubPath, dirSubPath = sharedMediaDirName, sharedMediaDirName
 			}
 			if m := buildMediaItem(mediaFileName, uriSubPath, dirSubPath, config); m != nil {
-				attach(m, mediaFileName)
+				attach(m, mediaFileName, uriSubPath, dirSubPath)
 				allMedia = append(allMedia, *m)
 			}
 			continue
@@ -1063,7 +1268,7 @@ func parseMediaFileNames(mediaFileNames []string, contentEntityType contentEntit
 		}
 		for _, fileName := range resolved {
 			if m := buildMediaItem(fileName, uriSubPath, dirSubPath, config); m != nil {
-				attach(m, fileName)
+				attach(m, fileName, uriSubPath, dirSubPath)
 				allMedia = append(allMedia, *m)
 			}
 		}
@@ -1084,6 +1289,96 @@ func parseSharedMediaFileNames(mediaFileNames []string, config appConfig) []medi
 	return allMedia
 }
 
+// comparePostsByDateTime orders dated posts newest first (0 for the same date/time)
+func comparePostsByDateTime(a, b post) int {
+	if c := b.Date.Compare(a.Date); c != 0 {
+		return c
+	}
+	return b.Time.Compare(a.Time)
+}
+
+// sortPosts orders the given posts (expected in the descending file name order) according to
+// the `postOrder` config option: either as they are, or newest first by their date/time, in which case
+// posts with the same date/time keep their file name order, while the undated posts are handled
+// according to the `undatedPosts` config option:
+//   - error: the undated posts are reported and the program exits with an error
+//   - bottom: the undated posts are put after all the dated ones (in the file name order)
+//   - filename: the undated posts keep their places in the file name order, the dated ones are sorted around them
+func sortPosts(posts []post, config appConfig) []post {
+	if config.postOrder != DateTimePostOrder || len(posts) < 2 {
+		return posts
+	}
+	var dated, undated []post
+	var datedIdx []int
+	for i, p := range posts {
+		if p.Date.IsZero() {
+			undated = append(undated, p)
+		} else {
+			dated = append(dated, p)
+			datedIdx = append(datedIdx, i)
+		}
+	}
+	slices.SortStableFunc(dated, comparePostsByDateTime)
+	switch config.undatedPosts {
+	case ErrorUndatedPostPolicy:
+		if len(undated) > 0 {
+			var files []string
+			for _, p := range undated {
+				files = append(files, markdownPostsDirName+"/"+p.Id+markdownFileExtension)
+			}
+			exitWithError("posts without a date (required by the `undatedPosts: " + ErrorUndatedPostPolicy.String() + "` config option):\n - " + strings.Join(files, "\n - "))
+		}
+		return dated
+	case BottomUndatedPostPolicy:
+		return append(dated, undated...)
+	default:
+		sorted := slices.Clone(posts)
+		for i, idx := range datedIdx {
+			sorted[idx] = dated[i]
+		}
+		return sorted
+	}
+}
+
+// inspectPostOrderMismatches returns a description of every pair of adjacent dated posts
+// (in the descending file name order) where an older post comes before a newer one,
+// i.e. where the file name order and the date/time order disagree
+func inspectPostOrderMismatches(posts []post) []string {
+	byFileName := slices.Clone(posts)
+	slices.SortFunc(byFileName, func(a, b post) int {
+		return strings.Compare(b.Id+markdownFileExtension, a.Id+markdownFileExtension)
+	})
+	var mismatches []string
+	var prev *post
+	for i := range byFileName {
+		p := &byFileName[i]
+		if p.Date.IsZero() {
+			continue
+		}
+		if prev != nil && comparePostsByDateTime(*prev, *p) > 0 {
+			mismatches = append(mismatches, fmt.Sprintf("%s/%s%s (%s) comes before %s/%s%s (%s) by file name",
+				markdownPostsDirName, prev.Id, markdownFileExtension, strings.TrimSpace(prev.FmtDate()+" "+prev.FmtTime()),
+				markdownPostsDirName, p.Id, markdownFileExtension, strings.TrimSpace(p.FmtDate()+" "+p.FmtTime())))
+		}
+		prev = p
+	}
+	return mismatches
+}
+
+// splitPendingPosts splits the given posts into the published ones and the ones pending publication
+// (drafts and posts scheduled in the future) as of the given moment, preserving the original order
+func splitPendingPosts(posts []post, now time.Time) ([]post, []post) {
+	var published, pending []post
+	for _, p := range posts {
+		if p.isPendingAt(now) {
+			pending = append(pending, p)
+		} else {
+			published = append(published, p)
+		}
+	}
+	return published, pending
+}
+
 // inspectTagTitleDuplicates returns a map of normalized tag URI -> sorted distinct
 // original titles, for URIs that appear with more than one distinct title across the given posts.
 func inspectTagTitleDuplicates(posts []post) map[string][]string {
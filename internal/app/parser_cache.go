@@ -14,35 +14,35 @@ type postEntityCacheData struct {
 	post    post
 }
 
-var pageCacheData = make(map[string]pageEntityCacheData)
+var pageCacheData = newSyncCache[string, pageEntityCacheData]()
 
-var postCacheData = make(map[string]postEntityCacheData)
+var postCacheData = newSyncCache[string, postEntityCacheData]()
 
 func addContentEntityToCache(fileName string, modTime time.Time, ce contentEntity) {
 	switch ce.ContentEntityType() {
 	case Page:
-		pageCacheData[fileName] = pageEntityCacheData{
+		pageCacheData.set(fileName, pageEntityCacheData{
 			modTime: modTime,
 			page:    ce.(page),
-		}
+		})
 	case Post:
-		postCacheData[fileName] = postEntityCacheData{
+		postCacheData.set(fileName, postEntityCacheData{
 			modTime: modTime,
 			post:    ce.(post),
-		}
+		})
 	}
 }
 
 func getContentEntityFromCache(ceType contentEntityType, fileName string, modTime time.Time) contentEntity {
 	switch ceType {
 	case Page:
-		if data, ok := pageCacheData[fileName]; ok {
+		if data, ok := pageCacheData.get(fileName); ok {
 			if data.modTime == modTime {
 				return data.page
 			}
 		}
 	case Post:
-		if data, ok := postCacheData[fileName]; ok {
+		if data, ok := postCacheData.get(fileName); ok {
 			if data.modTime == modTime {
 				return data.post
 			}
@@ -54,8 +54,8 @@ func getContentEntityFromCache(ceType contentEntityType, fileName string, modTim
 func removeContentEntityFromCache(ceType contentEntityType, fileName string) {
 	switch ceType {
 	case Page:
-		delete(pageCacheData, fileName)
+		pageCacheData.delete(fileName)
 	case Post:
-		delete(postCacheData, fileName)
+		postCacheData.delete(fileName)
 	}
 }
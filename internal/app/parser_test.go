@@ -7,6 +7,9 @@ import (
 	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
 )
 
 const testPageTitle = "Test Page"
@@ -528,7 +531,7 @@ func TestSharedMediaResolution(t *testing.T) {
 	config := defaultConfig()
 
 	// content-specific file: should use content-specific URI (takes precedence)
-	result := parseMediaFileNames([]string{"specific.jpg"}, Post, "test-post", config, true, nil)
+	result := parseMediaFileNames([]string{"specific.jpg"}, Post, "test-post", config, true, nil, nil)
 	if len(result) != 1 {
 		t.Fatalf("expected 1 media item, got %d", len(result))
 	}
@@ -537,7 +540,7 @@ func TestSharedMediaResolution(t *testing.T) {
 	}
 
 	// shared file (not in content-specific dir): should fall back to shared URI
-	result = parseMediaFileNames([]string{"shared.jpg"}, Post, "test-post", config, true, nil)
+	result = parseMediaFileNames([]string{"shared.jpg"}, Post, "test-post", config, true, nil, nil)
 	if len(result) != 1 {
 		t.Fatalf("expected 1 media item, got %d", len(result))
 	}
@@ -546,7 +549,7 @@ func TestSharedMediaResolution(t *testing.T) {
 	}
 
 	// missing file (not in either dir): should use content-specific URI (no fallback)
-	result = parseMediaFileNames([]string{"missing.jpg"}, Post, "test-post", config, true, nil)
+	result = parseMediaFileNames([]string{"missing.jpg"}, Post, "test-post", config, true, nil, nil)
 	if len(result) != 1 {
 		t.Fatalf("expected 1 media item, got %d", len(result))
 	}
@@ -555,7 +558,7 @@ func TestSharedMediaResolution(t *testing.T) {
 	}
 
 	// non-explicit call: shared.jpg exists in shared dir but isExplicit=false, so no fallback
-	result = parseMediaFileNames([]string{"shared.jpg"}, Post, "test-post", config, false, nil)
+	result = parseMediaFileNames([]string{"shared.jpg"}, Post, "test-post", config, false, nil, nil)
 	if len(result) != 1 {
 		t.Fatalf("expected 1 media item, got %d", len(result))
 	}
@@ -1244,6 +1247,101 @@ A typo'd directive {medai:a.jpg} should be flagged, but ` + "`{media:a.jpg}`" +
 	}
 }
 
+func TestDraftMetadata(t *testing.T) {
+	post := parsePost("draft", "---\ndate: 2025-08-30\ndraft: true\n---\n\nPost body.", defaultConfig(), testResLoader())
+	if !post.Draft {
+		t.Error("expected the post to be flagged as a draft")
+	}
+	post = parsePost("not-draft", "---\ndate: 2025-08-30\ndraft: false\n---\n\nPost body.", defaultConfig(), testResLoader())
+	if post.Draft {
+		t.Error("expected the post not to be flagged as a draft")
+	}
+	post = parsePost("malformed-draft", "---\ndate: 2025-08-30\ndraft: maybe\n---\n\nPost body.", defaultConfig(), testResLoader())
+	if post.Draft || len(post.Warnings) != 1 || !strings.Contains(post.Warnings[0], "draft: malformed value") {
+		t.Errorf("expected a malformed draft value warning, got draft=%v, warnings=%v", post.Draft, post.Warnings)
+	}
+}
+
+func TestSplitPendingPosts(t *testing.T) {
+	now := time.Date(2026, 4, 18, 12, 0, 0, 0, time.Local)
+	posts := []post{
+		{Id: "scheduled-date", Date: civil.Date{Year: 2026, Month: 4, Day: 19}},
+		{Id: "scheduled-time", Date: civil.Date{Year: 2026, Month: 4, Day: 18}, Time: civil.Time{Hour: 12, Minute: 30}},
+		{Id: "today", Date: civil.Date{Year: 2026, Month: 4, Day: 18}},
+		{Id: "draft", Date: civil.Date{Year: 2026, Month: 4, Day: 1}, Draft: true},
+		{Id: "past", Date: civil.Date{Year: 2026, Month: 4, Day: 1}, Time: civil.Time{Hour: 9}},
+		{Id: "undated"},
+	}
+	published, pending := splitPendingPosts(posts, now)
+	ids := func(posts []post) []string {
+		var ids []string
+		for _, p := range posts {
+			ids = append(ids, p.Id)
+		}
+		return ids
+	}
+	if expected := []string{"today", "past", "undated"}; !slices.Equal(ids(published), expected) {
+		t.Errorf("expected published posts %v, got %v", expected, ids(published))
+	}
+	if expected := []string{"scheduled-date", "scheduled-time", "draft"}; !slices.Equal(ids(pending), expected) {
+		t.Errorf("expected pending posts %v, got %v", expected, ids(pending))
+	}
+}
+
+func TestSortPosts(t *testing.T) {
+	// in the descending file name order, as listed by parseAllPosts
+	posts := []post{
+		{Id: "zeta", Date: civil.Date{Year: 2024, Month: 1, Day: 1}},
+		{Id: "theta"},
+		{Id: "gamma", Date: civil.Date{Year: 2024, Month: 3, Day: 1}},
+		{Id: "delta", Date: civil.Date{Year: 2024, Month: 3, Day: 1}, Time: civil.Time{Hour: 8}},
+		{Id: "beta", Date: civil.Date{Year: 2024, Month: 3, Day: 1}},
+		{Id: "alpha"},
+	}
+	ids := func(posts []post) []string {
+		var ids []string
+		for _, p := range posts {
+			ids = append(ids, p.Id)
+		}
+		return ids
+	}
+	testCases := []struct {
+		name         string
+		order        postOrder
+		undatedPosts undatedPostPolicy
+		expected     []string
+	}{
+		{"file name order", FileNamePostOrder, FileNameUndatedPostPolicy, []string{"zeta", "theta", "gamma", "delta", "beta", "alpha"}},
+		{"date order, undated posts at the bottom", DateTimePostOrder, BottomUndatedPostPolicy, []string{"delta", "gamma", "beta", "zeta", "theta", "alpha"}},
+		{"date order, undated posts in place", DateTimePostOrder, FileNameUndatedPostPolicy, []string{"delta", "theta", "gamma", "beta", "zeta", "alpha"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := defaultConfig()
+			config.postOrder = tc.order
+			config.undatedPosts = tc.undatedPosts
+			sorted := ids(sortPosts(slices.Clone(posts), config))
+			if !slices.Equal(sorted, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, sorted)
+			}
+		})
+	}
+}
+
+func TestInspectPostOrderMismatches(t *testing.T) {
+	posts := []post{
+		{Id: "2024-03-post", Date: civil.Date{Year: 2024, Month: 3, Day: 1}},
+		{Id: "2024-02-post", Date: civil.Date{Year: 2024, Month: 4, Day: 1}, Time: civil.Time{Hour: 9, Minute: 30}},
+		{Id: "2024-01-undated"},
+		{Id: "2024-01-post", Date: civil.Date{Year: 2024, Month: 1, Day: 1}},
+	}
+	mismatches := inspectPostOrderMismatches(posts)
+	if len(mismatches) != 1 {
+		t.Fatalf("expected 1 mismatch, got %d: %v", len(mismatches), mismatches)
+	}
+	verifyStringContains(mismatches[0], "posts/2024-03-post.md (2024-03-01) comes before posts/2024-02-post.md (2024-04-01 09:30)", t)
+}
+
 func TestInspectTagTitleDuplicates(t *testing.T) {
 	posts := []post{
 		{Id: "a", Tags: []string{"Three Word Tag", "Books"}},
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"sort"
+)
+
+// postRelationKeys returns the keys relating the given post to the other ones:
+// its (normalized) tags and collection items
+func postRelationKeys(p post) []string {
+	var keys []string
+	for _, tag := range p.Tags {
+		keys = append(keys, deployTagsDirName+"/"+normalizeURIString(tag))
+	}
+	for _, ref := range p.Collections {
+		keys = append(keys, deployCollectionsDirName+"/"+normalizeURIString(ref.Collection)+"/"+normalizeURIString(ref.Item))
+	}
+	return keys
+}
+
+// buildRelatedPosts returns (up to relatedPostCnt) posts related to each one of the given (ordered) posts:
+// the more tags and collection items a post shares with the given one, the more related it is,
+// while the ties are resolved in favour of the posts closer in the post order (the newer one first)
+func buildRelatedPosts(posts []post, relatedPostCnt int) [][]post {
+	related := make([][]post, len(posts))
+	if relatedPostCnt <= 0 {
+		return related
+	}
+	postKeys := make([][]string, len(posts))
+	keyPosts := make(map[string][]int)
+	for i, p := range posts {
+		seen := make(map[string]struct{})
+		for _, key := range postRelationKeys(p) {
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			postKeys[i] = append(postKeys[i], key)
+			keyPosts[key] = append(keyPosts[key], i)
+		}
+	}
+	for i := range posts {
+		scores := make(map[int]int)
+		for _, key := range postKeys[i] {
+			for _, j := range keyPosts[key] {
+				if j != i {
+					scores[j]++
+				}
+			}
+		}
+		candidates := make([]int, 0, len(scores))
+		for j := range scores {
+			candidates = append(candidates, j)
+		}
+		distance := func(j int) int {
+			if j < i {
+				return i - j
+			}
+			return j - i
+		}
+		sort.Slice(candidates, func(a, b int) bool {
+			ja, jb := candidates[a], candidates[b]
+			if scores[ja] != scores[jb] {
+				return scores[ja] > scores[jb]
+			}
+			if distance(ja) != distance(jb) {
+				return distance(ja) < distance(jb)
+			}
+			return ja < jb
+		})
+		for _, j := range candidates[:min(relatedPostCnt, len(candidates))] {
+			related[i] = append(related[i], posts[j])
+		}
+	}
+	return related
+}
+
+// buildPostNavs builds the navigation data for each one of the given (ordered) posts:
+// the posts are ordered from the newest to the oldest one, so the previous post is the following one in the list
+// (and vice versa); nil is returned for the posts with nothing to link to
+func buildPostNavs(posts []post, relatedPostCnt int) []*postNavData {
+	navs := make([]*postNavData, len(posts))
+	related := buildRelatedPosts(posts, relatedPostCnt)
+	series := buildPostSeries(posts)
+	for i := range posts {
+		nav := postNavData{Related: related[i], Series: series[i]}
+		if i > 0 {
+			nav.Next = &posts[i-1]
+		}
+		if i < len(posts)-1 {
+			nav.Prev = &posts[i+1]
+		}
+		if nav.Prev != nil || nav.Next != nil || len(nav.Related) > 0 || nav.Series != nil {
+			navs[i] = &nav
+		}
+	}
+	return navs
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildPostNavs(t *testing.T) {
+	posts := []post{{Id: "p3"}, {Id: "p2"}, {Id: "p1"}}
+	navs := buildPostNavs(posts, 0)
+	if navs[0].Next != nil || navs[0].Prev.Id != "p2" {
+		t.Errorf("unexpected newest post navigation: %+v", navs[0])
+	}
+	if navs[1].Next.Id != "p3" || navs[1].Prev.Id != "p1" {
+		t.Errorf("unexpected middle post navigation: %+v", navs[1])
+	}
+	if navs[2].Next.Id != "p2" || navs[2].Prev != nil {
+		t.Errorf("unexpected oldest post navigation: %+v", navs[2])
+	}
+	if navs := buildPostNavs(posts[:1], 3); navs[0] != nil {
+		t.Errorf("expected no navigation for a single post, got: %+v", navs[0])
+	}
+}
+
+func TestBuildRelatedPosts(t *testing.T) {
+	posts := []post{
+		{Id: "p6", Tags: []string{"Go"}},
+		{Id: "p5", Tags: []string{"go", "Web"}, Collections: []postCollectionRef{{Collection: "Books", Item: "Dune"}}},
+		{Id: "p4", Tags: []string{"Misc"}},
+		{Id: "p3", Tags: []string{"Go", "Web"}, Collections: []postCollectionRef{{Collection: "books", Item: "dune"}}},
+		{Id: "p2", Tags: []string{"Web"}},
+		{Id: "p1", Tags: []string{"Go"}},
+	}
+	ids := func(related []post) []string {
+		var ids []string
+		for _, p := range related {
+			ids = append(ids, p.Id)
+		}
+		return ids
+	}
+	related := buildRelatedPosts(posts, 3)
+	// p5 shares 2 tags and a collection item with p3, then p3 is equally related to p2 (Web) and p6/p1 (Go),
+	// with the closest ones in the post order (p2, p1) coming first
+	verifyStringsEqual(strings.Join(ids(related[3]), " "), "p5 p2 p1", t)
+	verifyStringsEqual(strings.Join(ids(related[0]), " "), "p5 p3 p1", t)
+	if len(related[2]) != 0 {
+		t.Errorf("expected no related posts for p4, got: %v", ids(related[2]))
+	}
+	if related := buildRelatedPosts(posts, 0); related[3] != nil {
+		t.Errorf("expected no related posts when disabled, got: %v", ids(related[3]))
+	}
+}
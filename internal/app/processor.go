@@ -19,7 +19,90 @@ import (
 
 func process(pages []page, posts []post,
 	resLoader resourceLoader, handleOutput processorOutputHandler) stats {
+	config := resLoader.config
+	homePages := resolveHomePages(pages, config)
+	if len(config.languages) > 0 && config.homePage != "" && len(pages) > 0 && len(homePages) == 0 {
+		exitWithError(fmt.Sprintf("home page not found: '%s'", config.homePage))
+	}
+	linkTranslations(pages, posts, homePages, config)
+	contentLangs := make(map[string]string)
+	for _, p := range pages {
+		contentLangs[deployPageDirName+"/"+p.Id] = contentLanguage(p.Lang, config)
+	}
+	for _, p := range posts {
+		contentLangs[deployPostDirName+"/"+p.Id] = contentLanguage(p.Lang, config)
+	}
+	var pStats stats
+	var sitemapEntries []sitemapEntry
+	for _, lang := range siteLanguages(config) {
+		langConfig := config
+		langConfig.lang = lang
+		if len(config.languages) > 0 {
+			langConfig.homePage = homePages[lang]
+			langConfig.translations = loadTranslations(lang, resLoader)
+		}
+		langResLoader := resLoader
+		langResLoader.config = langConfig
+		var langPages []page
+		for _, p := range pages {
+			if contentLanguage(p.Lang, config) == lang {
+				p.Body = localizeContentLinks(p.Body, contentLangs, langConfig)
+				langPages = append(langPages, p)
+			}
+		}
+		var langPosts []post
+		for _, p := range posts {
+			if contentLanguage(p.Lang, config) == lang {
+				p.Body = localizeContentLinks(p.Body, contentLangs, langConfig)
+				p.langURIPrefix = langURIPrefix(lang, config)
+				langPosts = append(langPosts, p)
+			}
+		}
+		if len(config.languages) > 0 && len(langPages) == 0 && len(langPosts) == 0 {
+			continue
+		}
+		if len(config.languages) > 0 {
+			sprintln(" - processing language: " + lang)
+		}
+		handleLangOutput := handleOutput
+		if handleOutput != nil {
+			handleLangOutput = func(outputFilePath string, data []byte) bool {
+				return handleOutput(localizeOutputFilePath(outputFilePath, langConfig), data)
+			}
+		}
+		langStats, langSitemapEntries := processLanguage(langPages, langPosts, langResLoader, handleLangOutput)
+		pStats.pageCnt += langStats.pageCnt
+		pStats.postCnt += langStats.postCnt
+		pStats.tagCnt += langStats.tagCnt
+		pStats.collCnt += langStats.collCnt
+		pStats.collItemCnt += langStats.collItemCnt
+		sitemapEntries = append(sitemapEntries, langSitemapEntries...)
+	}
+	if config.generateSitemap {
+		generateSitemap(sitemapEntries, resLoader, handleOutput)
+	}
+	return pStats
+}
+
+// processLanguage processes the given pages and posts of a single language (the one of the given resource loader config),
+// returning the stats along with the (language URI prefixed) sitemap entries of the generated content
+func processLanguage(pages []page, posts []post,
+	resLoader resourceLoader, handleOutput processorOutputHandler) (stats, []sitemapEntry) {
 	var searchIndex = mapSlice{}
+	// record the generated content URIs for the sitemap
+	var outputURIs []string
+	handleContentOutput := handleOutput
+	if resLoader.config.generateSitemap {
+		handleContentOutput = func(outputFilePath string, data []byte) bool {
+			if strings.HasSuffix(outputFilePath, contentFileExtension) {
+				outputURIs = append(outputURIs, outputFilePathToURI(outputFilePath))
+			}
+			if handleOutput != nil {
+				return handleOutput(outputFilePath, data)
+			}
+			return false
+		}
+	}
 	// aggregate collections up front: embedded collection views on pages,
 	// post footer back-links, and collection page generation all depend on the aggregated model
 	collections := aggregateCollections(posts)
@@ -39,25 +122,35 @@ func process(pages []page, posts []post,
 		posts[i].collItemPostCnt = itemPostCnt
 		for _, title := range posts[i].MetaCollections {
 			if mc, ok := metaColls[normalizeURIString(title)]; ok {
-				link := "/" + deployPageDirName + "/" + mc.PageId + contentFileExtension
+				link := langURIPrefix(resLoader.config.lang, resLoader.config) + "/" + deployPageDirName + "/" + mc.PageId + contentFileExtension
 				if mc.PageId == resLoader.config.homePage {
-					link = "/"
+					link = langURIPrefix(resLoader.config.lang, resLoader.config) + "/"
 				}
 				posts[i].metaCollGroups = append(posts[i].metaCollGroups, postCollectionGroup{Title: mc.Title, Link: link})
 			}
 		}
 	}
-	pageCnt := processPages(pages, collections, &searchIndex, resLoader, handleOutput)
-	postCnt, tagCnt, collCnt, collItemCnt := processPosts(posts, collections, &searchIndex, resLoader, handleOutput)
+	pageCnt := processPages(pages, collections, &searchIndex, resLoader, handleContentOutput)
+	postCnt, tagCnt, collCnt, collItemCnt := processPosts(posts, collections, &searchIndex, resLoader, handleContentOutput)
 	config := resLoader.config
 	if len(config.generateFeeds) > 0 {
 		generateFeeds(posts, config, handleOutput)
 	}
+	var sitemapEntries []sitemapEntry
+	if config.generateSitemap {
+		sitemapEntries = buildSitemapEntries(outputURIs, pages, posts, config)
+		for i := range sitemapEntries {
+			sitemapEntries[i].uri = langURIPrefix(config.lang, config) + sitemapEntries[i].uri
+		}
+	}
+	if config.generateAPI {
+		generateAPI(pages, posts, collections, resLoader, handleOutput)
+	}
 	if config.enableSearch {
 		sprintln(" - generating search files ...")
 		searchIndexJson, err := json.Marshal(searchIndex)
 		check(err)
-		searchIndexOutputFilePath := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, searchIndexFileName)
+		searchIndexOutputFilePath := localizeOutputFilePath(fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, searchIndexFileName), config)
 		writeDataToFileIfChanged(searchIndexOutputFilePath, searchIndexJson)
 		searchTemplate := compileSearchTemplate(resLoader)
 		outputFilePath := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, searchPageFileName)
@@ -74,7 +167,7 @@ func process(pages []page, posts []post,
 		tagCnt:      tagCnt,
 		collCnt:     collCnt,
 		collItemCnt: collItemCnt,
-	}
+	}, sitemapEntries
 }
 
 func processPages(pages []page, collections []collectionData, searchIndex *mapSlice,
@@ -98,14 +191,16 @@ func processPages(pages []page, collections []collectionData, searchIndex *mapSl
 			}
 		}
 
-		for _, page := range pages {
+		// the pages are rendered in parallel, then handled in the page order
+		outputs := make([]renderedOutput, len(pages))
+		forEachParallel(len(pages), workerPoolSize(resLoader.config), func(i int) {
+			page := pages[i]
 			// a page embedding collections via {collection:...} directives is always (re)processed
 			// — its output depends on post data, not just its own file
 			if !page.skipProcessing || len(page.CollectionRefs) > 0 {
 				if len(page.CollectionRefs) > 0 {
 					page.Body = renderEmbeddedCollections(page.Body, collections, resLoader)
 				}
-				pageTemplate := compilePageTemplate(page, resLoader)
 				outputFileName := page.Id + contentFileExtension
 				var outputFilePath string
 				if homePage == page.Id {
@@ -119,14 +214,19 @@ func processPages(pages []page, collections []collectionData, searchIndex *mapSl
 					pTitle = title + " - " + page.Title
 				}
 
-				var pageContentBuffer bytes.Buffer
-				err := pageTemplate.Execute(&pageContentBuffer, templateContent{EntityType: Page, Title: pTitle, FileName: outputFileName, Content: page, Config: buildTemplateConfigMap(resLoader.config)})
-				check(err)
+				data := renderOrReuseOutput(outputFilePath, func() string { return hashJSON(page, pTitle) }, resLoader.config, func() []byte {
+					pageTemplate := compilePageTemplate(page, resLoader)
+					var pageContentBuffer bytes.Buffer
+					err := pageTemplate.Execute(&pageContentBuffer, templateContent{EntityType: Page, Title: pTitle, FileName: outputFileName, Content: page, Meta: buildPageMetaData(page, resLoader.config), Config: buildTemplateConfigMap(resLoader.config)})
+					check(err)
+					return pageContentBuffer.Bytes()
+				})
 
-				if handleOutput != nil {
-					handleOutput(outputFilePath, pageContentBuffer.Bytes())
-				}
+				outputs[i] = renderedOutput{filePath: outputFilePath, data: data}
 			}
+		})
+		handleRenderedOutputs(outputs, handleOutput)
+		for _, page := range pages {
 			*searchIndex = append(*searchIndex, mapItem{Key: page.SearchData.TypeId, Value: page.SearchData.Content})
 		}
 	}
@@ -159,8 +259,8 @@ func processPosts(posts []post, collections []collectionData, searchIndex *mapSl
 		ppd := pagerData{
 			CurrPageNum:   1,
 			TotalPageCnt:  totalPageCnt,
-			PageUriPrefix: "/" + deployPostsDirName,
-			IndexPageUri:  "/",
+			PageUriPrefix: langURIPrefix(config.lang, config) + "/" + deployPostsDirName,
+			IndexPageUri:  langURIPrefix(config.lang, config) + "/",
 		}
 
 		if homePage != "" {
@@ -182,8 +282,23 @@ func processPosts(posts []post, collections []collectionData, searchIndex *mapSl
 		collPostCnt := make(map[string]int)
 		collContent := make(map[string][]string)
 
-		for _, post := range posts {
-			pagePostCnt++
+		// the rendered post listing content, in the post order
+		// (the series pages list the posts in the series order, so they're looked up by index)
+		postContents := make([]string, len(posts))
+
+		postNavs := buildPostNavs(posts, config.relatedPostCnt)
+
+		// the posts (their listing content along with the single post files) are rendered in parallel,
+		// then handled in the post order
+		singlePostOutputs := make([]renderedOutput, len(posts))
+		// the album pages of the posts with gallery directives (if enabled)
+		albumOutputs := make([]renderedOutput, len(posts))
+		var albumTemplate *template.Template
+		if config.generateAlbums {
+			albumTemplate = compileAlbumTemplate(resLoader)
+		}
+		forEachParallel(len(posts), workerPoolSize(config), func(i int) {
+			post := posts[i]
 			pTitle := title
 			if post.Title != "" {
 				pTitle = title + " - " + post.Title
@@ -194,43 +309,63 @@ func processPosts(posts []post, collections []collectionData, searchIndex *mapSl
 			err := postContentTemplate.Execute(&postContentBuffer, templateContent{EntityType: Post, Title: pTitle, Content: post, FileName: outputFileName, Config: buildTemplateConfigMap(resLoader.config)})
 			check(err)
 
-			postContent := strings.TrimSpace(postContentBuffer.String())
+			// the heading IDs are prefixed with the post id, as the listing pages concatenate the content of multiple posts
+			postContents[i] = prefixHeadingIDs(strings.TrimSpace(postContentBuffer.String()), post.Id+headingIDPrefixSeparator)
 
-			postPageContent += postContent
+			// a single post file links to the neighbouring/related posts, which may have changed
+			// even if the post itself hasn't, so it's rendered either way (unchanged files aren't re-written)
+			if !post.skipProcessing || postNavs[i] != nil {
+				outputFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployPostDirName, os.PathSeparator, outputFileName)
+				data := renderOrReuseOutput(outputFilePath, func() string {
+					// the collection footer groups are resolved during processing (out of the other posts too)
+					return hashJSON(post, post.CollectionGroups(), postNavs[i], pTitle)
+				}, config, func() []byte {
+					return renderSinglePost(post, pTitle, postNavs[i], postContentTemplate, resLoader)
+				})
+				singlePostOutputs[i] = renderedOutput{filePath: outputFilePath, data: data}
+			}
 
-			if !post.skipProcessing {
-				var singlePostContentBuffer bytes.Buffer
-				err = postContentTemplate.Execute(&singlePostContentBuffer, templateContent{EntityType: Post, Title: pTitle, Content: post, Config: buildTemplateConfigMap(resLoader.config)})
-				check(err)
+			if config.generateAlbums && len(post.GalleryMedia) > 0 && !post.skipProcessing {
+				outputFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployAlbumsDirName, os.PathSeparator, outputFileName)
+				data := renderOrReuseOutput(outputFilePath, func() string {
+					return hashJSON(post, pTitle)
+				}, config, func() []byte {
+					var albumBuffer bytes.Buffer
+					err := albumTemplate.Execute(&albumBuffer, templateContent{EntityType: Post, Title: pTitle, Content: post, FileName: outputFileName, Config: buildTemplateConfigMap(config)})
+					check(err)
+					return albumBuffer.Bytes()
+				})
+				albumOutputs[i] = renderedOutput{filePath: outputFilePath, data: data}
+			}
+		})
+		handleRenderedOutputs(singlePostOutputs, handleOutput)
+		handleRenderedOutputs(albumOutputs, handleOutput)
 
-				fullTemplate := compileFullTemplate(outputFileName, singlePostContentBuffer.String(), nil, resLoader)
+		// the post listing pages (as well as the archive, tag, series and collection ones) are rendered in parallel as well
+		var listingPages []contentPage
 
-				var singlePostFullContentBuffer bytes.Buffer
-				err = fullTemplate.Execute(&singlePostFullContentBuffer, templateContent{EntityType: Post, Title: pTitle, Content: post, Config: buildTemplateConfigMap(resLoader.config)})
-				check(err)
+		for i, post := range posts {
+			pagePostCnt++
+			postContent := postContents[i]
 
-				outputFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployPostDirName, os.PathSeparator, outputFileName)
-				if handleOutput != nil {
-					handleOutput(outputFilePath, singlePostFullContentBuffer.Bytes())
-				}
-			}
+			postPageContent += postContent
 
 			if pagePostCnt == pageSize {
 				var pagerBuffer bytes.Buffer
-				err = pagerTemplate.Execute(&pagerBuffer, ppd)
+				err := pagerTemplate.Execute(&pagerBuffer, ppd)
 				check(err)
 				postPageContent += pagerBuffer.String()
 				if ppd.CurrPageNum == 1 {
 					if homePage == "" {
 						outputFilePath := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, indexPageFileName)
-						processContent(indexPageFileName, Post, config.siteName, postPageContent, outputFilePath, resLoader, handleOutput)
+						listingPages = append(listingPages, contentPage{templateName: indexPageFileName, title: config.siteName, content: postPageContent, outputFilePath: outputFilePath})
 					}
 					outputFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployPostsDirName, os.PathSeparator, indexPageFileName)
-					processContent(deployPostsDirName+"/"+indexPageFileName, Post, config.siteName, postPageContent, outputFilePath, resLoader, handleOutput)
+					listingPages = append(listingPages, contentPage{templateName: deployPostsDirName + "/" + indexPageFileName, title: config.siteName, content: postPageContent, outputFilePath: outputFilePath})
 				} else {
 					fileName := strconv.Itoa(ppd.CurrPageNum) + contentFileExtension
 					outputFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployPostsDirName, os.PathSeparator, fileName)
-					processContent(fileName, Post, config.siteName, postPageContent, outputFilePath, resLoader, handleOutput)
+					listingPages = append(listingPages, contentPage{templateName: fileName, title: config.siteName, content: postPageContent, outputFilePath: outputFilePath})
 				}
 				pagePostCnt = 0
 				postPageContent = ""
@@ -310,10 +445,10 @@ func processPosts(posts []post, collections []collectionData, searchIndex *mapSl
 			if ppd.CurrPageNum == 1 {
 				if homePage == "" {
 					outputFilePath := fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, indexPageFileName)
-					processContent(indexPageFileName, Post, config.siteName, postPageContent, outputFilePath, resLoader, handleOutput)
+					listingPages = append(listingPages, contentPage{templateName: indexPageFileName, title: config.siteName, content: postPageContent, outputFilePath: outputFilePath})
 				}
 				outputFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployPostsDirName, os.PathSeparator, indexPageFileName)
-				processContent(deployPostsDirName+"/"+indexPageFileName, Post, config.siteName, postPageContent, outputFilePath, resLoader, handleOutput)
+				listingPages = append(listingPages, contentPage{templateName: deployPostsDirName + "/" + indexPageFileName, title: config.siteName, content: postPageContent, outputFilePath: outputFilePath})
 			} else {
 				fileName := strconv.Itoa(ppd.CurrPageNum) + contentFileExtension
 				outputFilePath := fmt.Sprintf("%s%c%s%c%s", deployDirName, os.PathSeparator, deployPostsDirName, os.PathSeparator, fileName)
@@ -321,9 +456,10 @@ func processPosts(posts []post, collections []collectionData, searchIndex *mapSl
 				err := pagerTemplate.Execute(&pagerBuffer, ppd)
 				check(err)
 				postPageContent += pagerBuffer.String()
-				processContent(fileName, Post, config.siteName, postPageContent, outputFilePath, resLoader, handleOutput)
+				listingPages = append(listingPages, contentPage{templateName: fileName, title: config.siteName, content: postPageContent, outputFilePath: outputFilePath})
 			}
 		}
+		processContentPages(listingPages, resLoader, handleOutput)
 
 		if config.generateArchive && len(archivePostCnt) > 0 {
 			sprintln(" - generating archive ...")
@@ -389,6 +525,19 @@ func processPosts(posts []post, collections []collectionData, searchIndex *mapSl
 			processPaginatedPostContent(tagPostCnt, tagContent, pageSize, deployTagsDirName, pagerTemplate, resLoader, handleOutput)
 		}
 
+		seriesPostCnt := make(map[string]int)
+		seriesContent := make(map[string][]string)
+		for uri, idxs := range groupSeriesPosts(posts) {
+			seriesPostCnt[uri] = len(idxs)
+			for _, idx := range idxs {
+				seriesContent[uri] = append(seriesContent[uri], postContents[idx])
+			}
+		}
+		if len(seriesPostCnt) > 0 {
+			sprintln(" - generating series pages ...")
+			processPaginatedPostContent(seriesPostCnt, seriesContent, pageSize, deploySeriesDirName, pagerTemplate, resLoader, handleOutput)
+		}
+
 		collCnt, collItemCnt = processCollections(collections, collPostCnt, collContent, pagerTemplate, resLoader, handleOutput)
 	}
 	return len(posts), tagCnt, collCnt, collItemCnt
@@ -399,12 +548,19 @@ func processPaginatedPostContent(postCnt map[string]int, content map[string][]st
 	resLoader resourceLoader, handleOutput processorOutputHandler) {
 	postCntLen := len(postCnt)
 	if postCntLen > 0 {
-		for key, cnt := range postCnt {
+		keys := make([]string, 0, postCntLen)
+		for key := range postCnt {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var contentPages []contentPage
+		for _, key := range keys {
+			cnt := postCnt[key]
 			totalPageCnt := cnt / pageSize
 			if cnt%pageSize > 0 {
 				totalPageCnt++
 			}
-			pdIndexUri := "/" + contentDeployDirName + "/" + key
+			pdIndexUri := langURIPrefix(resLoader.config.lang, resLoader.config) + "/" + contentDeployDirName + "/" + key
 			pd := pagerData{
 				CurrPageNum:   1,
 				TotalPageCnt:  totalPageCnt,
@@ -429,7 +585,7 @@ func processPaginatedPostContent(postCnt map[string]int, content map[string][]st
 					}
 					// key may contain a nested sub-path (e.g. collection/item), so join portably
 					outputFilePath := filepath.Join(deployDirName, contentDeployDirName, filepath.FromSlash(key), fileName)
-					processContent(fileName, Post, resLoader.config.siteName+" - "+key, pageContent, outputFilePath, resLoader, handleOutput)
+					contentPages = append(contentPages, contentPage{templateName: fileName, title: resLoader.config.siteName + " - " + key, content: pageContent, outputFilePath: outputFilePath})
 					pagePostCnt = 0
 					pageContent = ""
 					pd.CurrPageNum++
@@ -447,19 +603,76 @@ func processPaginatedPostContent(postCnt map[string]int, content map[string][]st
 					pageContent += pagerBuffer.String()
 				}
 				outputFilePath := filepath.Join(deployDirName, contentDeployDirName, filepath.FromSlash(key), fileName)
-				processContent(fileName, Post, resLoader.config.siteName+" - "+key, pageContent, outputFilePath, resLoader, handleOutput)
+				contentPages = append(contentPages, contentPage{templateName: fileName, title: resLoader.config.siteName + " - " + key, content: pageContent, outputFilePath: outputFilePath})
 			}
 		}
+		processContentPages(contentPages, resLoader, handleOutput)
 	}
 }
 
-func processContent(templateName string, ceType contentEntityType, title string, content string, outputFilePath string, resLoader resourceLoader, handleOutput processorOutputHandler) {
-	tmplt := compileFullTemplate(templateName, content, nil, resLoader)
-	var contentBuffer bytes.Buffer
-	err := tmplt.Execute(&contentBuffer, templateContent{EntityType: ceType, Title: title, Config: buildTemplateConfigMap(resLoader.config)})
+// renderSinglePost renders the full (main template based) single post page
+func renderSinglePost(post post, title string, nav *postNavData, postContentTemplate *template.Template, resLoader resourceLoader) []byte {
+	meta := buildPostMetaData(post, resLoader.config)
+
+	var singlePostContentBuffer bytes.Buffer
+	err := postContentTemplate.Execute(&singlePostContentBuffer, templateContent{EntityType: Post, Title: title, Content: post, Meta: meta, Nav: nav, Config: buildTemplateConfigMap(resLoader.config)})
+	check(err)
+
+	fullTemplate := compileFullTemplate(post.Id+contentFileExtension, singlePostContentBuffer.String(), nil, resLoader)
+
+	var singlePostFullContentBuffer bytes.Buffer
+	err = fullTemplate.Execute(&singlePostFullContentBuffer, templateContent{EntityType: Post, Title: title, Content: post, Meta: meta, Nav: nav, Config: buildTemplateConfigMap(resLoader.config)})
 	check(err)
-	if handleOutput != nil {
-		handleOutput(outputFilePath, contentBuffer.Bytes())
+
+	return singlePostFullContentBuffer.Bytes()
+}
+
+// renderPendingPostPreview renders the single post page of a post pending publication
+// (a draft or a scheduled post) in memory, without writing anything to the deploy dir;
+// returns nil if there's no such post pending publication
+func renderPendingPostPreview(postId string, config appConfig, resLoader resourceLoader) []byte {
+	mdPostFilePath := fmt.Sprintf("%s%c%s", markdownPostsDirName, os.PathSeparator, postId+markdownFileExtension)
+	if !fileExists(mdPostFilePath) {
+		return nil
+	}
+	post := parsePost(postId, string(readDataFromFile(mdPostFilePath)), config, resLoader)
+	if !post.isPendingAt(time.Now()) {
+		return nil
+	}
+	title := config.siteName
+	if post.Title != "" {
+		title += " - " + post.Title
+	}
+	return renderSinglePost(post, title, nil, compilePostTemplate(resLoader), resLoader)
+}
+
+// processContentPages renders the given post listing pages in parallel, then handles them in the given order
+func processContentPages(contentPages []contentPage, resLoader resourceLoader, handleOutput processorOutputHandler) {
+	outputs := make([]renderedOutput, len(contentPages))
+	forEachParallel(len(contentPages), workerPoolSize(resLoader.config), func(i int) {
+		cp := contentPages[i]
+		data := renderOrReuseOutput(cp.outputFilePath, func() string { return hashStrings(cp.templateName, cp.title, cp.content) }, resLoader.config, func() []byte {
+			tmplt := compileFullTemplate(cp.templateName, cp.content, nil, resLoader)
+			var contentBuffer bytes.Buffer
+			err := tmplt.Execute(&contentBuffer, templateContent{EntityType: Post, Title: cp.title, Config: buildTemplateConfigMap(resLoader.config)})
+			check(err)
+			return contentBuffer.Bytes()
+		})
+		outputs[i] = renderedOutput{filePath: cp.outputFilePath, data: data}
+	})
+	handleRenderedOutputs(outputs, handleOutput)
+}
+
+// handleRenderedOutputs hands the given rendered outputs over to the output handler, in the given order
+// (skipping the empty slots of the content that didn't need to be rendered)
+func handleRenderedOutputs(outputs []renderedOutput, handleOutput processorOutputHandler) {
+	if handleOutput == nil {
+		return
+	}
+	for _, output := range outputs {
+		if output.filePath != "" {
+			handleOutput(output.filePath, output.data)
+		}
 	}
 }
 
@@ -486,7 +699,7 @@ func collapseBlankLines(data []byte) []byte {
 func processAndHandleStats(config appConfig, resLoader resourceLoader, useCache bool) {
 	generatedCnt := 0
 	pages := parsePages(config, resLoader, processImgThumbnails, useCache)
-	posts := parsePosts(config, resLoader, processImgThumbnails, useCache)
+	posts, pendingPosts := splitPendingPosts(parseAllPosts(config, resLoader, processImgThumbnails, useCache), time.Now())
 	pStats := process(
 		pages,
 		posts,
@@ -507,14 +720,31 @@ func processAndHandleStats(config appConfig, resLoader resourceLoader, useCache
 			}
 			return generated
 		})
+	pStats.pendingCnt = len(pendingPosts)
 	pStats.genCnt = generatedCnt
+	writeGalleryJS(pages, posts)
 	sharedMediaDirPath := fmt.Sprintf("%s%c%s%c%s",
 		deployDirName, os.PathSeparator, mediaDirName, os.PathSeparator, sharedMediaDirName)
 	processImgThumbnails(sharedMediaDirPath, config)
 	handleStats(pStats)
 	// surface content-directive warnings at the very end so they are not buried
-	// under the per-file "generated file:" output
-	reportContentWarnings(pages, posts)
+	// under the per-file "generated file:" output; posts pending publication are included,
+	// so that their issues get fixed before they go live
+	validateContentLinks(pages, posts, pendingPosts)
+	reportContentWarnings(pages, slices.Concat(posts, pendingPosts))
+}
+
+// writeGalleryJS writes the gallery (lightbox) script into the deploy resources dir
+// if any of the given pages/posts has a gallery, deleting it otherwise
+func writeGalleryJS(pages []page, posts []post) {
+	galleryJSFilePath := filepath.Join(deployDirName, resourcesDirName, galleryJSFileName)
+	if slices.ContainsFunc(pages, func(p page) bool { return len(p.GalleryMedia) > 0 }) ||
+		slices.ContainsFunc(posts, func(p post) bool { return len(p.GalleryMedia) > 0 }) {
+		createDirIfNotExists(filepath.Dir(galleryJSFilePath))
+		writeDataToFileIfChanged(galleryJSFilePath, []byte(galleryJS))
+	} else {
+		deleteIfExists(galleryJSFilePath)
+	}
 }
 
 // reportContentWarnings prints any accumulated content-directive warnings (malformed captions,
@@ -577,7 +807,7 @@ func generateFeeds(posts []post, config appConfig, handleOutput processorOutputH
 
 	feed := &feeds.Feed{
 		Title:       feedTitle,
-		Link:        &feeds.Link{Href: config.siteBaseURL},
+		Link:        &feeds.Link{Href: config.siteBaseURL + langURIPrefix(config.lang, config)},
 		Description: feedDescription,
 		Created:     feedCreated,
 		Updated:     feedUpdated,
@@ -604,7 +834,7 @@ func generateFeeds(posts []post, config appConfig, handleOutput processorOutputH
 		itemContent := buildFeedItemExcerpt(p, config)
 
 		// construct item URL (deployPostDirName is "post", not "deploy/post")
-		itemURL := fmt.Sprintf("%s/%s/%s%s", config.siteBaseURL, deployPostDirName, p.Id, contentFileExtension)
+		itemURL := fmt.Sprintf("%s%s/%s/%s%s", config.siteBaseURL, langURIPrefix(config.lang, config), deployPostDirName, p.Id, contentFileExtension)
 
 		// create timestamp using date at noon UTC for better timezone compatibility
 		createdTime := time.Date(
@@ -614,24 +844,14 @@ func generateFeeds(posts []post, config appConfig, handleOutput processorOutputH
 		)
 
 		// prepend image to content if post has images
-		mediaFileNames := listAllMedia(Post, p.Id, nil)
-		if len(mediaFileNames) > 0 {
-			mediaList := parseMediaFileNames(mediaFileNames, Post, p.Id, config, false, nil)
-
-			// read original markdown content to find first image reference
-			rawContent := getRawPostContent(p.Id)
-			if rawContent != "" {
-				firstImage := getFirstImageFromContent(rawContent, mediaList, config)
-				if firstImage != nil {
-					// prefer smallest thumbnail over original
-					imageUri := getSmallestThumbnailOrOriginal(*firstImage)
-					imageAbsoluteUrl := config.siteBaseURL + imageUri
-
-					// prepend image tag to content
-					imageTag := fmt.Sprintf(`<img src="%s" />`, imageAbsoluteUrl)
-					itemContent = imageTag + itemContent
-				}
-			}
+		if firstImage := getLeadImage(Post, p.Id, config); firstImage != nil {
+			// prefer smallest thumbnail over original
+			imageUri := getSmallestThumbnailOrOriginal(*firstImage)
+			imageAbsoluteUrl := config.siteBaseURL + imageUri
+
+			// prepend image tag to content
+			imageTag := fmt.Sprintf(`<img src="%s" />`, imageAbsoluteUrl)
+			itemContent = imageTag + itemContent
 		}
 
 		// create feed item
@@ -693,15 +913,39 @@ func buildFeedItemTitle(p post) string {
 // buildFeedItemExcerpt creates an HTML excerpt from the post's cleaned markdown content,
 // converts to HTML, and adds a "view on website" link
 func buildFeedItemExcerpt(p post, config appConfig) string {
+	excerptMarkdown := buildExcerptMarkdown(p.FeedContent)
+
+	// convert the markdown excerpt to HTML (preserves formatting)
+	var buf bytes.Buffer
+	err := markdown.Convert([]byte(excerptMarkdown), &buf)
+	check(err)
+	htmlExcerpt := strings.TrimSpace(buf.String())
+
+	// point the hashtag/search links to the ones of the post language
+	htmlExcerpt = localizeContentLinks(htmlExcerpt, nil, config)
+
+	// convert relative URLs to absolute (for hashtag links, etc.)
+	htmlExcerpt = convertRelativeURLsToAbsolute(htmlExcerpt, config.siteBaseURL)
+
+	// build the "view on website" link
+	postURL := fmt.Sprintf("%s%s/%s/%s%s", config.siteBaseURL, langURIPrefix(config.lang, config), deployPostDirName, p.Id, contentFileExtension)
+	continueLink := fmt.Sprintf(`<p><a href="%s">%s</a></p>`, postURL, config.feedPostViewOnWebsiteLinkText)
+
+	return htmlExcerpt + continueLink
+}
+
+// buildExcerptMarkdown extracts the first few sentences (or words, if no sentences are found)
+// from the given cleaned markdown content, ending with an ellipsis if the content was truncated
+func buildExcerptMarkdown(content string) string {
 	// extract first N sentences from the cleaned markdown
-	sentences := extractSentences(p.FeedContent, feedExcerptSentenceCnt)
+	sentences := extractSentences(content, feedExcerptSentenceCnt)
 
 	var excerptMarkdown string
 	if len(sentences) > 0 {
 		excerptMarkdown = strings.Join(sentences, " ")
 		// add ellipsis only if content was actually truncated
 		lastSentence := sentences[len(sentences)-1]
-		wasTruncated := !strings.HasSuffix(strings.TrimSpace(p.FeedContent), lastSentence)
+		wasTruncated := !strings.HasSuffix(strings.TrimSpace(content), lastSentence)
 		if wasTruncated {
 			// replace final punctuation (., !, or ?) with ellipsis
 			if strings.HasSuffix(excerptMarkdown, ".") ||
@@ -714,26 +958,13 @@ func buildFeedItemExcerpt(p post, config appConfig) string {
 		}
 	} else {
 		// fallback: use first N words if no sentences found
-		excerptMarkdown = extractFirstNWords(p.FeedContent, feedExcerptFallbackWordCnt)
-		if len(strings.Fields(p.FeedContent)) > feedExcerptFallbackWordCnt {
+		excerptMarkdown = extractFirstNWords(content, feedExcerptFallbackWordCnt)
+		if len(strings.Fields(content)) > feedExcerptFallbackWordCnt {
 			excerptMarkdown += "..."
 		}
 	}
 
-	// convert the markdown excerpt to HTML (preserves formatting)
-	var buf bytes.Buffer
-	err := markdown.Convert([]byte(excerptMarkdown), &buf)
-	check(err)
-	htmlExcerpt := strings.TrimSpace(buf.String())
-
-	// convert relative URLs to absolute (for hashtag links, etc.)
-	htmlExcerpt = convertRelativeURLsToAbsolute(htmlExcerpt, config.siteBaseURL)
-
-	// build the "view on website" link
-	postURL := fmt.Sprintf("%s/%s/%s%s", config.siteBaseURL, deployPostDirName, p.Id, contentFileExtension)
-	continueLink := fmt.Sprintf(`<p><a href="%s">%s</a></p>`, postURL, config.feedPostViewOnWebsiteLinkText)
-
-	return htmlExcerpt + continueLink
+	return excerptMarkdown
 }
 
 // extractSentences splits text into sentences and returns up to maxSentences
@@ -920,10 +1151,29 @@ func getFirstImageFromContent(content string, mediaList []media, config appConfi
 	return firstSharedExplicit // priority 3, or nil
 }
 
-// getRawPostContent reads the original markdown content of a post file
-func getRawPostContent(postId string) string {
-	postFilePath := filepath.Join(markdownPostsDirName, postId+markdownFileExtension)
-	data, err := os.ReadFile(postFilePath)
+// getLeadImage returns the lead (first) image of the given post/page (see getFirstImageFromContent),
+// or nil if it has no images
+func getLeadImage(ceType contentEntityType, ceId string, config appConfig) *media {
+	mediaFileNames := listAllMedia(ceType, ceId, nil)
+	if len(mediaFileNames) == 0 {
+		return nil
+	}
+	mediaList := parseMediaFileNames(mediaFileNames, ceType, ceId, config, false, nil, nil)
+	// read original markdown content to find first image reference
+	rawContent := getRawContent(ceType, ceId)
+	if rawContent == "" {
+		return nil
+	}
+	return getFirstImageFromContent(rawContent, mediaList, config)
+}
+
+// getRawContent reads the original markdown content of a post/page file
+func getRawContent(ceType contentEntityType, ceId string) string {
+	mdDirName := markdownPostsDirName
+	if ceType == Page {
+		mdDirName = markdownPagesDirName
+	}
+	data, err := os.ReadFile(filepath.Join(mdDirName, ceId+markdownFileExtension))
 	if err != nil {
 		return ""
 	}
@@ -932,7 +1182,11 @@ func getRawPostContent(postId string) string {
 
 func buildTemplateConfigMap(config appConfig) map[string]any {
 	configMap := map[string]any{
-		"PageSize": config.pageSize,
+		"PageSize":         config.pageSize,
+		"CodeHighlighting": config.codeHighlighting.String(),
+		"Lang":             config.lang,
+		"LangURIPrefix":    langURIPrefix(config.lang, config),
+		"Translations":     config.translations,
 	}
 	if len(config.generateFeeds) > 0 {
 		configMap["GenerateFeeds"] = config.generateFeeds
@@ -3,11 +3,13 @@ package app
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/civil"
 )
@@ -1718,6 +1720,56 @@ func TestGenerateWithCollectionDirective(t *testing.T) {
 	}
 }
 
+func TestGenerateAlbums(t *testing.T) {
+	galleryPost := post{
+		Id:           "gallery-post",
+		Title:        "Gallery Post",
+		Body:         "Gallery Post Body",
+		GalleryMedia: []media{img("/media/post/gallery-post/a.jpg"), img("/media/post/gallery-post/b.jpg")},
+	}
+	galleryPost.Date, _ = civil.ParseDate("2024-05-01")
+	plainPost := post{
+		Id:    "plain-post",
+		Title: "Plain Post",
+		Body:  "Plain Post Body",
+	}
+	plainPost.Date, _ = civil.ParseDate("2024-04-01")
+
+	config := defaultConfig()
+	config.enableSearch = false
+	config.siteName = testSiteName
+
+	albumFile := func(postId string) string {
+		return fmt.Sprintf("%s/%s/%s%s", deployDirName, deployAlbumsDirName, postId, contentFileExtension)
+	}
+
+	output := processOutput(nil, []post{galleryPost, plainPost}, nil, nil, config)
+	if _, ok := output[albumFile(galleryPost.Id)]; ok {
+		t.Error("album pages must not be generated unless enabled")
+	}
+
+	config.generateAlbums = true
+	output = processOutput(nil, []post{galleryPost, plainPost}, nil, nil, config)
+	albumContent, ok := output[albumFile(galleryPost.Id)]
+	if !ok {
+		t.Fatal("missing album output file")
+	}
+	for _, ec := range []string{
+		testSiteName + " - " + galleryPost.Title,
+		`class="gallery album-items"`,
+		`href="/media/post/gallery-post/a.jpg"`,
+		`href="/media/post/gallery-post/b.jpg"`,
+		`href="/` + deployPostDirName + `/gallery-post` + contentFileExtension + `"`,
+	} {
+		if !strings.Contains(albumContent, ec) {
+			missingExpectedContentError(t, "album", ec)
+		}
+	}
+	if _, ok := output[albumFile(plainPost.Id)]; ok {
+		t.Error("album page must not be generated for a post without galleries")
+	}
+}
+
 func TestGenerateWithMetaCollections(t *testing.T) {
 	buildContent := func(homePage string) (pages []page, posts []post, config appConfig) {
 		p := post{
@@ -1810,3 +1862,218 @@ func TestPageHeaderRendering(t *testing.T) {
 		t.Error("title-less page must not render an (empty) title header")
 	}
 }
+
+func TestHeadMetaDataRendering(t *testing.T) {
+	config := defaultConfig()
+	config.siteName = testSiteName
+	config.siteBaseURL = "https://example.com"
+	config.enableSearch = false
+
+	posts := []post{{Id: "hello", Title: "Hello", Description: `Say "hi" & <wave>`, Body: "body", FeedContent: "body"}}
+	output := processOutput(nil, posts, nil, nil, config)
+	html := output[deployDirName+"/"+deployPostDirName+"/hello.html"]
+	for _, expected := range []string{
+		`<meta name="description" content="Say &#34;hi&#34; &amp; &lt;wave&gt;" />`,
+		`<link rel="canonical" href="https://example.com/post/hello.html" />`,
+		`<meta property="og:type" content="article" />`,
+		`<meta property="og:title" content="Hello" />`,
+		`<meta name="twitter:card" content="summary" />`,
+		`<script type="application/ld+json">{"@context":"https://schema.org","@type":"BlogPosting"`,
+		`"description":"Say \"hi\" \u0026 \u003cwave\u003e"`,
+	} {
+		verifyStringContains(html, expected, t)
+	}
+}
+
+func TestSinglePostNavigationRendering(t *testing.T) {
+	config := defaultConfig()
+	config.siteName = testSiteName
+	config.enableSearch = false
+
+	posts := []post{
+		{Id: "post-3", Title: "Third", Body: "body 3", Tags: []string{"Go"}},
+		{Id: "post-2", Body: "body 2", Date: civil.Date{Year: 2024, Month: 2, Day: 1}},
+		{Id: "post-1", Title: "First", Body: "body 1", Tags: []string{"go"}},
+	}
+	output := processOutput(nil, posts, nil, nil, config)
+
+	middle := output[deployDirName+"/"+deployPostDirName+"/post-2.html"]
+	verifyStringContains(middle, `<a href="/post/post-1.html" class="prev" rel="prev"><i class="fa-solid fa-angle-left"></i> First</a>`, t)
+	verifyStringContains(middle, `<a href="/post/post-3.html" class="next" rel="next">Third <i class="fa-solid fa-angle-right"></i></a>`, t)
+	if strings.Contains(middle, `class="related"`) {
+		t.Error("post without tags should not list related posts")
+	}
+
+	newest := output[deployDirName+"/"+deployPostDirName+"/post-3.html"]
+	verifyStringContains(newest, `<a href="/post/post-2.html" class="prev" rel="prev"><i class="fa-solid fa-angle-left"></i> 2024-02-01</a>`, t)
+	verifyStringContains(newest, `<section class="related"><span class="label">Related posts:</span><ul><li><a href="/post/post-1.html">First</a></li></ul></section>`, t)
+	if strings.Contains(newest, `class="next"`) {
+		t.Error("newest post should not link to a next post")
+	}
+
+	if strings.Contains(output[deployDirName+"/"+indexPageFileName], `class="post-nav"`) {
+		t.Error("post listings should not render the post navigation")
+	}
+}
+
+func TestGenerateAPI(t *testing.T) {
+	config := defaultConfig()
+	config.enableSearch = false
+	config.generateAPI = true
+	config.apiDir = "data/v1"
+
+	posts := []post{{Id: "post-1", Title: "First", Body: "body 1", Tags: []string{"go"}}}
+	output := processOutput(nil, posts, nil, nil, config)
+
+	for _, path := range []string{
+		"data/v1/index.json",
+		"data/v1/post/post-1.json",
+		"data/v1/posts/index.json",
+		"data/v1/tags/index.json",
+		"data/v1/tags/go/index.json",
+		"data/v1/collections/index.json",
+	} {
+		if _, ok := output[deployDirName+"/"+path]; !ok {
+			t.Errorf("missing API output file: %s", path)
+		}
+	}
+	verifyStringContains(output[deployDirName+"/data/v1/post/post-1.json"], `"api":"/data/v1/post/post-1.json"`, t)
+
+	config.generateAPI = false
+	for path := range processOutput(nil, posts, nil, nil, config) {
+		if strings.HasPrefix(path, deployDirName+"/data/") {
+			t.Errorf("unexpected API output file: %s", path)
+		}
+	}
+}
+
+func TestMultiLanguageGeneration(t *testing.T) {
+	config := defaultConfig()
+	config.enableSearch = false
+	config.languages = []string{"en", "de"}
+	config.siteBaseURL = "https://example.com"
+	config.generateFeeds = []string{feedFormatRSS}
+
+	posts := []post{
+		{Id: "hallo", Lang: "de", TranslationOf: "hello", Title: "Hallo", Body: `<p><a href="/tags/go/">#go</a></p>`, Tags: []string{"go"}, Date: civil.Date{Year: 2024, Month: 2, Day: 2}},
+		{Id: "hello", Title: "Hello", Body: "<p>hello</p>", Tags: []string{"go"}, Date: civil.Date{Year: 2024, Month: 2, Day: 1}},
+		{Id: "welt", Lang: "de", Title: "Welt", Body: "<p>welt</p>", Tags: []string{"go"}, Date: civil.Date{Year: 2024, Month: 1, Day: 1}},
+	}
+	globalIncludes := map[string]string{
+		filepath.Join(i18nDirName, "de"+i18nFileExtension): `"Related posts:": "Verwandte Beiträge:"`,
+	}
+	output := processOutput(nil, posts, globalIncludes, nil, config)
+
+	for _, path := range []string{
+		"index.html",
+		"post/hello.html",
+		"tags/go/index.html",
+		"rss.xml",
+		"de/index.html",
+		"de/post/hallo.html",
+		"de/post/welt.html",
+		"de/tags/go/index.html",
+		"de/rss.xml",
+	} {
+		if _, ok := output[deployDirName+"/"+path]; !ok {
+			t.Errorf("missing output file: %s", path)
+		}
+	}
+	for _, path := range []string{"post/hallo.html", "de/post/hello.html"} {
+		if _, ok := output[deployDirName+"/"+path]; ok {
+			t.Errorf("unexpected output file: %s", path)
+		}
+	}
+
+	hallo := output[deployDirName+"/de/post/hallo.html"]
+	verifyStringContains(hallo, `<html lang="de">`, t)
+	verifyStringContains(hallo, `<link rel="alternate" hreflang="en" href="https://example.com/post/hello.html" />`, t)
+	verifyStringContains(hallo, `<link rel="alternate" hreflang="de" href="https://example.com/de/post/hallo.html" />`, t)
+	verifyStringContains(hallo, `<link rel="alternate" hreflang="x-default" href="https://example.com/post/hello.html" />`, t)
+	verifyStringContains(hallo, `<link rel="canonical" href="https://example.com/de/post/hallo.html" />`, t)
+	verifyStringContains(hallo, `href="https://example.com/de/rss.xml"`, t)
+	verifyStringContains(hallo, `<a href="/de/tags/go/">#go</a>`, t)
+	verifyStringContains(hallo, `<a class="tag" href="/de/tags/go/">go</a>`, t)
+	verifyStringContains(hallo, `<a href="/de/post/welt.html" class="prev" rel="prev">`, t)
+	verifyStringContains(hallo, `<span class="label">Verwandte Beiträge:</span>`, t)
+
+	hello := output[deployDirName+"/post/hello.html"]
+	verifyStringContains(hello, `<html lang="en">`, t)
+	if strings.Contains(hello, `class="post-nav"`) {
+		t.Error("post navigation should be scoped to the post language")
+	}
+
+	enTag := output[deployDirName+"/tags/go/index.html"]
+	if strings.Contains(enTag, "Hallo") || !strings.Contains(enTag, "Hello") {
+		t.Error("tag pages should be scoped to the post language")
+	}
+	verifyStringContains(output[deployDirName+"/de/rss.xml"], "https://example.com/de/post/hallo.html", t)
+	if strings.Contains(output[deployDirName+"/rss.xml"], "hallo") {
+		t.Error("feeds should be scoped to the post language")
+	}
+}
+
+func TestPostSeriesGeneration(t *testing.T) {
+	config := defaultConfig()
+	config.siteName = testSiteName
+	config.enableSearch = false
+
+	posts := []post{
+		{Id: "post-3", Title: "Part Two", Body: "body 3", Series: "Building a Blog", SeriesPart: 2},
+		{Id: "post-2", Title: "Other", Body: "body 2"},
+		{Id: "post-1", Title: "Part One", Body: "body 1", Series: "Building a Blog", SeriesPart: 1},
+	}
+	output := processOutput(nil, posts, nil, nil, config)
+
+	seriesPage := output[deployDirName+"/"+deploySeriesDirName+"/building-a-blog/"+indexPageFileName]
+	first, second := strings.Index(seriesPage, `id="post-1"`), strings.Index(seriesPage, `id="post-3"`)
+	if first == -1 || second == -1 || first > second {
+		t.Errorf("series page should list the parts in the series order: %s", seriesPage)
+	}
+	if strings.Contains(seriesPage, `id="post-2"`) {
+		t.Error("series page should not list the posts not in the series")
+	}
+
+	part1 := output[deployDirName+"/"+deployPostDirName+"/post-1.html"]
+	verifyStringContains(part1, `<span class="label">Part 1 of 2:</span><a href="/series/building-a-blog/">Building a Blog</a>`, t)
+	verifyStringContains(part1, `<a href="/post/post-3.html" class="next">Part Two <i class="fa-solid fa-angles-right"></i></a>`, t)
+	part2 := output[deployDirName+"/"+deployPostDirName+"/post-3.html"]
+	verifyStringContains(part2, `<span class="label">Part 2 of 2:</span>`, t)
+	verifyStringContains(part2, `<a href="/post/post-1.html" class="prev"><i class="fa-solid fa-angles-left"></i> Part One</a>`, t)
+	if strings.Contains(output[deployDirName+"/"+deployPostDirName+"/post-2.html"], `class="series"`) {
+		t.Error("post not in a series should not render the series navigation")
+	}
+}
+
+func TestParallelProcessingOutputIsDeterministic(t *testing.T) {
+	config := defaultConfig()
+	config.siteName = testSiteName
+	config.enableSearch = false
+	config.pageSize = 3
+
+	var posts []post
+	for i := 40; i > 0; i-- {
+		posts = append(posts, post{
+			Id:     fmt.Sprintf("post-%02d", i),
+			Title:  fmt.Sprintf("Post %d", i),
+			Body:   fmt.Sprintf("body %d", i),
+			Date:   civil.Date{Year: 2024, Month: time.Month(i%12 + 1), Day: 1},
+			Tags:   []string{fmt.Sprintf("tag-%d", i%4), "all"},
+			Series: fmt.Sprintf("Series %d", i%3),
+		})
+	}
+	pages := []page{{Id: "about", Title: "About", Body: "about"}, {Id: "contact", Title: "Contact", Body: "contact"}}
+
+	config.workerCnt = 1
+	sequential := processOutput(pages, posts, nil, nil, config)
+	config.workerCnt = 8
+	parallel := processOutput(pages, posts, nil, nil, config)
+	if len(sequential) != len(parallel) {
+		t.Fatalf("output file count mismatch: %d / %d", len(sequential), len(parallel))
+	}
+	for path, data := range sequential {
+		if parallel[path] != data {
+			t.Errorf("output mismatch: %s", path)
+		}
+	}
+}
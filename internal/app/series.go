@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseSeriesMetaData parses the `series` frontmatter value of a post:
+// either the series title, or a `title`/`part` map (the part number being optional);
+// malformed values are reported as warnings (and the post is not considered to be a part of any series)
+func parseSeriesMetaData(metaData map[string]interface{}, warnings *[]string) (string, int) {
+	raw, ok := metaData[metaDataKeySeries]
+	if !ok || raw == nil {
+		return "", 0
+	}
+	if title, ok := raw.(string); ok {
+		return strings.TrimSpace(title), 0
+	}
+	seriesMap, ok := toStringKeyMap(raw)
+	if !ok {
+		*warnings = append(*warnings, fmt.Sprintf("series: malformed metadata: %v (expected a series title, or a map of title and part number)", raw))
+		return "", 0
+	}
+	title, _ := seriesMap[metaDataKeyTitle].(string)
+	title = strings.TrimSpace(title)
+	if title == "" {
+		*warnings = append(*warnings, "series: missing series title")
+		return "", 0
+	}
+	part := 0
+	if rawPart, ok := seriesMap[metaDataKeySeriesPart]; ok {
+		if p, ok := rawPart.(int); ok && p > 0 {
+			part = p
+		} else {
+			*warnings = append(*warnings, fmt.Sprintf("series: malformed part number: %v (expected a positive number)", rawPart))
+		}
+	}
+	return title, part
+}
+
+// groupSeriesPosts groups the given (ordered) posts by series URI (post indexes, from the first part to the last one):
+// the posts with a part number are ordered by it, while the ones without one follow in publication order
+// (the posts are ordered from the newest to the oldest one, so the oldest one first)
+func groupSeriesPosts(posts []post) map[string][]int {
+	series := map[string][]int{}
+	for i, p := range posts {
+		if uri := normalizeURIString(p.Series); uri != "" {
+			series[uri] = append(series[uri], i)
+		}
+	}
+	for _, idxs := range series {
+		sort.SliceStable(idxs, func(a, b int) bool {
+			pa, pb := posts[idxs[a]].SeriesPart, posts[idxs[b]].SeriesPart
+			if (pa == 0) != (pb == 0) {
+				return pa != 0
+			}
+			if pa != pb {
+				return pa < pb
+			}
+			return idxs[a] > idxs[b]
+		})
+	}
+	return series
+}
+
+// buildPostSeries builds the series data for each one of the given (ordered) posts
+// (nil for the posts not being a part of any series); the posts without a part number
+// (following the numbered ones) are numbered after the highest part number given,
+// so that they don't clash with any of the given ones
+func buildPostSeries(posts []post) []*postSeriesData {
+	series := make([]*postSeriesData, len(posts))
+	for uri, idxs := range groupSeriesPosts(posts) {
+		parts := make([]int, len(idxs))
+		lastPart := 0
+		for pos, idx := range idxs {
+			lastPart = max(lastPart, posts[idx].SeriesPart)
+			parts[pos] = posts[idx].SeriesPart
+		}
+		for pos := range parts {
+			if parts[pos] == 0 {
+				lastPart++
+				parts[pos] = lastPart
+			}
+		}
+		partCnt := max(len(idxs), lastPart)
+		for pos, idx := range idxs {
+			sd := postSeriesData{
+				Title:   posts[idxs[0]].Series,
+				URI:     uri,
+				Part:    parts[pos],
+				PartCnt: partCnt,
+			}
+			if pos > 0 {
+				sd.Prev = &posts[idxs[pos-1]]
+			}
+			if pos < len(idxs)-1 {
+				sd.Next = &posts[idxs[pos+1]]
+			}
+			series[idx] = &sd
+		}
+	}
+	return series
+}
+
+// inspectSeriesParts checks the part numbers of each series for gaps and duplicates,
+// returning the issues found (sorted by series URI)
+func inspectSeriesParts(posts []post) []string {
+	var issues []string
+	seriesPosts := groupSeriesPosts(posts)
+	uris := make([]string, 0, len(seriesPosts))
+	for uri := range seriesPosts {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		partPosts := map[int][]string{}
+		maxPart := 0
+		for _, idx := range seriesPosts[uri] {
+			if part := posts[idx].SeriesPart; part > 0 {
+				partPosts[part] = append(partPosts[part], posts[idx].Id)
+				maxPart = max(maxPart, part)
+			}
+		}
+		var missing []string
+		for part := 1; part <= maxPart; part++ {
+			ids := partPosts[part]
+			if len(ids) == 0 {
+				missing = append(missing, strconv.Itoa(part))
+			} else if len(ids) > 1 {
+				issues = append(issues, fmt.Sprintf("%s: duplicate part %d: %s", uri, part, strings.Join(ids, ", ")))
+			}
+		}
+		if len(missing) > 0 {
+			issues = append(issues, uri+": missing part(s): "+strings.Join(missing, ", "))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestParseSeriesMetaData(t *testing.T) {
+	config := defaultConfig()
+	resLoader := testResLoader()
+
+	p := parsePost("part-2", "---\nseries:\n  title: Building a Blog\n  part: 2\n---\nBody", config, resLoader)
+	verifyStringsEqual(p.Series, "Building a Blog", t)
+	if p.SeriesPart != 2 {
+		t.Errorf("expected part 2, got: %d", p.SeriesPart)
+	}
+
+	p = parsePost("intro", "---\nseries: Building a Blog\n---\nBody", config, resLoader)
+	verifyStringsEqual(p.Series, "Building a Blog", t)
+	if p.SeriesPart != 0 || len(p.Warnings) != 0 {
+		t.Errorf("unexpected series part/warnings: %d / %v", p.SeriesPart, p.Warnings)
+	}
+
+	p = parsePost("bad-part", "---\nseries:\n  title: Building a Blog\n  part: two\n---\nBody", config, resLoader)
+	if p.SeriesPart != 0 || len(p.Warnings) != 1 {
+		t.Fatalf("expected a single malformed part warning, got: %v", p.Warnings)
+	}
+	verifyStringContains(p.Warnings[0], "series: malformed part number: two", t)
+
+	p = parsePost("no-title", "---\nseries:\n  part: 1\n---\nBody", config, resLoader)
+	if p.Series != "" || len(p.Warnings) != 1 {
+		t.Fatalf("expected a single missing title warning, got: %v", p.Warnings)
+	}
+	verifyStringContains(p.Warnings[0], "series: missing series title", t)
+}
+
+func TestBuildPostSeries(t *testing.T) {
+	posts := []post{
+		{Id: "extra", Series: "Building a Blog"},
+		{Id: "part-2", Series: "Building a Blog", SeriesPart: 2},
+		{Id: "other"},
+		{Id: "part-1", Series: "building a blog", SeriesPart: 1},
+	}
+	series := buildPostSeries(posts)
+	if series[2] != nil {
+		t.Errorf("unexpected series data of a post not in a series: %+v", series[2])
+	}
+	first := series[3]
+	verifyStringsEqual(first.Title, "building a blog", t)
+	verifyStringsEqual(first.URI, "building-a-blog", t)
+	if first.Part != 1 || first.PartCnt != 3 || first.Prev != nil || first.Next.Id != "part-2" {
+		t.Errorf("unexpected first part series data: %+v", first)
+	}
+	if series[1].Part != 2 || series[1].Prev.Id != "part-1" || series[1].Next.Id != "extra" {
+		t.Errorf("unexpected second part series data: %+v", series[1])
+	}
+	if series[0].Part != 3 || series[0].Prev.Id != "part-2" || series[0].Next != nil {
+		t.Errorf("unexpected unnumbered part series data: %+v", series[0])
+	}
+}
+
+func TestBuildPostSeriesMixedParts(t *testing.T) {
+	posts := []post{
+		{Id: "unnumbered-newer", Series: "Guide"},
+		{Id: "part-3", Series: "Guide", SeriesPart: 3},
+		{Id: "unnumbered-older", Series: "Guide"},
+		{Id: "part-1", Series: "Guide", SeriesPart: 1},
+	}
+	series := buildPostSeries(posts)
+	// the unnumbered parts don't take the part numbers given to the other ones (e.g. the part 3)
+	for i, expected := range []int{5, 3, 4, 1} {
+		if series[i].Part != expected || series[i].PartCnt != 5 {
+			t.Errorf("%s: expected the part %d of 5, got: %d of %d", posts[i].Id, expected, series[i].Part, series[i].PartCnt)
+		}
+	}
+	if series[1].Next.Id != "unnumbered-older" || series[2].Next.Id != "unnumbered-newer" {
+		t.Errorf("unexpected unnumbered parts order: %+v, %+v", series[1], series[2])
+	}
+}
+
+func TestInspectSeriesParts(t *testing.T) {
+	posts := []post{
+		{Id: "a4", Series: "A", SeriesPart: 4},
+		{Id: "a2", Series: "A", SeriesPart: 2},
+		{Id: "a2-bis", Series: "A", SeriesPart: 2},
+		{Id: "b2", Series: "B", SeriesPart: 2},
+		{Id: "b1", Series: "B", SeriesPart: 1},
+		{Id: "c", Series: "C"},
+	}
+	issues := inspectSeriesParts(posts)
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 series issues, got: %v", issues)
+	}
+	verifyStringsEqual(issues[0], "a: duplicate part 2: a2-bis, a2", t)
+	verifyStringsEqual(issues[1], "a: missing part(s): 1, 3", t)
+}
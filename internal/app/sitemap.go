@@ -0,0 +1,223 @@
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	uri     string
+	lastMod time.Time
+}
+
+// outputFilePathToURI converts a generated output file path (inside the deploy dir)
+// to the corresponding site URI, e.g. `deploy/tags/x/index.html` -> `/tags/x/`
+func outputFilePathToURI(outputFilePath string) string {
+	uri := filepath.ToSlash(strings.TrimPrefix(outputFilePath, deployDirName))
+	if !strings.HasPrefix(uri, "/") {
+		uri = "/" + uri
+	}
+	return strings.TrimSuffix(uri, indexPageFileName)
+}
+
+// postLastMod returns the last modification time of the given post:
+// the later one of its publish time and its content file modification time
+func postLastMod(p post) time.Time {
+	if pt := p.publishTime(); pt.After(p.modTime) {
+		return pt
+	}
+	return p.modTime
+}
+
+// buildSitemapLastMods maps the site URIs to their last modification times:
+// single post/page URIs map to the corresponding entity's one, while content dir URIs (e.g. `/tags/x/`)
+// map to the one of the most recently modified post listed in there — as a new post shifts the pagination,
+// all the pages inside a content dir are considered to be modified along with it
+func buildSitemapLastMods(pages []page, posts []post, config appConfig) map[string]time.Time {
+	lastMods := map[string]time.Time{}
+	bump := func(uri string, t time.Time) {
+		if t.After(lastMods[uri]) {
+			lastMods[uri] = t
+		}
+	}
+	for _, p := range posts {
+		lm := postLastMod(p)
+		bump("/"+deployPostDirName+"/"+p.Id+contentFileExtension, lm)
+		bump("/"+deployPostsDirName+"/", lm)
+		bump("/"+deployTagsDirName+"/", lm)
+		bump("/"+deployCollectionsDirName+"/", lm)
+		if config.homePage == "" {
+			bump("/", lm)
+		}
+		for _, tag := range p.Tags {
+			bump("/"+deployTagsDirName+"/"+normalizeURIString(tag)+"/", lm)
+		}
+		if seriesUri := normalizeURIString(p.Series); seriesUri != "" {
+			bump("/"+deploySeriesDirName+"/"+seriesUri+"/", lm)
+		}
+		for _, ref := range p.Collections {
+			collUri := "/" + deployCollectionsDirName + "/" + normalizeURIString(ref.Collection) + "/"
+			bump(collUri, lm)
+			bump(collUri+normalizeURIString(ref.Item)+"/", lm)
+		}
+		if !p.Date.IsZero() {
+			bump("/"+deployArchiveDirName+"/", lm)
+			bump("/"+deployArchiveDirName+"/"+formatYearAndMonth(p.Date.Year, p.Date.Month)+"/", lm)
+		}
+	}
+	for _, p := range pages {
+		if p.Id == config.homePage {
+			bump("/", p.modTime)
+		} else {
+			bump("/"+deployPageDirName+"/"+p.Id+contentFileExtension, p.modTime)
+		}
+	}
+	return lastMods
+}
+
+// buildSitemapEntries builds the (URI sorted) sitemap entries for the given generated content URIs,
+// along with the single post/page URIs skipped during (cached) processing, which are not re-generated
+func buildSitemapEntries(uris []string, pages []page, posts []post, config appConfig) []sitemapEntry {
+	seen := map[string]struct{}{}
+	var all []string
+	add := func(uri string) {
+		if _, ok := seen[uri]; !ok {
+			seen[uri] = struct{}{}
+			all = append(all, uri)
+		}
+	}
+	for _, uri := range uris {
+		add(uri)
+	}
+	for _, p := range posts {
+		if p.skipProcessing {
+			add("/" + deployPostDirName + "/" + p.Id + contentFileExtension)
+		}
+	}
+	for _, p := range pages {
+		if p.skipProcessing {
+			if p.Id == config.homePage {
+				add("/")
+			} else {
+				add("/" + deployPageDirName + "/" + p.Id + contentFileExtension)
+			}
+		}
+	}
+	sort.Strings(all)
+	lastMods := buildSitemapLastMods(pages, posts, config)
+	entries := make([]sitemapEntry, 0, len(all))
+	for _, uri := range all {
+		lm, ok := lastMods[uri]
+		if !ok {
+			lm = lastMods[uri[:strings.LastIndex(uri, "/")+1]]
+		}
+		entries = append(entries, sitemapEntry{uri: uri, lastMod: lm})
+	}
+	return entries
+}
+
+func formatSitemapLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func marshalSitemapXML(v any) []byte {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	check(err)
+	return append([]byte(xml.Header), append(data, '\n')...)
+}
+
+// renderSitemaps renders the sitemap file(s) for the given entries (file name -> file data):
+// a single sitemap file, or — if there are more than maxUrlCnt entries —
+// a sitemap index file referencing as many numbered sitemap files as needed
+func renderSitemaps(entries []sitemapEntry, siteBaseURL string, maxUrlCnt int) map[string][]byte {
+	toURLSet := func(entries []sitemapEntry) (sitemapURLSet, time.Time) {
+		urlSet := sitemapURLSet{Xmlns: sitemapXmlns}
+		var lastMod time.Time
+		for _, e := range entries {
+			urlSet.URLs = append(urlSet.URLs, sitemapURL{Loc: siteBaseURL + e.uri, LastMod: formatSitemapLastMod(e.lastMod)})
+			if e.lastMod.After(lastMod) {
+				lastMod = e.lastMod
+			}
+		}
+		return urlSet, lastMod
+	}
+	if len(entries) <= maxUrlCnt {
+		urlSet, _ := toURLSet(entries)
+		return map[string][]byte{sitemapFileName: marshalSitemapXML(urlSet)}
+	}
+	sitemaps := map[string][]byte{}
+	index := sitemapIndex{Xmlns: sitemapXmlns}
+	for i := 0; i*maxUrlCnt < len(entries); i++ {
+		fileName := fmt.Sprintf(sitemapPartFileNameFormat, i+1)
+		urlSet, lastMod := toURLSet(entries[i*maxUrlCnt : min((i+1)*maxUrlCnt, len(entries))])
+		sitemaps[fileName] = marshalSitemapXML(urlSet)
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: siteBaseURL + "/" + fileName, LastMod: formatSitemapLastMod(lastMod)})
+	}
+	sitemaps[sitemapFileName] = marshalSitemapXML(index)
+	return sitemaps
+}
+
+// renderRobotsTxt renders the robots.txt file: the rules are taken from the `robots.txt` include file
+// (global level first, then theme level), defaulting to allowing everything, followed by the sitemap location
+func renderRobotsTxt(resLoader resourceLoader) []byte {
+	rules := defaultRobotsTxtRules
+	for _, level := range []templateIncludeLevel{Global, Theme} {
+		include, err := resLoader.loadInclude(robotsTxtFileName, level)
+		check(err)
+		if include != nil {
+			rules = strings.TrimSpace(string(include))
+			break
+		}
+	}
+	return []byte(rules + "\n\nSitemap: " + resLoader.config.siteBaseURL + "/" + sitemapFileName + "\n")
+}
+
+// generateSitemap generates the sitemap file(s) covering the given entries (of all the languages),
+// as well as the robots.txt file (unless disabled)
+func generateSitemap(entries []sitemapEntry, resLoader resourceLoader, handleOutput processorOutputHandler) {
+	if handleOutput == nil {
+		return
+	}
+	config := resLoader.config
+	sprintln(" - generating sitemap ...")
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].uri < entries[j].uri
+	})
+	sitemaps := renderSitemaps(entries, config.siteBaseURL, sitemapMaxUrlCnt)
+	fileNames := make([]string, 0, len(sitemaps))
+	for fileName := range sitemaps {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	for _, fileName := range fileNames {
+		handleOutput(fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, fileName), sitemaps[fileName])
+	}
+	if config.generateRobotsTxt {
+		handleOutput(fmt.Sprintf("%s%c%s", deployDirName, os.PathSeparator, robotsTxtFileName), renderRobotsTxt(resLoader))
+	}
+}
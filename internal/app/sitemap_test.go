@@ -0,0 +1,81 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+func TestSitemapGeneration(t *testing.T) {
+	pageModTime := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	postModTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pages := []page{{Id: "about", Title: "About", Body: "about", modTime: pageModTime}}
+	posts := []post{
+		{Id: "post-2", Title: "Post 2", Body: "body 2", Date: civil.Date{Year: 2024, Month: 3, Day: 5}, Tags: []string{"Go"}, modTime: postModTime},
+		{Id: "post-1", Title: "Post 1", Body: "body 1", Date: civil.Date{Year: 2024, Month: 2, Day: 1}, Tags: []string{"Go", "Misc"}, modTime: postModTime},
+	}
+
+	config := defaultConfig()
+	config.siteName = testSiteName
+	config.siteBaseURL = "https://example.com"
+	config.generateSitemap = true
+	config.enableSearch = false
+
+	output := processOutput(pages, posts, nil, map[string]string{robotsTxtFileName: "User-agent: *\nDisallow: /search.html"}, config)
+
+	sitemap, ok := output[deployDirName+"/"+sitemapFileName]
+	if !ok {
+		t.Fatal("Missing sitemap output file")
+	}
+	post2LastMod := civil.DateTime{Date: posts[0].Date}.In(time.Local).Format(time.RFC3339)
+	post1LastMod := civil.DateTime{Date: posts[1].Date}.In(time.Local).Format(time.RFC3339)
+	for _, expected := range []string{
+		"<loc>https://example.com/</loc><lastmod>" + post2LastMod + "</lastmod>",
+		"<loc>https://example.com/posts/</loc><lastmod>" + post2LastMod + "</lastmod>",
+		"<loc>https://example.com/page/about.html</loc><lastmod>" + pageModTime.Format(time.RFC3339) + "</lastmod>",
+		"<loc>https://example.com/post/post-1.html</loc><lastmod>" + post1LastMod + "</lastmod>",
+		"<loc>https://example.com/tags/go/</loc><lastmod>" + post2LastMod + "</lastmod>",
+		"<loc>https://example.com/tags/misc/</loc><lastmod>" + post1LastMod + "</lastmod>",
+		"<loc>https://example.com/archive/2024-02/</loc><lastmod>" + post1LastMod + "</lastmod>",
+		"<loc>https://example.com/archive/</loc>",
+	} {
+		verifyStringContains(sitemap, expected, t)
+	}
+	if strings.Contains(sitemap, searchPageFileName) {
+		t.Error("sitemap should not list the search page")
+	}
+
+	robotsTxt, ok := output[deployDirName+"/"+robotsTxtFileName]
+	if !ok {
+		t.Fatal("Missing robots.txt output file")
+	}
+	verifyStringsEqual(robotsTxt, "User-agent: *Disallow: /search.htmlSitemap: https://example.com/sitemap.xml", t)
+}
+
+func TestSitemapIndexSplit(t *testing.T) {
+	var entries []sitemapEntry
+	for _, uri := range []string{"/a.html", "/b.html", "/c.html"} {
+		entries = append(entries, sitemapEntry{uri: uri})
+	}
+	entries[2].lastMod = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	sitemaps := renderSitemaps(entries, "https://example.com", 3)
+	if len(sitemaps) != 1 {
+		t.Fatalf("expected a single sitemap file, got %d", len(sitemaps))
+	}
+
+	sitemaps = renderSitemaps(entries, "https://example.com", 2)
+	if len(sitemaps) != 3 {
+		t.Fatalf("expected a sitemap index and 2 sitemap files, got %d", len(sitemaps))
+	}
+	index := string(sitemaps[sitemapFileName])
+	verifyStringContains(index, "<sitemapindex", t)
+	verifyStringContains(index, "<loc>https://example.com/sitemap-1.xml</loc>", t)
+	verifyStringContains(index, "<loc>https://example.com/sitemap-2.xml</loc>\n    <lastmod>2024-05-01T00:00:00Z</lastmod>", t)
+	verifyStringContains(string(sitemaps["sitemap-2.xml"]), "<loc>https://example.com/c.html</loc>", t)
+	if strings.Contains(string(sitemaps["sitemap-1.xml"]), "c.html") {
+		t.Error("first sitemap file should not list the entries beyond the max URL count")
+	}
+}
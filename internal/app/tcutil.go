@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"html"
 	"os"
 	"strconv"
 	"strings"
@@ -10,8 +11,8 @@ import (
 )
 
 var (
-	templateIncludeCache = make(map[string]string)
-	templateCache        = make(map[string]*template.Template)
+	templateIncludeCache = newSyncCache[string, string]()
+	templateCache        = newSyncCache[string, *template.Template]()
 	funcMap              = /* const */ template.FuncMap{
 		"mod":   func(a, b int) int { return a % b },
 		"minus": func(a, b int) int { return a - b },
@@ -43,6 +44,8 @@ var (
 		"fmtYearAndMonth":    formatYearAndMonth,
 		"toLowerCase":        strings.ToLower,
 		"normalizeURIString": normalizeURIString,
+		"escapeHTML":         html.EscapeString,
+		"translate":          translate,
 	}
 )
 
@@ -79,7 +82,7 @@ func compileCollectionIndexTemplate(resLoader resourceLoader) *template.Template
 // with the standalone collection page compile of the same template file
 func compileCollectionBlockTemplate(resLoader resourceLoader) *template.Template {
 	const cacheKey = "collection-block"
-	collectionBlockTemplate, ok := templateCache[cacheKey]
+	collectionBlockTemplate, ok := templateCache.get(cacheKey)
 	if !ok {
 		collectionTemplateMarkup, err := readTemplateFile(collectionTemplateFileName, resLoader)
 		check(err)
@@ -87,11 +90,15 @@ func compileCollectionBlockTemplate(resLoader resourceLoader) *template.Template
 		tmplt, err := template.New(collectionTemplateFileName).Funcs(funcMap).Parse(collectionTemplateMarkup)
 		check(err)
 		collectionBlockTemplate = tmplt
-		templateCache[cacheKey] = tmplt
+		templateCache.set(cacheKey, tmplt)
 	}
 	return collectionBlockTemplate
 }
 
+func compileAlbumTemplate(resLoader resourceLoader) *template.Template {
+	return compileStandalonePageTemplate(albumTemplateFileName, resLoader)
+}
+
 func compileSearchTemplate(resLoader resourceLoader) *template.Template {
 	return compileStandalonePageTemplate(searchTemplateFileName, resLoader)
 }
@@ -114,7 +121,7 @@ func compilePagerTemplate(resLoader resourceLoader) *template.Template {
 }
 
 func compilePostTemplate(resLoader resourceLoader) *template.Template {
-	postTemplate, ok := templateCache[postTemplateFileName]
+	postTemplate, ok := templateCache.get(postTemplateFileName)
 	if !ok {
 		postTemplateMarkup, err := readTemplateFile(postTemplateFileName, resLoader)
 		check(err)
@@ -122,14 +129,14 @@ func compilePostTemplate(resLoader resourceLoader) *template.Template {
 		tmplt, err := template.New(postTemplateMarkup).Funcs(funcMap).Parse(postTemplateMarkup)
 		check(err)
 		postTemplate = tmplt
-		templateCache[postTemplateFileName] = tmplt
+		templateCache.set(postTemplateFileName, tmplt)
 	}
 	return postTemplate
 }
 
 func compileContentDirectiveTemplate(directive string, resLoader resourceLoader) (*template.Template, error) {
 	templateFileName := fmt.Sprintf(contentDirectiveTemplateFileNameFormat, directive)
-	contentDirectiveTemplate, ok := templateCache[templateFileName]
+	contentDirectiveTemplate, ok := templateCache.get(templateFileName)
 	if !ok {
 		contentDirectiveMarkup, err := readTemplateFile(templateFileName, resLoader)
 		if err != nil {
@@ -139,13 +146,13 @@ func compileContentDirectiveTemplate(directive string, resLoader resourceLoader)
 		tmplt, err := template.New(contentDirectiveMarkup).Funcs(funcMap).Parse(contentDirectiveMarkup)
 		check(err)
 		contentDirectiveTemplate = tmplt
-		templateCache[templateFileName] = tmplt
+		templateCache.set(templateFileName, tmplt)
 	}
 	return contentDirectiveTemplate, nil
 }
 
 func compileMediaTemplate(resLoader resourceLoader) *template.Template {
-	inlineMediaTemplate, ok := templateCache[mediaTemplateFileName]
+	inlineMediaTemplate, ok := templateCache.get(mediaTemplateFileName)
 	if !ok {
 		inlineMediaTemplateMarkup, err := readTemplateFile(mediaTemplateFileName, resLoader)
 		check(err)
@@ -153,7 +160,7 @@ func compileMediaTemplate(resLoader resourceLoader) *template.Template {
 		tmplt, err := template.New(mediaTemplateFileName).Funcs(funcMap).Parse(inlineMediaTemplateMarkup)
 		check(err)
 		inlineMediaTemplate = tmplt
-		templateCache[mediaTemplateFileName] = tmplt
+		templateCache.set(mediaTemplateFileName, tmplt)
 	}
 	return inlineMediaTemplate
 }
@@ -161,20 +168,28 @@ func compileMediaTemplate(resLoader resourceLoader) *template.Template {
 func compileFullTemplate(name string, content string,
 	mainTemplateMarkupHandler func(mainTemplateMarkup string) string,
 	resLoader resourceLoader) *template.Template {
-	if mainTemplateMarkup == "" {
-		markup, err := readTemplateFile(mainTemplateFileName, resLoader)
-		check(err)
-		mainTemplateMarkup = markup
-	}
+	markup := loadMainTemplateMarkup(resLoader)
 	if mainTemplateMarkupHandler != nil {
-		mainTemplateMarkup = mainTemplateMarkupHandler(mainTemplateMarkup)
+		markup = mainTemplateMarkupHandler(markup)
 	}
-	templateMarkup := compileSubTemplate(mainTemplateMarkup, content, resLoader)
+	templateMarkup := compileSubTemplate(markup, content, resLoader)
 	tmplt, err := template.New(name).Funcs(funcMap).Parse(templateMarkup)
 	check(err)
 	return tmplt
 }
 
+// loadMainTemplateMarkup loads the main template markup (once, shared by all the full template compiles)
+func loadMainTemplateMarkup(resLoader resourceLoader) string {
+	mainTemplateMarkupMutex.Lock()
+	defer mainTemplateMarkupMutex.Unlock()
+	if mainTemplateMarkup == "" {
+		markup, err := readTemplateFile(mainTemplateFileName, resLoader)
+		check(err)
+		mainTemplateMarkup = markup
+	}
+	return mainTemplateMarkup
+}
+
 func compileSubTemplate(mainTemplateMarkup string, subTemplateMarkup string, resLoader resourceLoader) string {
 	fullTemplateMarkup := strings.Replace(mainTemplateMarkup, subTemplatePlaceholder, subTemplateMarkup, 1)
 	fullTemplateMarkup = processDirectives(fullTemplateMarkup, resLoader)
@@ -201,7 +216,7 @@ func processDirectives(templateMarkup string, resLoader resourceLoader) string {
 
 	for _, ti := range templateIncludes {
 		ticKey := ti.includeType.String() + "/" + ti.fileName
-		includeMarkup, ok := templateIncludeCache[ticKey]
+		includeMarkup, ok := templateIncludeCache.get(ticKey)
 		if !ok {
 			switch ti.includeType {
 			case Template:
@@ -223,7 +238,7 @@ func processDirectives(templateMarkup string, resLoader resourceLoader) string {
 					includeMarkup += string(ic)
 				}
 			}
-			templateIncludeCache[ticKey] = includeMarkup
+			templateIncludeCache.set(ticKey, includeMarkup)
 		}
 		templateMarkup = strings.Replace(templateMarkup, ti.placeholder, includeMarkup, 1)
 
@@ -231,20 +246,7 @@ func processDirectives(templateMarkup string, resLoader resourceLoader) string {
 		if contentLinkPlaceholders != nil {
 			for _, clp := range contentLinkPlaceholders {
 				placeholder := clp[0]
-				entityType := strings.ToLower(clp[1])
-				entryId := clp[2]
-				var ceType contentEntityType
-				switch entityType {
-				case "page":
-					ceType = Page
-				case "post":
-					ceType = Post
-				}
-				var link string
-				if ceType != UndefinedContentEntityType {
-					link = "/" + strings.ToLower(ceType.String()) + "/" + entryId + contentFileExtension
-				}
-				templateMarkup = strings.Replace(templateMarkup, placeholder, link, 1)
+				templateMarkup = strings.Replace(templateMarkup, placeholder, contentLinkURI(parseContentLinkRef(clp)), 1)
 			}
 		}
 	}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// parseTocDirectiveProps validates the given toc directive properties (e.g. `(min=2,max=3)`)
+// and returns them in the normalized form embedded into the toc directive placeholder;
+// invalid properties are reported as warnings and replaced with the default values
+func parseTocDirectiveProps(propStr string, warnings *[]string) string {
+	minLevel, maxLevel := defaultTocMinLevel, defaultTocMaxLevel
+	propStr = strings.Trim(propStr, "()")
+	if propStr != "" {
+		for _, pStr := range strings.Split(propStr, ",") {
+			key, val, _ := strings.Cut(strings.TrimSpace(pStr), "=")
+			key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+			level, err := strconv.Atoi(val)
+			if err != nil || level < 1 || level > 6 {
+				*warnings = append(*warnings, "invalid {"+tocDirective+"} heading level value: "+strings.TrimSpace(pStr)+" (expected: 1-6)")
+				continue
+			}
+			switch key {
+			case tocDirectivePropMinLevel:
+				minLevel = level
+			case tocDirectivePropMaxLevel:
+				maxLevel = level
+			default:
+				*warnings = append(*warnings, "unknown {"+tocDirective+"} property: "+key+" (allowed: "+tocDirectivePropMinLevel+", "+tocDirectivePropMaxLevel+")")
+			}
+		}
+	}
+	if minLevel > maxLevel {
+		*warnings = append(*warnings, fmt.Sprintf("{%s} min heading level (%d) exceeds the max one (%d); using the default levels", tocDirective, minLevel, maxLevel))
+		minLevel, maxLevel = defaultTocMinLevel, defaultTocMaxLevel
+	}
+	return fmt.Sprintf("%s=%d,%s=%d", tocDirectivePropMinLevel, minLevel, tocDirectivePropMaxLevel, maxLevel)
+}
+
+// extractHeadings returns all the headings (with IDs) of the given rendered HTML content, in document order
+func extractHeadings(body string) []headingData {
+	var headings []headingData
+	for _, m := range headingRegexp.FindAllStringSubmatch(body, -1) {
+		headings = append(headings, headingFromMatch(m))
+	}
+	return headings
+}
+
+// dedupeHeadingIDs makes the heading IDs of the given rendered HTML content unique:
+// the auto generated IDs are only unique within a single markdown conversion,
+// while the content directive blocks (e.g. `{cols}`, `{with-media}`) are converted separately;
+// the duplicates get numeric suffixes (e.g. `intro-1`), just like the markdown converter does
+func dedupeHeadingIDs(body string) string {
+	used := make(map[string]bool)
+	for _, h := range extractHeadings(body) {
+		used[h.ID] = true
+	}
+	seen := make(map[string]bool)
+	return headingRegexp.ReplaceAllStringFunc(body, func(match string) string {
+		m := headingRegexp.FindStringSubmatch(match)
+		id := m[2]
+		if seen[id] {
+			for i := 1; ; i++ {
+				if candidate := id + "-" + strconv.Itoa(i); !used[candidate] {
+					id = candidate
+					used[id] = true
+					break
+				}
+			}
+		}
+		seen[id] = true
+		return fmt.Sprintf(`<h%s id="%s">%s%s`, m[1], id, m[3], m[4])
+	})
+}
+
+// prefixHeadingIDs prefixes the heading IDs of the given rendered post (listing) content, along with the links to them,
+// so that they stay unique on the listing pages concatenating the content of multiple posts
+func prefixHeadingIDs(content string, prefix string) string {
+	ids := make(map[string]bool)
+	for _, h := range extractHeadings(content) {
+		ids[h.ID] = true
+	}
+	if len(ids) == 0 {
+		return content
+	}
+	content = headingRegexp.ReplaceAllStringFunc(content, func(match string) string {
+		m := headingRegexp.FindStringSubmatch(match)
+		return fmt.Sprintf(`<h%s id="%s">%s%s`, m[1], prefix+m[2], m[3], m[4])
+	})
+	return fragmentLinkRegexp.ReplaceAllStringFunc(content, func(match string) string {
+		if id := fragmentLinkRegexp.FindStringSubmatch(match)[1]; ids[id] {
+			return `href="#` + prefix + id + `"`
+		}
+		return match
+	})
+}
+
+func headingFromMatch(m []string) headingData {
+	level, _ := strconv.Atoi(m[1])
+	return headingData{Level: level, ID: m[2], Title: html.EscapeString(htmlToPlainText(m[3]))}
+}
+
+// buildTOC builds the nested table of contents out of the given headings within the given level range:
+// each heading is nested under the closest preceding heading of a higher level (lower level number)
+func buildTOC(headings []headingData, minLevel int, maxLevel int) []*tocItem {
+	var toc []*tocItem
+	var parents []*tocItem
+	for _, h := range headings {
+		if h.Level < minLevel || h.Level > maxLevel {
+			continue
+		}
+		item := &tocItem{Level: h.Level, ID: h.ID, Title: h.Title}
+		for len(parents) > 0 && parents[len(parents)-1].Level >= h.Level {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) == 0 {
+			toc = append(toc, item)
+		} else {
+			parent := parents[len(parents)-1]
+			parent.Children = append(parent.Children, item)
+		}
+		parents = append(parents, item)
+	}
+	return toc
+}
+
+// compileHeadingAnchorTemplate compiles the (optional) heading anchor theme template,
+// returning nil if the theme doesn't provide one
+func compileHeadingAnchorTemplate(resLoader resourceLoader) *template.Template {
+	headingAnchorTemplate, ok := templateCache.get(headingAnchorTemplateFileName)
+	if !ok {
+		headingAnchorMarkup, err := readTemplateFile(headingAnchorTemplateFileName, resLoader)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			check(err)
+		}
+		if headingAnchorMarkup != "" {
+			headingAnchorTemplate, err = template.New(headingAnchorTemplateFileName).Funcs(funcMap).Parse(strings.TrimSpace(headingAnchorMarkup))
+			check(err)
+		}
+		templateCache.set(headingAnchorTemplateFileName, headingAnchorTemplate)
+	}
+	return headingAnchorTemplate
+}
+
+// renderHeadings renders the toc directive placeholders of the given rendered HTML content
+// (through the `content-toc.html` theme template), and appends the anchor links to the headings
+// (through the optional `heading-anchor.html` theme template)
+func renderHeadings(body string, resLoader resourceLoader, warnings []string) (string, []string) {
+	body = dedupeHeadingIDs(body)
+	headings := extractHeadings(body)
+	if tocDirectivePlaceholderRegexp.MatchString(body) {
+		render := func(propStr string) string {
+			props := make(map[string]string)
+			for _, pStr := range strings.Split(propStr, ",") {
+				key, val, _ := strings.Cut(pStr, "=")
+				props[key] = val
+			}
+			minLevel, _ := strconv.Atoi(props[tocDirectivePropMinLevel])
+			maxLevel, _ := strconv.Atoi(props[tocDirectivePropMaxLevel])
+			toc := buildTOC(headings, minLevel, maxLevel)
+			if len(toc) == 0 {
+				warnings = append(warnings, fmt.Sprintf("{%s} directive found no headings (levels %d-%d)", tocDirective, minLevel, maxLevel))
+				return ""
+			}
+			tocTemplate, err := compileContentDirectiveTemplate(tocDirective, resLoader)
+			if err != nil {
+				println(" - failed to process " + tocDirective + " directive: " + err.Error())
+				return ""
+			}
+			var tocBuffer bytes.Buffer
+			err = tocTemplate.Execute(&tocBuffer, contentDirectiveData{TOC: toc, Props: props})
+			check(err)
+			return strings.TrimSpace(tocBuffer.String())
+		}
+		// the <p>-wrapped form is substituted first, so the wrapper is stripped along with the placeholder
+		body = tocDirectiveWrappedPlaceholderRegexp.ReplaceAllStringFunc(body, func(match string) string {
+			return render(tocDirectiveWrappedPlaceholderRegexp.FindStringSubmatch(match)[1])
+		})
+		body = tocDirectivePlaceholderRegexp.ReplaceAllStringFunc(body, func(match string) string {
+			return render(tocDirectivePlaceholderRegexp.FindStringSubmatch(match)[1])
+		})
+	}
+	headingAnchorTemplate := compileHeadingAnchorTemplate(resLoader)
+	if headingAnchorTemplate != nil {
+		body = headingRegexp.ReplaceAllStringFunc(body, func(match string) string {
+			m := headingRegexp.FindStringSubmatch(match)
+			var anchorBuffer bytes.Buffer
+			err := headingAnchorTemplate.Execute(&anchorBuffer, headingFromMatch(m))
+			check(err)
+			return strings.TrimSuffix(match, m[4]) + anchorBuffer.String() + m[4]
+		})
+	}
+	return body, warnings
+}
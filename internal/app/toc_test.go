@@ -0,0 +1,81 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTocDirective(t *testing.T) {
+	// the (optional) heading anchor template absence may have been cached by the tests run outside the module dir
+	templateCache.delete(headingAnchorTemplateFileName)
+	content := "---\ntitle: Guide\n---\n\n{toc(max=3)}\n\n# Intro\n\ntext\n\n## Setup *fast*\n\n### Step one\n\n#### Too deep\n\n## Usage\n\n# Summary\n"
+	p := parsePage("guide", content, defaultConfig(), testResLoader())
+	if len(p.Warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", p.Warnings)
+	}
+	toc := strings.ReplaceAll(string(trimRegexp.ReplaceAll([]byte(p.Body), []byte(""))), "\n", "")
+	verifyStringContains(toc, `<nav class="toc"><ul>`+
+		`<li><a href="#intro">Intro</a><ul>`+
+		`<li><a href="#setup-fast">Setup fast</a><ul><li><a href="#step-one">Step one</a></li></ul></li>`+
+		`<li><a href="#usage">Usage</a></li></ul></li>`+
+		`<li><a href="#summary">Summary</a></li>`+
+		`</ul></nav>`, t)
+	if strings.Contains(toc[:strings.Index(toc, "</nav>")], `href="#too-deep"`) {
+		t.Error("toc should not list the headings beyond the max level")
+	}
+	if strings.Contains(p.Body, "<p><nav") {
+		t.Error("toc placed on its own line should not be wrapped in a paragraph")
+	}
+	// heading anchors are rendered through the theme's heading-anchor.html template
+	verifyStringContains(p.Body, `<h2 id="setup-fast">Setup <em>fast</em><a class="heading-anchor" href="#setup-fast" aria-label="Link to this section">#</a></h2>`, t)
+}
+
+func TestTocDirectiveProps(t *testing.T) {
+	var warnings []string
+	verifyStringsEqual(parseTocDirectiveProps("", &warnings), "min=1,max=3", t)
+	verifyStringsEqual(parseTocDirectiveProps("(min=2, max=4)", &warnings), "min=2,max=4", t)
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	verifyStringsEqual(parseTocDirectiveProps("(min=0,depth=2)", &warnings), "min=1,max=3", t)
+	verifyStringsEqual(parseTocDirectiveProps("(min=4,max=2)", &warnings), "min=1,max=3", t)
+	if len(warnings) != 3 {
+		t.Errorf("expected 3 warnings, got: %v", warnings)
+	}
+
+	_, warnings = renderHeadings(":@@@:toc:min=5,max=6:@@@:<h2 id=\"a\">A</h2>", testResLoader(), nil)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "found no headings") {
+		t.Errorf("expected a no headings warning, got: %v", warnings)
+	}
+}
+
+func TestBuildTOCLevelJumps(t *testing.T) {
+	toc := buildTOC([]headingData{
+		{Level: 3, ID: "a"},
+		{Level: 2, ID: "b"},
+		{Level: 4, ID: "c"},
+		{Level: 3, ID: "d"},
+	}, 1, 6)
+	if len(toc) != 2 || toc[0].ID != "a" || toc[1].ID != "b" {
+		t.Fatalf("unexpected top level entries: %+v", toc)
+	}
+	if len(toc[1].Children) != 2 || toc[1].Children[0].ID != "c" || toc[1].Children[1].ID != "d" {
+		t.Errorf("unexpected nested entries: %+v", toc[1].Children)
+	}
+}
+
+func TestDedupeHeadingIDs(t *testing.T) {
+	// the content directive blocks are converted separately, each one generating the IDs on its own
+	body := `<h2 id="intro">Intro</h2><div class="cols"><h2 id="intro">Intro</h2><h2 id="intro-1">Intro 1</h2></div><h3 id="intro">Intro</h3>`
+	verifyStringsEqual(dedupeHeadingIDs(body),
+		`<h2 id="intro">Intro</h2><div class="cols"><h2 id="intro-2">Intro</h2><h2 id="intro-1">Intro 1</h2></div><h3 id="intro-3">Intro</h3>`, t)
+}
+
+func TestPrefixHeadingIDs(t *testing.T) {
+	content := `<nav class="toc"><ul><li><a href="#intro">Intro</a></li></ul></nav>` +
+		`<h2 id="intro">Intro<a class="heading-anchor" href="#intro">#</a></h2><p><a href="#top">Top</a></p>`
+	verifyStringsEqual(prefixHeadingIDs(content, "hello--"),
+		`<nav class="toc"><ul><li><a href="#hello--intro">Intro</a></li></ul></nav>`+
+			`<h2 id="hello--intro">Intro<a class="heading-anchor" href="#hello--intro">#</a></h2><p><a href="#top">Top</a></p>`, t)
+	verifyStringsEqual(prefixHeadingIDs("<p>no headings</p>", "hello--"), "<p>no headings</p>", t)
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"image/png"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -55,6 +57,186 @@ func pngCompressionLevelStringValues() []string {
 	return []string{NoCompression.String(), BestSpeed.String(), BestCompression.String(), DefaultCompression.String()}
 }
 
+type postOrder string
+
+const (
+	DateTimePostOrder postOrder = "date"
+	FileNamePostOrder postOrder = "filename"
+)
+
+func (o postOrder) String() string {
+	return string(o)
+}
+
+func postOrderFromString(order string) postOrder {
+	switch strings.ToLower(order) {
+	case DateTimePostOrder.String():
+		return DateTimePostOrder
+	case FileNamePostOrder.String():
+		return FileNamePostOrder
+	}
+	return ""
+}
+
+func postOrderStringValues() []string {
+	return []string{DateTimePostOrder.String(), FileNamePostOrder.String()}
+}
+
+// undatedPostPolicy defines where the posts without a date end up when posts are ordered by date/time
+type undatedPostPolicy string
+
+const (
+	ErrorUndatedPostPolicy    undatedPostPolicy = "error"
+	BottomUndatedPostPolicy   undatedPostPolicy = "bottom"
+	FileNameUndatedPostPolicy undatedPostPolicy = "filename"
+)
+
+func (p undatedPostPolicy) String() string {
+	return string(p)
+}
+
+func undatedPostPolicyFromString(policy string) undatedPostPolicy {
+	switch strings.ToLower(policy) {
+	case ErrorUndatedPostPolicy.String():
+		return ErrorUndatedPostPolicy
+	case BottomUndatedPostPolicy.String():
+		return BottomUndatedPostPolicy
+	case FileNameUndatedPostPolicy.String():
+		return FileNameUndatedPostPolicy
+	}
+	return ""
+}
+
+func undatedPostPolicyStringValues() []string {
+	return []string{ErrorUndatedPostPolicy.String(), BottomUndatedPostPolicy.String(), FileNameUndatedPostPolicy.String()}
+}
+
+// codeHighlighting defines how the syntax highlighting of fenced code blocks is rendered
+type codeHighlighting string
+
+const (
+	ClassCodeHighlighting  codeHighlighting = "class"
+	InlineCodeHighlighting codeHighlighting = "inline"
+	NoCodeHighlighting     codeHighlighting = "none"
+)
+
+func (h codeHighlighting) String() string {
+	return string(h)
+}
+
+func codeHighlightingFromString(highlighting string) codeHighlighting {
+	switch strings.ToLower(highlighting) {
+	case ClassCodeHighlighting.String():
+		return ClassCodeHighlighting
+	case InlineCodeHighlighting.String():
+		return InlineCodeHighlighting
+	case NoCodeHighlighting.String(), "no", "false":
+		return NoCodeHighlighting
+	}
+	return ""
+}
+
+func codeHighlightingStringValues() []string {
+	return []string{ClassCodeHighlighting.String(), InlineCodeHighlighting.String(), NoCodeHighlighting.String()}
+}
+
+// deployTargetType defines the backend the generated site is deployed with (see the deploy command)
+type deployTargetType string
+
+const (
+	RsyncDeployTarget deployTargetType = "rsync"
+	SFTPDeployTarget  deployTargetType = "sftp"
+	S3DeployTarget    deployTargetType = "s3"
+	LocalDeployTarget deployTargetType = "local"
+)
+
+func (t deployTargetType) String() string {
+	return string(t)
+}
+
+func deployTargetTypeFromString(target string) deployTargetType {
+	switch strings.ToLower(target) {
+	case RsyncDeployTarget.String():
+		return RsyncDeployTarget
+	case SFTPDeployTarget.String():
+		return SFTPDeployTarget
+	case S3DeployTarget.String():
+		return S3DeployTarget
+	case LocalDeployTarget.String():
+		return LocalDeployTarget
+	}
+	return ""
+}
+
+func deployTargetTypeStringValues() []string {
+	return []string{RsyncDeployTarget.String(), SFTPDeployTarget.String(), S3DeployTarget.String(), LocalDeployTarget.String()}
+}
+
+// webpVariantsPolicy defines the images (originals and thumbnails) WebP variants are generated for
+// (the variants are encoded losslessly, which only pays off for PNG images, so the JPEG ones aren't covered)
+type webpVariantsPolicy string
+
+const (
+	NoWebPVariants  webpVariantsPolicy = "none"
+	PNGWebPVariants webpVariantsPolicy = "png"
+)
+
+func (p webpVariantsPolicy) String() string {
+	return string(p)
+}
+
+func webpVariantsPolicyFromString(policy string) webpVariantsPolicy {
+	switch strings.ToLower(policy) {
+	case NoWebPVariants.String(), "no", "false":
+		return NoWebPVariants
+	case PNGWebPVariants.String(), webpVariantsAllValue, "yes", "true":
+		return PNGWebPVariants
+	}
+	return ""
+}
+
+func webpVariantsPolicyStringValues() []string {
+	return []string{NoWebPVariants.String(), PNGWebPVariants.String()}
+}
+
+// sourceFileExtensions returns the extensions of the image files WebP variants are generated for
+// ((animated) GIFs aren't supported, as the variants are encoded as still images)
+func (p webpVariantsPolicy) sourceFileExtensions() []string {
+	if p == PNGWebPVariants {
+		return []string{".png"}
+	}
+	return nil
+}
+
+// imgMetadataStripPolicy defines the metadata removed from the original images (see processOriginalMediaFile)
+type imgMetadataStripPolicy string
+
+const (
+	NoImgMetadataStrip  imgMetadataStripPolicy = "none"
+	GPSImgMetadataStrip imgMetadataStripPolicy = "gps"
+	AllImgMetadataStrip imgMetadataStripPolicy = "all"
+)
+
+func (p imgMetadataStripPolicy) String() string {
+	return string(p)
+}
+
+func imgMetadataStripPolicyFromString(policy string) imgMetadataStripPolicy {
+	switch strings.ToLower(policy) {
+	case NoImgMetadataStrip.String(), "no", "false":
+		return NoImgMetadataStrip
+	case GPSImgMetadataStrip.String():
+		return GPSImgMetadataStrip
+	case AllImgMetadataStrip.String(), "yes", "true":
+		return AllImgMetadataStrip
+	}
+	return ""
+}
+
+func imgMetadataStripPolicyStringValues() []string {
+	return []string{NoImgMetadataStrip.String(), GPSImgMetadataStrip.String(), AllImgMetadataStrip.String()}
+}
+
 type appConfig struct {
 	siteBaseURL                   string
 	siteName                      string
@@ -64,23 +246,55 @@ type appConfig struct {
 	generateArchive               bool
 	generateTagIndex              bool
 	generateCollectionIndex       bool
+	generateAlbums                bool
 	generateFeeds                 []string
 	feedPostCnt                   int
 	feedPostViewOnWebsiteLinkText string
 	enableSearch                  bool
+	generateSitemap               bool
+	generateRobotsTxt             bool
+	generateAPI                   bool
+	apiDir                        string
+	languages                     []string
+	lang                          string            // the language being processed (empty if no languages are configured)
+	translations                  map[string]string // template string translations of the language being processed
 	pageSize                      int
+	relatedPostCnt                int
+	workerCnt                     int
+	postOrder                     postOrder
+	undatedPosts                  undatedPostPolicy
+	codeHighlighting              codeHighlighting
+	codeHighlightStyle            string
 	resizeOrigImages              bool
 	maxImgSize                    int
 	useThumbs                     bool
 	thumbSizes                    []int
+	cropThumbSizes                []cropThumbSize // the fixed-aspect (cropped around the focal point) thumbnail sizes
 	thumbThreshold                float64
 	jpegQuality                   int
 	pngCompressionLevel           pngCompressionLevel
+	webpVariants                  webpVariantsPolicy
+	imgPlaceholders               bool
+	stripImgMetadata              imgMetadataStripPolicy
 	serveHost                     string
 	servePort                     int
+	deployTarget                  deployTargetType
 	deployPath                    string
 	deployHost                    string
+	deployPort                    int
 	deployUsername                string
+	deployPassword                string                       // sftp (preferably set via the corresponding environment variable)
+	deployKeyFile                 string                       // sftp private key file (the ssh-agent and the default keys are used otherwise)
+	deployKnownHostsFile          string                       // sftp known hosts file (`~/.ssh/known_hosts` by default)
+	deployBucket                  string                       // s3
+	deployEndpoint                string                       // s3-compatible storage endpoint URL (AWS S3 by default)
+	deployRegion                  string                       // s3
+	deployAccessKey               string                       // s3 (preferably set via the corresponding environment variable)
+	deploySecretKey               string                       // s3 (preferably set via the corresponding environment variable)
+	deployDeleteThreshold         int                          // the number of deletions above which a deploy asks for a confirmation
+	deployReleases                int                          // the number of releases to keep (enables the release-based deploys if non-zero)
+	environments                  map[string]map[string]string // the named deploy environments along with their setting overrides
+	environment                   string                       // the deploy environment the config overrides are applied for (if any)
 }
 
 type appCommandDescriptor struct {
@@ -148,6 +362,17 @@ func (c contentEntityType) MarshalJSON() ([]byte, error) {
 	return json.Marshal(strings.ToLower(c.String()))
 }
 
+func (c *contentEntityType) UnmarshalJSON(data []byte) error {
+	var typeName string
+	if err := json.Unmarshal(data, &typeName); err != nil {
+		return err
+	}
+	if *c = contentEntityTypeFromString(typeName); *c == UndefinedContentEntityType {
+		return fmt.Errorf("invalid content entity type: %s", typeName)
+	}
+	return nil
+}
+
 func (c contentEntityType) Page() bool {
 	return c == Page
 }
@@ -182,6 +407,49 @@ type templateContent struct {
 	FileName   string
 	Content    any
 	Config     map[string]any
+	Meta       *entityMetaData // structured metadata, available for single post/page files only
+	Nav        *postNavData    // post navigation, available for single post files only
+}
+
+// postNavData holds the posts linked from a single post file:
+// the neighbouring posts (in the post order), the related ones (sharing tags and/or collection items)
+// and the neighbouring parts of the post series
+type postNavData struct {
+	Prev    *post  // the previous (older) post, if any
+	Next    *post  // the next (newer) post, if any
+	Related []post // the most related posts, the most related ones first
+	Series  *postSeriesData
+}
+
+// postSeriesData holds the position of a post in its series, along with its neighbouring parts
+type postSeriesData struct {
+	Title   string // the series title (as given by its first part)
+	URI     string // the series URI (the series page is generated at `/series/<uri>/`)
+	Part    int    // the part number: the one given in the frontmatter, or the position in the series
+	PartCnt int    // the number of parts
+	Prev    *post  // the previous part, if any
+	Next    *post  // the next part, if any
+}
+
+// entityMetaData carries the structured metadata of a single post/page
+// used to render link previews (Open Graph, Twitter Cards) and search engine (JSON-LD) head tags
+type entityMetaData struct {
+	Type          string // Open Graph object type: `article` for posts, `website` for pages
+	Title         string // plain text post/page title, falling back to the post date/time or the site name
+	SiteName      string
+	Description   string            // plain text `description` frontmatter value, falling back to an auto excerpt
+	CanonicalURL  string            // absolute URL, empty if the `siteBaseURL` config option is not set
+	Image         string            // absolute lead image URL, empty if there's no image or the `siteBaseURL` is not set
+	PublishedTime string            // RFC 3339 post date/time, empty for pages and undated posts
+	ModifiedTime  string            // RFC 3339 last modification time, empty if unknown
+	JSONLD        string            // `BlogPosting` (posts) / `WebPage` (pages) JSON-LD document
+	Alternates    []translationLink // `hreflang` alternates (absolute URLs if the `siteBaseURL` is set), empty without translations
+}
+
+// translationLink links to a single language version of a post/page
+type translationLink struct {
+	Lang string // language code, or `x-default` (for the `hreflang` alternate of the default language version)
+	URI  string
 }
 
 type contentDirectiveData struct {
@@ -191,6 +459,23 @@ type contentDirectiveData struct {
 	Props               map[string]string
 	Columns             []colData
 	GridTemplateColumns string
+	TOC                 []*tocItem
+	AlbumUri            string // the URI of the post album page (gallery directives only, if generated)
+}
+
+// tocItem is a table of contents entry: a heading along with its nested (lower level) headings
+type tocItem struct {
+	Level    int
+	ID       string
+	Title    string
+	Children []*tocItem
+}
+
+// headingData is the data passed to the (optional) heading anchor template
+type headingData struct {
+	Level int
+	ID    string
+	Title string
 }
 
 type colsBlock struct {
@@ -294,22 +579,186 @@ func (m mediaType) Video() bool {
 }
 
 type thumb struct {
-	Uri  string
-	Size int
+	Uri    string
+	Size   int
+	Width  int `json:",omitempty"` // the intrinsic dimensions of the thumbnail (0 if unknown)
+	Height int `json:",omitempty"`
+}
+
+// cropThumbSize is the size of a fixed-aspect thumbnail (e.g. `480x480`),
+// cropped out of the image around its focal point
+type cropThumbSize struct {
+	width  int
+	height int
+}
+
+func (s cropThumbSize) String() string {
+	return fmt.Sprintf("%dx%d", s.width, s.height)
+}
+
+// focalPoint is the point of interest of an image (in percent of its width/height, from the top-left corner),
+// the crop thumbnails are cropped around (e.g. `30x40`)
+type focalPoint struct {
+	x int
+	y int
+}
+
+func (fp focalPoint) String() string {
+	return fmt.Sprintf("%dx%d", fp.x, fp.y)
+}
+
+// isCenter reports whether the focal point is the (default) image center
+func (fp focalPoint) isCenter() bool {
+	return fp.x == 50 && fp.y == 50
+}
+
+func parseFocalPoint(s string) (focalPoint, bool) {
+	m := focalPointRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return focalPoint{}, false
+	}
+	x, _ := strconv.Atoi(m[1])
+	y, _ := strconv.Atoi(m[2])
+	if x > 100 || y > 100 {
+		return focalPoint{}, false
+	}
+	return focalPoint{x: x, y: y}, true
 }
 
 type media struct {
-	Type    mediaType
-	Uri     string
-	Caption string
-	thumbs  []thumb
+	Type        mediaType
+	Uri         string
+	Caption     string
+	thumbs      []thumb
+	webpUri     string  // the WebP variant of the image (empty if not available)
+	webpThumbs  []thumb // the WebP variants of the thumbnails
+	width       int     // the intrinsic dimensions of the image/video (0 if unknown)
+	height      int
+	placeholder string         // the tiny blurred placeholder data URI (empty unless enabled, see the imgPlaceholders config option)
+	color       string         // the dominant colour (`#rrggbb`, empty unless enabled along with the placeholder)
+	posterUri   string         // the poster image of the video (empty if not available)
+	duration    float64        // the duration of the video in seconds (0 if unknown)
+	captureTime civil.DateTime // the EXIF (original) date/time of the image (zero if unknown)
+	camera      string         // the EXIF camera make and model of the image
+	focus       *focalPoint    // the focal point of the image (nil if not specified, i.e. the center)
+	cropThumbs  []thumb        // the fixed-aspect thumbnails cropped around the focal point
+}
+
+// Width and Height are the intrinsic dimensions of the image/video (0 if unknown),
+// e.g. to render the `width`/`height` attributes of the `<img>`, so that the page doesn't jump around as it loads
+func (m media) Width() int {
+	return m.width
+}
+
+func (m media) Height() int {
+	return m.height
+}
+
+// HasDimensions reports whether the intrinsic dimensions of the image/video are known
+func (m media) HasDimensions() bool {
+	return m.width > 0 && m.height > 0
+}
+
+// Placeholder is a tiny blurred version of the image (a base64 encoded JPEG data URI),
+// e.g. to use as the `<img>` background image while the image loads
+func (m media) Placeholder() string {
+	return m.placeholder
+}
+
+// Color is the dominant colour of the image (`#rrggbb`)
+func (m media) Color() string {
+	return m.color
+}
+
+// CaptureTime is the EXIF (original) date/time of the image (zero if unknown)
+func (m media) CaptureTime() civil.DateTime {
+	return m.captureTime
+}
+
+func (m media) FmtCaptureDate() string {
+	if !m.captureTime.IsZero() {
+		return m.captureTime.Date.String()
+	}
+	return ""
+}
+
+func (m media) FmtCaptureTime() string {
+	if !m.captureTime.IsZero() {
+		t, _ := strings.CutSuffix(m.captureTime.Time.String(), ":00")
+		return t
+	}
+	return ""
+}
+
+// Camera is the EXIF camera make and model of the image (e.g. `Apple iPhone 13`)
+func (m media) Camera() string {
+	return m.camera
+}
+
+// Poster is the URI of the poster image of the video (a sibling image file named after it, e.g. `clip.mp4.poster.jpg`),
+// e.g. to render the `poster` attribute of the `<video>`
+func (m media) Poster() string {
+	return m.posterUri
+}
+
+// Duration is the duration of the video in seconds (read from the MP4/MOV container metadata, 0 if unknown)
+func (m media) Duration() float64 {
+	return m.duration
+}
+
+// DurationString is the duration of the video formatted as `m:ss` (or `h:mm:ss`), empty if unknown
+func (m media) DurationString() string {
+	if m.duration <= 0 {
+		return ""
+	}
+	d := int(math.Round(m.duration))
+	if d >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", d/3600, d%3600/60, d%60)
+	}
+	return fmt.Sprintf("%d:%02d", d/60, d%60)
+}
+
+// Focus is the focal point of the image as a CSS position (e.g. `30% 40%`, the center if not specified),
+// e.g. to render the `object-position` of an image cropped with `object-fit: cover`
+func (m media) Focus() string {
+	if m.focus == nil {
+		return "50% 50%"
+	}
+	return fmt.Sprintf("%d%% %d%%", m.focus.x, m.focus.y)
+}
+
+// HasFocus reports whether the focal point of the image is specified
+func (m media) HasFocus() bool {
+	return m.focus != nil
 }
 
 func (m media) SrcSet() string {
+	return srcSet(m.Uri, m.thumbs)
+}
+
+// HasWebP reports whether the WebP variants of the image (and its thumbnails) are available,
+// e.g. to render a `<picture>` with a `<source type="image/webp">`
+func (m media) HasWebP() bool {
+	return m.webpUri != ""
+}
+
+func (m media) WebPUri() string {
+	return m.webpUri
+}
+
+// WebPSrcSet is the SrcSet of the WebP variants
+func (m media) WebPSrcSet() string {
+	if m.webpUri == "" {
+		return ""
+	}
+	return srcSet(m.webpUri, m.webpThumbs)
+}
+
+func srcSet(uri string, thumbs []thumb) string {
 	minThumbSize := defaultThumbSizes[0]
 	maxThumbSize := defaultThumbSizes[len(defaultThumbSizes)-1]
 	var srcSet []string
-	for _, thumb := range m.thumbs {
+	for _, thumb := range thumbs {
 		srcSet = append(srcSet, fmt.Sprintf("%s %dw", thumb.Uri, thumb.Size))
 		if thumb.Size < minThumbSize {
 			minThumbSize = thumb.Size
@@ -318,7 +767,7 @@ func (m media) SrcSet() string {
 			maxThumbSize = thumb.Size
 		}
 	}
-	srcSet = append(srcSet, fmt.Sprintf("%s %dw", m.Uri, maxThumbSize+(minThumbSize+maxThumbSize)/2))
+	srcSet = append(srcSet, fmt.Sprintf("%s %dw", uri, maxThumbSize+(minThumbSize+maxThumbSize)/2))
 	return strings.Join(srcSet, ", ")
 }
 
@@ -326,6 +775,42 @@ func (m media) ThumbUri(sizeIdx int) string {
 	return m.thumbs[sizeIdx-1].Uri
 }
 
+func (m media) ThumbWidth(sizeIdx int) int {
+	return m.thumbs[sizeIdx-1].Width
+}
+
+func (m media) ThumbHeight(sizeIdx int) int {
+	return m.thumbs[sizeIdx-1].Height
+}
+
+// HasCropThumbs reports whether the crop thumbnails are available (see the `thumbSizes` config option)
+func (m media) HasCropThumbs() bool {
+	return len(m.cropThumbs) > 0
+}
+
+// CropThumbUri is the URI of the crop thumbnail of the given (1-based) crop size index,
+// falling back to the first regular thumbnail if there are no crop thumbnails
+func (m media) CropThumbUri(sizeIdx int) string {
+	if sizeIdx > len(m.cropThumbs) {
+		return m.ThumbUri(1)
+	}
+	return m.cropThumbs[sizeIdx-1].Uri
+}
+
+func (m media) CropThumbWidth(sizeIdx int) int {
+	if sizeIdx > len(m.cropThumbs) {
+		return m.ThumbWidth(1)
+	}
+	return m.cropThumbs[sizeIdx-1].Width
+}
+
+func (m media) CropThumbHeight(sizeIdx int) int {
+	if sizeIdx > len(m.cropThumbs) {
+		return m.ThumbHeight(1)
+	}
+	return m.cropThumbs[sizeIdx-1].Height
+}
+
 type searchData struct {
 	TypeId  string
 	Content string
@@ -336,16 +821,32 @@ type contentEntity interface {
 	EntityId() string
 }
 
+// contentLinkRef is a `{%post:id%}`/`{%page:id%}` content link, with an optional `#anchor` suffix
+// (the ID of a heading of the linked post/page)
+type contentLinkRef struct {
+	Type   contentEntityType
+	Id     string
+	Anchor string
+}
+
 type page struct {
 	Id             string
 	Title          string
 	Body           string
 	Media          []media
-	MetaCollection string   // meta collection defined by this page (raw title from the `meta-collection` frontmatter key)
-	CollectionRefs []string // normalized URIs of collections embedded via `{collection:...}` directives (deduplicated)
+	Description    string            // raw `description` frontmatter value
+	MetaCollection string            // meta collection defined by this page (raw title from the `meta-collection` frontmatter key)
+	CollectionRefs []string          // normalized URIs of collections embedded via `{collection:...}` directives (deduplicated)
+	GalleryMedia   []media           // the media of the `{gallery}` directives (deduplicated)
+	ContentLinks   []contentLinkRef  // `{%post:...%}`/`{%page:...%}` content links (deduplicated, see validateContentLinks)
+	Lang           string            // `lang` frontmatter value (empty for the default language)
+	TranslationOf  string            // `translation-of` frontmatter value: the id of the page this one is a translation of
+	Translations   []translationLink // all the language versions of the page (including itself), populated during processing
 	SearchData     searchData
 	Warnings       []string // content-directive warnings (malformed captions, unparsed directives)
 	skipProcessing bool
+	excerptContent string    // cleaned markdown content (directives removed) for auto excerpts
+	modTime        time.Time // content file modification time
 }
 
 func (p page) ContentEntityType() contentEntityType {
@@ -401,19 +902,31 @@ type post struct {
 	Date            civil.Date
 	Time            civil.Time
 	Title           string
+	Draft           bool   // excluded from the generated site until unflagged (see the `draft` frontmatter key)
+	Description     string // raw `description` frontmatter value
 	Body            string
 	FeedContent     string // cleaned markdown content for feed generation (directives removed)
 	Tags            []string
 	Collections     []postCollectionRef
-	MetaCollections []string // referenced meta collection titles (raw, from the `meta-collections` frontmatter key)
+	MetaCollections []string          // referenced meta collection titles (raw, from the `meta-collections` frontmatter key)
+	Lang            string            // `lang` frontmatter value (empty for the default language)
+	TranslationOf   string            // `translation-of` frontmatter value: the id of the post this one is a translation of
+	Translations    []translationLink // all the language versions of the post (including itself), populated during processing
+	Series          string            // `series` frontmatter value: the title of the series the post is a part of
+	SeriesPart      int               // the part number of the post in its series (0 if not given, see the `series` frontmatter key)
+	ContentLinks    []contentLinkRef  // `{%post:...%}`/`{%page:...%}` content links (deduplicated, see validateContentLinks)
+	GalleryMedia    []media           // the media of the `{gallery}` directives (deduplicated), listed on the post album page
 	SearchData      searchData
 	Warnings        []string // content-directive warnings (malformed captions, unparsed directives)
 	skipProcessing  bool
+	modTime         time.Time // content file modification time
 	// site-wide "<collection-uri>/<item-uri>" -> distinct referencing post count,
 	// populated during processing (from the aggregated collections model) for footer rendering
 	collItemPostCnt map[string]int
 	// resolved meta collection footer groups (links to the defining pages), populated during processing
 	metaCollGroups []postCollectionGroup
+	// URI prefix of the post language (empty for the default one), populated during processing
+	langURIPrefix string
 }
 
 func (p post) ContentEntityType() contentEntityType {
@@ -443,7 +956,7 @@ func (p post) CollectionGroups() []postCollectionGroup {
 			groups = append(groups, postCollectionGroup{
 				Title: ref.Collection,
 				URI:   collUri,
-				Link:  "/" + deployCollectionsDirName + "/" + collUri + "/",
+				Link:  p.langURIPrefix + "/" + deployCollectionsDirName + "/" + collUri + "/",
 			})
 			gi = len(groups) - 1
 			groupIdx[collUri] = gi
@@ -471,6 +984,33 @@ func (p post) HasDateOrTime() bool {
 	return !p.Date.IsZero() || !p.Time.IsZero()
 }
 
+// LinkTitle returns the title to render the links to the post with:
+// its title, falling back to its date/time, or its ID (for the undated posts without a title)
+func (p post) LinkTitle() string {
+	if p.Title != "" {
+		return p.Title
+	}
+	if p.HasDateOrTime() {
+		return strings.TrimSpace(p.FmtDate() + " " + p.FmtTime())
+	}
+	return p.Id
+}
+
+// publishTime returns the moment the post gets published: its date/time in the local time zone
+// (a date without a time stands for the start of that day), or the zero time for undated posts
+func (p post) publishTime() time.Time {
+	if p.Date.IsZero() {
+		return time.Time{}
+	}
+	return civil.DateTime{Date: p.Date, Time: p.Time}.In(time.Local)
+}
+
+// isPendingAt reports whether the post is pending publication at the given moment:
+// either flagged as a draft or scheduled (dated) in the future
+func (p post) isPendingAt(now time.Time) bool {
+	return p.Draft || p.publishTime().After(now)
+}
+
 func (p post) FmtDate() string {
 	if !p.Date.IsZero() {
 		return p.Date.String()
@@ -551,6 +1091,7 @@ type stats struct {
 	tagCnt      int
 	collCnt     int
 	collItemCnt int
+	pendingCnt  int
 	genCnt      int
 }
 
@@ -562,6 +1103,22 @@ type watchReloadData struct {
 
 type processorOutputHandler func(outputFilePath string, data []byte) bool
 
+// renderedOutput is an output file rendered by a worker, to be handed over to the output handler
+type renderedOutput struct {
+	filePath string
+	data     []byte
+}
+
+// contentPage is a post listing page (posts, archive, tag, series or collection item one) to be rendered
+type contentPage struct {
+	templateName   string
+	title          string
+	content        string
+	outputFilePath string
+}
+
+// imageThumbnailHandler handles the thumbnails of a content entity media dir;
+// called concurrently (for different dirs) while parsing the content
 type imageThumbnailHandler func(mediaDirPath string, config appConfig)
 
 type dirWatchOp string
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"runtime"
+	"sync"
+)
+
+// syncCache is a map safe for concurrent use,
+// backing the parsing/rendering caches shared by the workers of the generate pipeline
+type syncCache[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+}
+
+func newSyncCache[K comparable, V any]() *syncCache[K, V] {
+	return &syncCache[K, V]{m: make(map[K]V)}
+}
+
+func (c *syncCache[K, V]) get(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, ok := c.m[key]
+	return value, ok
+}
+
+func (c *syncCache[K, V]) set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[key] = value
+}
+
+func (c *syncCache[K, V]) delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, key)
+}
+
+// workerPoolSize returns the number of workers to parse/render the content with:
+// the configured one, or the number of CPUs if not set
+func workerPoolSize(config appConfig) int {
+	if config.workerCnt > 0 {
+		return config.workerCnt
+	}
+	return runtime.NumCPU()
+}
+
+// forEachParallel calls fn for each index in [0, n) across a pool of (up to) workerCnt workers,
+// returning once all the calls are done; fn is expected to only write to the state owned by its index,
+// so that the results don't depend on the scheduling (the calling code handles them in the index order afterwards);
+// a panic in any of the calls (e.g. a failed check) is re-raised in the calling goroutine
+func forEachParallel(n int, workerCnt int, fn func(i int)) {
+	if workerCnt <= 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	var panicOnce sync.Once
+	var panicValue any
+	jobs := make(chan int)
+	for w := 0; w < min(workerCnt, n); w++ {
+		wg.Go(func() {
+			for i := range jobs {
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							panicOnce.Do(func() { panicValue = r })
+						}
+					}()
+					fn(i)
+				}()
+			}
+		})
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if panicValue != nil {
+		panic(panicValue)
+	}
+}
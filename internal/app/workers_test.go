@@ -0,0 +1,53 @@
+package app
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEachParallel(t *testing.T) {
+	for _, workerCnt := range []int{0, 1, 4, 64} {
+		results := make([]int, 100)
+		var calls atomic.Int32
+		forEachParallel(len(results), workerCnt, func(i int) {
+			calls.Add(1)
+			results[i] = i * i
+		})
+		if calls.Load() != 100 {
+			t.Errorf("expected 100 calls with %d workers, got: %d", workerCnt, calls.Load())
+		}
+		for i, r := range results {
+			if r != i*i {
+				t.Fatalf("unexpected result #%d with %d workers: %d", i, workerCnt, r)
+			}
+		}
+	}
+}
+
+func TestForEachParallelPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "failed" {
+			t.Errorf("expected the worker panic to be re-raised, got: %v", r)
+		}
+	}()
+	forEachParallel(10, 4, func(i int) {
+		if i == 7 {
+			panic("failed")
+		}
+	})
+	t.Error("expected a panic")
+}
+
+func TestSyncCache(t *testing.T) {
+	cache := newSyncCache[string, int]()
+	forEachParallel(50, 8, func(i int) {
+		cache.set(string(rune('a'+i%5)), i%5)
+	})
+	if v, ok := cache.get("c"); !ok || v != 2 {
+		t.Errorf("unexpected cached value: %d (%v)", v, ok)
+	}
+	cache.delete("c")
+	if _, ok := cache.get("c"); ok {
+		t.Error("expected the cached value to be deleted")
+	}
+}